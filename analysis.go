@@ -0,0 +1,610 @@
+package neoarch
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Analysis helpers over the in-memory design
+// -----------------------------------------------------------------------------
+
+// hierarchy indexes the design nodes and their containment. Its maps are
+// shared by every caller and must not be modified.
+type hierarchy struct {
+	byFullId map[string]*Node
+	parents  map[string]string  // child FullId -> parent FullId, from the first BELONGS_TO of the child
+	children map[string][]*Node // parent FullId -> children, in insertion order
+}
+
+// hierarchyIndex returns the hierarchy of the design, building it on first use
+// after a node or relationship was added. Concurrent readers of the design,
+// such as the saves of SaveToMultiple, build it once.
+func (d *Design) hierarchyIndex() *hierarchy {
+	d.hierarchyMu.Lock()
+	defer d.hierarchyMu.Unlock()
+	if d.hierarchy != nil {
+		return d.hierarchy
+	}
+	h := &hierarchy{
+		byFullId: make(map[string]*Node, len(d.nodes)),
+		parents:  map[string]string{},
+		children: map[string][]*Node{},
+	}
+	for _, node := range d.nodes {
+		h.byFullId[node.FullId()] = node
+	}
+	for _, rel := range d.relationships {
+		if rel.Type != RelBelongsTo {
+			continue
+		}
+		child, ok := h.byFullId[rel.StartID]
+		if !ok {
+			continue
+		}
+		if _, seen := h.parents[rel.StartID]; seen {
+			continue
+		}
+		h.parents[rel.StartID] = rel.EndID
+		h.children[rel.EndID] = append(h.children[rel.EndID], child)
+	}
+	d.hierarchy = h
+	return h
+}
+
+// nodesByFullId indexes the design nodes by FullId, which is what relationships
+// reference. The map is cached and must not be modified.
+func (d *Design) nodesByFullId() map[string]*Node {
+	return d.hierarchyIndex().byFullId
+}
+
+// parentOf returns the design node of n's parent, or nil for top-level nodes.
+func (d *Design) parentOf(n *Node) *Node {
+	if n.ParentNode == nil {
+		return nil
+	}
+	return d.nodes[n.ParentNode.GetID()]
+}
+
+// ancestorAt returns n itself or its closest ancestor of the given type, or nil.
+func (d *Design) ancestorAt(n *Node, level NodeType) *Node {
+	for cur := n; cur != nil; cur = d.parentOf(cur) {
+		if cur.NodeType == level {
+			return cur
+		}
+	}
+	return nil
+}
+
+// systemOf returns the nearest system n belongs to, or nil for elements outside
+// any system and for persons, which stand for themselves even when scoped to a
+// system (see System.Person).
+func (d *Design) systemOf(n *Node) *Node {
+	if n.NodeType == NodeTypePerson {
+		return nil
+	}
+	return d.ancestorAt(n, NodeTypeSystem)
+}
+
+// sortNodes orders nodes by FullId.
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].FullId() < nodes[j].FullId() })
+}
+
+// sortedNodes returns the design nodes ordered by FullId. Exporters and
+// statement builders iterate them instead of the nodes map so their output
+// is the same from one run to the next.
+func (d *Design) sortedNodes() []*Node {
+	nodes := make([]*Node, 0, len(d.nodes))
+	for _, node := range d.nodes {
+		nodes = append(nodes, node)
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// -----------------------------------------------------------------------------
+// Layers
+// -----------------------------------------------------------------------------
+
+// LayerCycleError is returned by Layers when the USES edges at the requested
+// level contain cycles. NodeIDs lists the FullIds of the nodes on a cycle.
+type LayerCycleError struct {
+	Level   NodeType
+	NodeIDs []string
+}
+
+func (e *LayerCycleError) Error() string {
+	return fmt.Sprintf("cycle between %s nodes: %s", e.Level, strings.Join(e.NodeIDs, ", "))
+}
+
+// Layers topologically sorts the nodes of the given type by their explicit USES
+// edges. Edges between deeper elements are rolled up to their ancestors at that
+// level, and edges within the same node are ignored. The first layer holds the
+// nodes that use nothing, each following layer the nodes that only use nodes in
+// earlier layers.
+//
+// When the edges contain cycles, the partial layering is returned together with
+// a *LayerCycleError; nodes on a cycle, or depending on one, are left out.
+func (d *Design) Layers(level NodeType) ([][]*Node, error) {
+	byFullId := d.nodesByFullId()
+
+	var members []*Node
+	for _, node := range d.nodes {
+		if node.NodeType == level {
+			members = append(members, node)
+		}
+	}
+	sortNodes(members)
+
+	dependsOn := map[*Node]map[*Node]struct{}{}
+	usedBy := map[*Node][]*Node{}
+	for _, node := range members {
+		dependsOn[node] = map[*Node]struct{}{}
+	}
+	for _, rel := range d.relationships {
+		if rel.Type != RelUses {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		from, to := d.ancestorAt(start, level), d.ancestorAt(end, level)
+		if from == nil || to == nil || from == to {
+			continue
+		}
+		if _, ok := dependsOn[from][to]; ok {
+			continue
+		}
+		dependsOn[from][to] = struct{}{}
+		usedBy[to] = append(usedBy[to], from)
+	}
+
+	remaining := map[*Node]int{}
+	var current []*Node
+	for _, node := range members {
+		remaining[node] = len(dependsOn[node])
+		if remaining[node] == 0 {
+			current = append(current, node)
+		}
+	}
+
+	var layers [][]*Node
+	for len(current) > 0 {
+		sortNodes(current)
+		layers = append(layers, current)
+		var next []*Node
+		for _, node := range current {
+			delete(remaining, node)
+			for _, user := range usedBy[node] {
+				remaining[user]--
+				if remaining[user] == 0 {
+					next = append(next, user)
+				}
+			}
+		}
+		current = next
+	}
+
+	if len(remaining) == 0 {
+		return layers, nil
+	}
+	return layers, &LayerCycleError{Level: level, NodeIDs: cycleMembers(remaining, dependsOn)}
+}
+
+// cycleMembers returns the FullIds of the nodes that sit on a cycle within
+// the given subgraph, using Tarjan's strongly connected components.
+func cycleMembers(nodes map[*Node]int, dependsOn map[*Node]map[*Node]struct{}) []string {
+	index := map[*Node]int{}
+	lowlink := map[*Node]int{}
+	onStack := map[*Node]bool{}
+	var stack []*Node
+	var ids []string
+
+	var strongConnect func(n *Node)
+	strongConnect = func(n *Node) {
+		index[n] = len(index)
+		lowlink[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for dep := range dependsOn[n] {
+			if _, ok := nodes[dep]; !ok {
+				continue
+			}
+			if _, visited := index[dep]; !visited {
+				strongConnect(dep)
+				lowlink[n] = min(lowlink[n], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[n] = min(lowlink[n], index[dep])
+			}
+		}
+
+		if lowlink[n] == index[n] {
+			var component []*Node
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == n {
+					break
+				}
+			}
+			if len(component) > 1 {
+				for _, member := range component {
+					ids = append(ids, member.FullId())
+				}
+			}
+		}
+	}
+
+	sorted := make([]*Node, 0, len(nodes))
+	for n := range nodes {
+		sorted = append(sorted, n)
+	}
+	sortNodes(sorted)
+	for _, n := range sorted {
+		if _, visited := index[n]; !visited {
+			strongConnect(n)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LayersMarkdown renders the result of Layers as a Markdown table.
+func LayersMarkdown(layers [][]*Node) string {
+	b := strings.Builder{}
+	b.WriteString("| Layer | Nodes |\n")
+	b.WriteString("|---|---|\n")
+	for i, layer := range layers {
+		names := make([]string, 0, len(layer))
+		for _, node := range layer {
+			names = append(names, node.Name)
+		}
+		fmt.Fprintf(&b, "| %d | %s |\n", i, strings.Join(names, ", "))
+	}
+	return b.String()
+}
+
+// -----------------------------------------------------------------------------
+// External boundary
+// -----------------------------------------------------------------------------
+
+// BoundaryDirection tells whether a relationship leaves or enters the internal landscape.
+type BoundaryDirection string
+
+const (
+	BoundaryOutbound BoundaryDirection = "outbound" // internal element -> external element
+	BoundaryInbound  BoundaryDirection = "inbound"  // external element -> internal element
+)
+
+// BoundaryCrossing is a relationship with exactly one external endpoint.
+type BoundaryCrossing struct {
+	Relationship Relationship
+	Internal     *Node
+	External     *Node
+	Direction    BoundaryDirection
+}
+
+// isExternal reports whether n, or any of its ancestors, is marked external.
+func (d *Design) isExternal(n *Node) bool {
+	for cur := n; cur != nil; cur = d.parentOf(cur) {
+		if cur.IsExternal {
+			return true
+		}
+	}
+	return false
+}
+
+// ExternalBoundary lists every relationship where exactly one endpoint is
+// external, in the order they were added. Elements nested in an external
+// system count as external. BELONGS_TO edges are not considered crossings.
+func (d *Design) ExternalBoundary() []BoundaryCrossing {
+	byFullId := d.nodesByFullId()
+
+	var crossings []BoundaryCrossing
+	for _, rel := range d.relationships {
+		if rel.Type == RelBelongsTo {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		startExternal, endExternal := d.isExternal(start), d.isExternal(end)
+		switch {
+		case !startExternal && endExternal:
+			crossings = append(crossings, BoundaryCrossing{Relationship: rel, Internal: start, External: end, Direction: BoundaryOutbound})
+		case startExternal && !endExternal:
+			crossings = append(crossings, BoundaryCrossing{Relationship: rel, Internal: end, External: start, Direction: BoundaryInbound})
+		}
+	}
+	return crossings
+}
+
+// -----------------------------------------------------------------------------
+// Consumers
+// -----------------------------------------------------------------------------
+
+// ConsumerEntry aggregates the explicit USES relationships from one consumer
+// to a node or its descendants.
+type ConsumerEntry struct {
+	Consumer          *Node
+	ConsumerSystem    *Node    // System owning the consumer, nil for persons and top-level nodes
+	ConsumerContainer *Node    // Container owning the consumer, nil above container level
+	Targets           []*Node  // The node or descendants the consumer uses, in first-use order
+	Descriptions      []string // Distinct descriptions, in first-use order
+}
+
+// ConsumersOf returns who uses n, counting calls to any of its descendants as
+// calls to n (e.g. a call to a component counts for its container). Consumers
+// nested in n itself are not listed. There is one entry per consumer, sorted
+// by owning system, owning container and consumer.
+func (d *Design) ConsumersOf(n INode) []ConsumerEntry {
+	byFullId := d.nodesByFullId()
+	target, ok := byFullId[n.FullId()]
+	if !ok {
+		return nil
+	}
+
+	index := map[*Node]int{}
+	var entries []ConsumerEntry
+	for _, rel := range d.relationships {
+		if rel.Type != RelUses {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		if !slices.Contains(d.ancestorsOrSelf(end), target) || slices.Contains(d.ancestorsOrSelf(start), target) {
+			continue
+		}
+		i, ok := index[start]
+		if !ok {
+			i = len(entries)
+			index[start] = i
+			entries = append(entries, ConsumerEntry{
+				Consumer:          start,
+				ConsumerSystem:    d.ancestorAt(start, NodeTypeSystem),
+				ConsumerContainer: d.ancestorAt(start, NodeTypeContainer),
+			})
+		}
+		entry := &entries[i]
+		if !slices.Contains(entry.Targets, end) {
+			entry.Targets = append(entry.Targets, end)
+		}
+		if rel.Description != "" && !slices.Contains(entry.Descriptions, rel.Description) {
+			entry.Descriptions = append(entry.Descriptions, rel.Description)
+		}
+	}
+
+	fullName := func(n *Node) string {
+		if n == nil {
+			return ""
+		}
+		return n.FullName()
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if fullName(a.ConsumerSystem) != fullName(b.ConsumerSystem) {
+			return fullName(a.ConsumerSystem) < fullName(b.ConsumerSystem)
+		}
+		if fullName(a.ConsumerContainer) != fullName(b.ConsumerContainer) {
+			return fullName(a.ConsumerContainer) < fullName(b.ConsumerContainer)
+		}
+		return a.Consumer.FullName() < b.Consumer.FullName()
+	})
+	return entries
+}
+
+// ConsumersMarkdown renders ConsumersOf for each target as a Markdown table.
+func (d *Design) ConsumersMarkdown(targets ...INode) string {
+	b := strings.Builder{}
+	for i, target := range targets {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## Consumers of %s\n\n", target.FullName())
+		b.WriteString("| System | Container | Consumer | Uses | Descriptions |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, entry := range d.ConsumersOf(target) {
+			system, container := "", ""
+			if entry.ConsumerSystem != nil {
+				system = entry.ConsumerSystem.Name
+			}
+			if entry.ConsumerContainer != nil {
+				container = entry.ConsumerContainer.Name
+			}
+			uses := make([]string, 0, len(entry.Targets))
+			for _, t := range entry.Targets {
+				uses = append(uses, t.Name)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", system, container, entry.Consumer.Name,
+				strings.Join(uses, ", "), strings.Join(entry.Descriptions, "; "))
+		}
+	}
+	return b.String()
+}
+
+// -----------------------------------------------------------------------------
+// System boundaries
+// -----------------------------------------------------------------------------
+
+// CrossSystemRelationships returns the explicit USES (or custom type) and
+// INTERACTS_WITH relationships whose endpoints belong to different systems,
+// each element counting for its nearest system. Relationships with an endpoint
+// outside any system, such as a person, are left out. They are ordered by
+// start, end, type and description.
+func (d *Design) CrossSystemRelationships() []Relationship {
+	return d.relationshipsBetweenSystems(func(from, to *Node) bool { return from != to })
+}
+
+// IntraSystemRelationships returns the explicit USES (or custom type) and
+// INTERACTS_WITH relationships between two elements of sys or its
+// subsystems, ordered like CrossSystemRelationships.
+func (d *Design) IntraSystemRelationships(sys *System) []Relationship {
+	target, ok := d.nodesByFullId()[sys.FullId()]
+	if !ok {
+		return nil
+	}
+	return d.relationshipsBetweenSystems(func(from, to *Node) bool {
+		return slices.Contains(d.ancestorsOrSelf(from), target) && slices.Contains(d.ancestorsOrSelf(to), target)
+	})
+}
+
+// relationshipsBetweenSystems returns the relationships whose endpoints both
+// belong to a system, the nearest ones being accepted by keep.
+func (d *Design) relationshipsBetweenSystems(keep func(from, to *Node) bool) []Relationship {
+	byFullId := d.nodesByFullId()
+	var rels []Relationship
+	for _, rel := range sortedRelationships(d.relationships) {
+		if !usesLike(rel) && rel.Type != RelInteractsWith {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		from, to := d.systemOf(start), d.systemOf(end)
+		if from != nil && to != nil && keep(from, to) {
+			rels = append(rels, rel)
+		}
+	}
+	return rels
+}
+
+// CrossSystemMarkdown renders CrossSystemRelationships as a "Cross-system
+// integrations" Markdown section.
+func (d *Design) CrossSystemMarkdown() string {
+	byFullId := d.nodesByFullId()
+	b := strings.Builder{}
+	b.WriteString("## Cross-system integrations\n\n")
+	b.WriteString("| From system | From | To system | To | Description |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, rel := range d.CrossSystemRelationships() {
+		start, end := byFullId[rel.StartID], byFullId[rel.EndID]
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			d.ancestorAt(start, NodeTypeSystem).Name, start.Name,
+			d.ancestorAt(end, NodeTypeSystem).Name, end.Name, rel.Description)
+	}
+	return b.String()
+}
+
+// -----------------------------------------------------------------------------
+// Dependency matrix
+// -----------------------------------------------------------------------------
+
+// DependencyMatrix returns the coupling between systems: the system names,
+// ordered by FullId, and a matrix whose cell [i][j] counts the explicit USES
+// relationships (or of a custom type) from system i, or any of its elements,
+// to system j or any of its elements. Elements count towards their nearest
+// system, so subsystems have their own row; persons, even scoped to a system,
+// count towards none. The diagonal counts the relationships within a system.
+func (d *Design) DependencyMatrix() ([]string, [][]int) {
+	var systems []*Node
+	for _, node := range d.sortedNodes() {
+		if node.NodeType == NodeTypeSystem {
+			systems = append(systems, node)
+		}
+	}
+	index := make(map[*Node]int, len(systems))
+	names := make([]string, len(systems))
+	matrix := make([][]int, len(systems))
+	for i, system := range systems {
+		index[system] = i
+		names[i] = system.Name
+		matrix[i] = make([]int, len(systems))
+	}
+
+	byFullId := d.nodesByFullId()
+	for _, rel := range d.relationships {
+		if !usesLike(rel) {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		from, to := d.systemOf(start), d.systemOf(end)
+		if from == nil || to == nil {
+			continue // Persons and elements outside any system
+		}
+		matrix[index[from]][index[to]]++
+	}
+	return names, matrix
+}
+
+// NodeRank is a system ranked by TopDependedOn.
+type NodeRank struct {
+	Node *Node
+	// Incoming counts the explicit USES relationships (or of a custom type)
+	// reaching the system or any of its elements from outside it.
+	Incoming int
+	// Dependents counts the distinct systems and persons those relationships
+	// come from.
+	Dependents int
+}
+
+// TopDependedOn ranks the systems by fan-in, the most depended-on first, for
+// reliability planning. Relationships are rolled up to the nearest system of
+// both ends, as in DependencyMatrix; those within a system don't count, and
+// elements outside any system and persons, even scoped to a system, count as
+// themselves. Systems nobody depends on are left out. Ties are broken by
+// number of dependents, then by FullId. It returns at most n systems, or all
+// of them when n <= 0.
+func (d *Design) TopDependedOn(n int) []NodeRank {
+	byFullId := d.nodesByFullId()
+	incoming := map[*Node]int{}
+	dependents := map[*Node]map[*Node]struct{}{}
+	for _, rel := range d.relationships {
+		if !usesLike(rel) {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		to := d.systemOf(end)
+		from := d.systemOf(start)
+		if from == nil {
+			from = start
+		}
+		if to == nil || from == to {
+			continue
+		}
+		incoming[to]++
+		if dependents[to] == nil {
+			dependents[to] = map[*Node]struct{}{}
+		}
+		dependents[to][from] = struct{}{}
+	}
+
+	ranks := make([]NodeRank, 0, len(incoming))
+	for system, count := range incoming {
+		ranks = append(ranks, NodeRank{Node: system, Incoming: count, Dependents: len(dependents[system])})
+	}
+	slices.SortFunc(ranks, func(a, b NodeRank) int {
+		return cmp.Or(
+			cmp.Compare(b.Incoming, a.Incoming),
+			cmp.Compare(b.Dependents, a.Dependents),
+			cmp.Compare(a.Node.FullId(), b.Node.FullId()),
+		)
+	})
+	if n > 0 && len(ranks) > n {
+		ranks = ranks[:n]
+	}
+	return ranks
+}
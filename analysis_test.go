@@ -0,0 +1,170 @@
+package neoarch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newExample2Design returns the model of examples/example2: a user system and
+// a social system, each behind a gateway, whose tweet and follow services call
+// the user service.
+func newExample2Design() *Design {
+	d := NewDesign("Twitter Clone", "Social + User Systems with API Gateway, GraphQL, gRPC")
+
+	// ------------------------------
+	// USER SYSTEM
+	// ------------------------------
+	userSystem := d.System("UserSystem", "Handles user management and authentication")
+
+	userAPIGateway := userSystem.Container("User API Gateway", "HTTP entrypoint").
+		Tag("gateway")
+	userGraphQL := userSystem.Container("User GraphQL", "Orchestrates queries and mutations").
+		Tag("graphql").
+		Uses(userAPIGateway, "Receives traffic from")
+	userDB := userSystem.Container("User DB", "Stores user info").
+		Tag("db")
+	userS3 := userSystem.Container("User S3", "Stores avatars").
+		Tag("s3")
+	userTemporal := userSystem.Container("User Temporal Worker", "Handles background workflows").
+		Tag("temporal")
+
+	userService := userSystem.Container("User gRPC Service", "Handles core user operations").
+		Tag("grpc").
+		Uses(userDB, "Reads/writes user data").
+		Uses(userS3, "Stores profile images").
+		UsesAsync(userTemporal, "Schedules background jobs")
+
+	// Cross-layer
+	userGraphQL.Uses(userService, "Resolves user operations")
+
+	// GraphQL Components
+	userGraphQL.Component("Schema Definition", "Defines User types and fields")
+	userGraphQL.Component("Query Resolver", "Handles fetching user data")
+	userGraphQL.Component("Mutation Resolver", "Handles signup, update, etc.")
+	userGraphQL.Component("Middleware", "Cross-cutting GraphQL logic")
+	userGraphQL.Component("Authorization", "Enforces auth rules")
+
+	// gRPC Components
+	userService.Component("Handler", "Request-level handling")
+	userService.Component("Service", "Business logic")
+	userService.Component("Repository", "Persistence layer")
+
+	// ------------------------------
+	// SOCIAL SYSTEM
+	// ------------------------------
+	socialSystem := d.System("SocialSystem", "Handles tweets, follows, feeds")
+
+	socialAPIGateway := socialSystem.Container("Social API Gateway", "HTTP entrypoint").
+		Tag("gateway")
+	socialGraphQL := socialSystem.Container("Social GraphQL", "Manages tweet/feed queries").
+		Tag("graphql").
+		Uses(socialAPIGateway, "Receives traffic from")
+	socialDB := socialSystem.Container("Social DB", "Stores tweets, follows").
+		Tag("db")
+	socialS3 := socialSystem.Container("Social S3", "Stores tweet media").
+		Tag("s3")
+	socialTemporal := socialSystem.Container("Social Temporal Worker", "Feed generation and cleanup").
+		Tag("temporal")
+
+	tweetService := socialSystem.Container("Tweet gRPC Service", "Tweet logic").
+		Tag("grpc").
+		Uses(socialDB, "Reads/writes tweet data").
+		Uses(socialS3, "Stores media").
+		UsesAsync(socialTemporal, "Schedules tweet workflows")
+
+	followService := socialSystem.Container("Follow gRPC Service", "Follow/unfollow logic").
+		Tag("grpc").
+		Uses(socialDB, "Updates following/follower lists").
+		UsesAsync(socialTemporal, "Schedules notifications")
+
+	//Cross - layer
+	socialGraphQL.Uses(tweetService, "Resolves tweet ops")
+	socialGraphQL.Uses(followService, "Resolves follow ops")
+
+	// Inter-system call
+	tweetService.Uses(userService, "Fetch user profile info for tweets")
+	followService.Uses(userService, "Resolve target user")
+
+	// GraphQL Components
+	socialGraphQL.Component("Schema Definition", "Defines Tweet and Feed types")
+	socialGraphQL.Component("Query Resolver", "Handles fetching tweets/feed")
+	socialGraphQL.Component("Mutation Resolver", "Creates tweets, follows")
+	socialGraphQL.Component("Middleware", "Logging, timing, tracing")
+	socialGraphQL.Component("Authorization", "Check user permissions")
+
+	// gRPC Components
+	tweetService.Component("Handler", "gRPC entrypoint")
+	tweetService.Component("Service", "Tweet logic")
+	tweetService.Component("Repository", "Tweet persistence")
+
+	followService.Component("Handler", "gRPC entrypoint")
+	followService.Component("Service", "Follow logic")
+	followService.Component("Repository", "Follow persistence")
+	return d
+}
+
+func TestCrossSystemRelationships(t *testing.T) {
+	d := newExample2Design()
+	var got []string
+	for _, rel := range d.CrossSystemRelationships() {
+		got = append(got, rel.StartID+" -> "+rel.EndID)
+	}
+	want := []string{
+		"SocialSystem.SocialSystem.Follow gRPC Service -> UserSystem.UserSystem.User gRPC Service",
+		"SocialSystem.SocialSystem.Tweet gRPC Service -> UserSystem.UserSystem.User gRPC Service",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got cross-system relationships %q, want %q", got, want)
+	}
+
+	social := &System{Node: d.lookupNode("SocialSystem"), design: d}
+	intra := d.IntraSystemRelationships(social)
+	if len(intra) != 8 {
+		t.Errorf("got %d relationships within SocialSystem, want 8: %v", len(intra), intra)
+	}
+	for _, rel := range intra {
+		if !strings.HasPrefix(rel.StartID, "SocialSystem.") || !strings.HasPrefix(rel.EndID, "SocialSystem.") {
+			t.Errorf("relationship %s leaves SocialSystem", rel.Key())
+		}
+	}
+
+	out := d.ToMarkdown()
+	section := out[strings.Index(out, "## Cross-system integrations"):]
+	for _, want := range []string{
+		"| SocialSystem / Tweet gRPC Service | UserSystem / User gRPC Service | USES | Fetch user profile info for tweets |  |\n",
+		"| SocialSystem / Follow gRPC Service | UserSystem / User gRPC Service | USES | Resolve target user |  |\n",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("the cross-system section lacks %q:\n%s", want, section)
+		}
+	}
+	if out := newShopDesign().ToMarkdown(); !strings.Contains(out, "## Cross-system integrations") {
+		t.Errorf("the shop report has no cross-system section:\n%s", out)
+	}
+}
+
+func TestScopedPersonsCountTowardsNoSystem(t *testing.T) {
+	d := NewDesign("Scoped", "Scoped persons")
+	a := d.System("A", "Operated")
+	b := d.System("B", "Depended on")
+	a.Person("Ops", "Runs A").Uses(b, "Checks")
+	a.Container("Web", "Frontend").Uses(b, "Calls")
+
+	names, matrix := d.DependencyMatrix()
+	if !slices.Equal(names, []string{"A", "B"}) {
+		t.Fatalf("got systems %v", names)
+	}
+	// Only Web -> B: the person scoped to A doesn't make A depend on B
+	if matrix[0][1] != 1 {
+		t.Errorf("A -> B = %d, want 1: %v", matrix[0][1], matrix)
+	}
+
+	ranks := d.TopDependedOn(0)
+	if len(ranks) != 1 || ranks[0].Node.Name != "B" || ranks[0].Incoming != 2 || ranks[0].Dependents != 2 {
+		t.Errorf("got ranks %+v, want B used twice by A and the person", ranks)
+	}
+	if cross := d.CrossSystemRelationships(); len(cross) != 1 || cross[0].Description != "Calls" {
+		t.Errorf("got cross-system relationships %v, want Web -> B only", cross)
+	}
+}
@@ -0,0 +1,114 @@
+package neoarch
+
+import (
+	"fmt"
+)
+
+// -----------------------------------------------------------------------------
+// Edge bundling
+// -----------------------------------------------------------------------------
+
+// EdgeBundle is a set of relationships of the same type between the
+// descendants of two elements, e.g. every call from the resolvers of one
+// container to the repositories of another.
+type EdgeBundle struct {
+	StartID       string // FullId of the element the relationships start from, or one of its ancestors
+	EndID         string // FullId of the element they end at, or one of its ancestors
+	Type          RelationshipType
+	Relationships []Relationship // Bundled relationships, ordered by start, end, type and description
+}
+
+// Count returns the number of bundled relationships.
+func (b EdgeBundle) Count() int {
+	return len(b.Relationships)
+}
+
+// Relationship returns the single relationship standing for the bundle,
+// between StartID and EndID. It keeps the description of the first bundled
+// relationship, followed by the count when there are several, e.g. "Reads
+// users (14 calls)", and the technology they share, if any. A derived
+// relationship points to the first bundled one in DerivedFrom.
+func (b EdgeBundle) Relationship() Relationship {
+	first := b.Relationships[0]
+	rel := Relationship{StartID: b.StartID, EndID: b.EndID, Type: b.Type, Description: first.Description, Technology: first.Technology}
+	for _, other := range b.Relationships[1:] {
+		if other.Technology != rel.Technology {
+			rel.Technology = ""
+		}
+	}
+	if n := b.Count(); n > 1 {
+		if rel.Description == "" {
+			rel.Description = fmt.Sprintf("%d calls", n)
+		} else {
+			rel.Description = fmt.Sprintf("%s (%d calls)", rel.Description, n)
+		}
+	}
+	if b.Count() > 1 || first.StartID != b.StartID || first.EndID != b.EndID {
+		source := first.Ref()
+		if first.DerivedFrom != nil {
+			source = *first.DerivedFrom
+		}
+		rel.DerivedFrom = &source
+	}
+	return rel
+}
+
+// EdgeBundles groups the USES relationships (or of a custom type) among rels
+// by the ancestors of their endpoints at the given level, e.g. NodeTypeContainer
+// to bundle the relationships between components by container. Endpoints above
+// the level, such as persons, stand for themselves. Relationships within a
+// single element of the level are not bundled, nor are the other types. Bundles
+// are ordered by start, end and type; the relationships left out are returned
+// as they are, in the same order as rels. It does not modify the design.
+func (d *Design) EdgeBundles(rels []Relationship, level NodeType) (bundles []EdgeBundle, rest []Relationship) {
+	byFullId := d.nodesByFullId()
+	at := func(id string) string {
+		node, ok := byFullId[id]
+		if !ok {
+			return id
+		}
+		if ancestor := d.ancestorAt(node, level); ancestor != nil {
+			return ancestor.FullId()
+		}
+		return id
+	}
+
+	index := map[[3]string]int{}
+	for _, rel := range sortedRelationships(rels) {
+		if !usesLike(rel) {
+			continue
+		}
+		from, to := at(rel.StartID), at(rel.EndID)
+		if from == to {
+			continue
+		}
+		key := [3]string{from, to, string(rel.Type)}
+		i, ok := index[key]
+		if !ok {
+			i = len(bundles)
+			index[key] = i
+			bundles = append(bundles, EdgeBundle{StartID: from, EndID: to, Type: rel.Type})
+		}
+		bundles[i].Relationships = append(bundles[i].Relationships, rel)
+	}
+	for _, rel := range rels {
+		if !usesLike(rel) || at(rel.StartID) == at(rel.EndID) {
+			rest = append(rest, rel)
+		}
+	}
+	return bundles, rest
+}
+
+// bundled returns a copy of the design whose relationships are bundled at the
+// given level (see EdgeBundles), for BundleEdges.
+func (d *Design) bundled(level NodeType) *Design {
+	c := d.clone()
+	bundles, rest := c.EdgeBundles(c.relationships, level)
+	c.relationships = rest
+	for _, bundle := range bundles {
+		c.relationships = append(c.relationships, bundle.Relationship())
+	}
+	c.hierarchy = nil
+	c.relationshipIndex = nil
+	return c
+}
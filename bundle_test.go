@@ -0,0 +1,101 @@
+package neoarch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newDenseDesign returns a design where every resolver of the GraphQL and Admin
+// containers uses every repository of the Users, Orders and Catalog
+// containers: 50 relationships between components, falling into 6 pairs of
+// containers. One resolver also uses another, within GraphQL.
+func newDenseDesign() *Design {
+	d := NewDesign("Dense", "Dense component graph")
+	shop := d.System("Shop", "Online shop")
+	var resolvers []*Component
+	for _, name := range []string{"GraphQL", "Admin"} {
+		api := shop.Container(name, "API")
+		for i := range 5 {
+			resolvers = append(resolvers, api.Component(fmt.Sprintf("Resolver%d", i), "Resolves queries"))
+		}
+	}
+	repositories := map[string]int{"Users": 2, "Orders": 2, "Catalog": 1}
+	for _, name := range []string{"Users", "Orders", "Catalog"} {
+		service := shop.Container(name, "Service")
+		for i := range repositories[name] {
+			repo := service.Component(fmt.Sprintf("Repo%d", i), "Stores "+strings.ToLower(name))
+			for _, resolver := range resolvers {
+				resolver.Uses(repo, "Reads "+strings.ToLower(name))
+			}
+		}
+	}
+	resolvers[0].Uses(resolvers[1], "Delegates")
+	return d
+}
+
+func TestEdgeBundles(t *testing.T) {
+	d := newDenseDesign()
+	if got := len(usesDescriptions(d)); got != 51 {
+		t.Fatalf("the fixture has %d USES relationships, want 51", got)
+	}
+
+	bundles, rest := d.EdgeBundles(d.relationships, NodeTypeContainer)
+	var got []string
+	total := 0
+	for _, b := range bundles {
+		got = append(got, fmt.Sprintf("%s -> %s: %s", b.StartID, b.EndID, b.Relationship().Description))
+		total += b.Count()
+	}
+	want := []string{
+		"Shop.Shop.Admin -> Shop.Shop.Catalog: Reads catalog (5 calls)",
+		"Shop.Shop.Admin -> Shop.Shop.Orders: Reads orders (10 calls)",
+		"Shop.Shop.Admin -> Shop.Shop.Users: Reads users (10 calls)",
+		"Shop.Shop.GraphQL -> Shop.Shop.Catalog: Reads catalog (5 calls)",
+		"Shop.Shop.GraphQL -> Shop.Shop.Orders: Reads orders (10 calls)",
+		"Shop.Shop.GraphQL -> Shop.Shop.Users: Reads users (10 calls)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got bundles\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if total != 50 {
+		t.Errorf("the bundles hold %d relationships, want 50", total)
+	}
+	if rel := bundles[0].Relationship(); rel.DerivedFrom == nil || rel.DerivedFrom.EndID != "Shop.Shop.Catalog.Shop.Catalog.Repo0" {
+		t.Errorf("the Admin -> Catalog bundle is derived from %v", rel.DerivedFrom)
+	}
+
+	// Only the relationship within GraphQL, and the BELONGS_TO ones, are left
+	var delegates int
+	for _, rel := range rest {
+		switch {
+		case rel.Type == RelUses && rel.Description == "Delegates":
+			delegates++
+		case rel.Type != RelBelongsTo:
+			t.Errorf("unexpected relationship left out: %+v", rel)
+		}
+	}
+	if delegates != 1 {
+		t.Errorf("the relationship within GraphQL was left out %d times, want once", delegates)
+	}
+	if len(usesDescriptions(d)) != 51 {
+		t.Error("EdgeBundles modified the design")
+	}
+}
+
+func TestBundleEdgesExport(t *testing.T) {
+	d := newDenseDesign()
+	if got := strings.Count(d.ToDOT(), " -> "); got != 51 {
+		t.Errorf("the unbundled DOT output has %d edges, want 51", got)
+	}
+	out := d.ToDOT(BundleEdges(NodeTypeContainer))
+	if got := strings.Count(out, " -> "); got != 7 {
+		t.Errorf("the bundled DOT output has %d edges, want 7:\n%s", got, out)
+	}
+	if !strings.Contains(out, `label="Reads users (10 calls)"`) {
+		t.Errorf("the bundled DOT output lacks the count:\n%s", out)
+	}
+	if got := strings.Count(d.ToMermaid(BundleEdges(NodeTypeContainer)), "(10 calls)"); got != 4 {
+		t.Errorf("the bundled Mermaid output has %d edges of 10 calls, want 4", got)
+	}
+}
@@ -0,0 +1,120 @@
+package neoarch
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Canonical form
+// -----------------------------------------------------------------------------
+
+// CanonicalOption configures Canonicalize.
+type CanonicalOption func(*canonicalOptions)
+
+type canonicalOptions struct {
+	keepChildOrder bool
+}
+
+// KeepChildOrder keeps children in the order they were added instead of
+// ordering them by id.
+func KeepChildOrder() CanonicalOption {
+	return func(o *canonicalOptions) {
+		o.keepChildOrder = true
+	}
+}
+
+// Canonicalize returns a normalized copy of the design, so designs modeling the
+// same thing compare, diff and export the same whatever order they were built
+// in. Names, descriptions and technologies are trimmed, tags and labels are
+// sorted without duplicates, and relationships are ordered by start, end, type
+// and description. Children, which follow the order of their BELONGS_TO
+// relationships, are thus ordered by id, i.e. by name unless given an explicit
+// id; KeepChildOrder keeps them in the order they were added. The receiver is
+// not modified.
+func (d *Design) Canonicalize(opts ...CanonicalOption) *Design {
+	o := canonicalOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := d.clone()
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	for _, node := range c.nodes {
+		node.Name = strings.TrimSpace(node.Name)
+		node.Description = strings.TrimSpace(node.Description)
+		node.Technology = strings.TrimSpace(node.Technology)
+		node.Tags = canonicalStrings(node.Tags)
+		node.Labels = canonicalStrings(node.Labels)
+	}
+	for i := range c.relationships {
+		rel := &c.relationships[i]
+		rel.Description = strings.TrimSpace(rel.Description)
+		rel.Technology = strings.TrimSpace(rel.Technology)
+		rel.Tags = canonicalStrings(rel.Tags)
+	}
+
+	if o.keepChildOrder {
+		// BELONGS_TO relationships first, in the order they were added
+		var belongsTo, others []Relationship
+		for _, rel := range c.relationships {
+			if rel.Type == RelBelongsTo {
+				belongsTo = append(belongsTo, rel)
+			} else {
+				others = append(others, rel)
+			}
+		}
+		c.relationships = append(belongsTo, sortedRelationships(others)...)
+	} else {
+		c.relationships = sortedRelationships(c.relationships)
+	}
+	c.hierarchy = nil
+	c.relationshipIndex = nil
+	return c
+}
+
+// canonicalStrings returns the sorted, distinct values, or nil when there are none.
+func canonicalStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+// Fingerprint returns a hash of the canonical form of the design (see
+// Canonicalize), e.g. to tell in CI whether the model changed since it was
+// last saved. It covers the design id, name, description, version and
+// metadata, the attributes of the nodes and their parents, and the explicit
+// relationships; where elements were declared, settings and styles by tag are
+// left out. The hash is only meant to be compared with others from Fingerprint.
+func (d *Design) Fingerprint() string {
+	c := d.Canonicalize()
+	var b strings.Builder
+	fmt.Fprintf(&b, "design %q %q %q %q\n", c.ID, c.Name, c.Description, c.Version)
+	for _, key := range c.MetaKeys() {
+		fmt.Fprintf(&b, "meta %q %q\n", key, c.meta[key])
+	}
+	for _, node := range c.sortedNodes() {
+		parent := ""
+		if node.ParentNode != nil {
+			parent = node.ParentNode.FullId()
+		}
+		fmt.Fprintf(&b, "node %q %q %q %q %q %q %v %q %q %q %q %q %v %q\n",
+			node.FullId(), parent, node.NodeType, node.Name, node.Description, node.Technology, node.IsExternal,
+			node.Tags, node.Labels, node.Appearance, node.Snippet, node.Layout, node.Deprecated, node.DeprecationReason)
+	}
+	for _, rel := range c.relationships {
+		fmt.Fprintf(&b, "rel %q %q %q %q %q %q %v %q %d %v\n",
+			rel.StartID, rel.EndID, rel.Type, rel.Description, rel.Technology, rel.Tags, rel.NoImplied,
+			rel.InteractionStyle, rel.Weight, rel.Optional)
+		for _, key := range slices.Sorted(maps.Keys(rel.Properties)) {
+			fmt.Fprintf(&b, "prop %q %#v\n", key, rel.Properties[key])
+		}
+	}
+	return MD5(b.String())
+}
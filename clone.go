@@ -0,0 +1,80 @@
+package neoarch
+
+import (
+	"fmt"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Cloning
+// -----------------------------------------------------------------------------
+
+// Clone returns a deep copy of the design under a new id and name, e.g. to
+// derive a variant of a design and edit it with the usual DSL, RemoveNode and
+// RemoveRelationship. The copy shares no node, slice or map with the receiver,
+// so changing one never changes the other. Scenarios, settings, styles and
+// dynamic views are copied too.
+//
+// Only the design root is renamed: nested FullIds derive from the top-level
+// elements, not from the design, so they are the same in both designs, and
+// relationships to the root point to the new one.
+func (d *Design) Clone(newID, newName string) *Design {
+	c := d.clone()
+	c.ID, c.Name = newID, newName
+
+	if root, ok := c.nodes[d.ID]; ok {
+		delete(c.nodes, d.ID)
+		root.ID, root.Name = newID, newName
+		c.nodes[newID] = root
+		for i, rel := range c.relationships {
+			if rel.StartID == d.ID {
+				c.relationships[i].StartID = newID
+			}
+			if rel.EndID == d.ID {
+				c.relationships[i].EndID = newID
+			}
+		}
+	}
+
+	if d.scenarios != nil {
+		c.scenarios = make(map[string]*Scenario, len(d.scenarios))
+		for name, s := range d.scenarios {
+			delta := s.delta
+			delta.AddedNodes = slices.Clone(delta.AddedNodes)
+			delta.RemovedNodes = slices.Clone(delta.RemovedNodes)
+			delta.ChangedNodes = slices.Clone(delta.ChangedNodes)
+			delta.AddedRelationships = slices.Clone(delta.AddedRelationships)
+			delta.RemovedRelationships = slices.Clone(delta.RemovedRelationships)
+			c.scenarios[name] = &Scenario{Name: name, design: c, delta: delta}
+		}
+	}
+	c.hierarchy = nil
+	c.relationshipIndex = nil
+	return c
+}
+
+// RemoveNode removes the node with the given ID or FullId, its descendants and
+// every relationship touching them. The design root cannot be removed.
+func (d *Design) RemoveNode(id string) error {
+	node := d.lookupNode(id)
+	if node == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, id)
+	}
+	if node.ID == d.ID && node.NodeType == NodeTypeDesign {
+		return fmt.Errorf("cannot remove the design root %s", d.ID)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.removeNode(node)
+	return nil
+}
+
+// RemoveRelationship removes the relationship, and reports whether the design
+// had it.
+func (d *Design) RemoveRelationship(ref RelationshipRef) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.relationships)
+	d.removeRelationship(ref.Key())
+	return len(d.relationships) < n
+}
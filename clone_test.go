@@ -0,0 +1,129 @@
+package neoarch
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCloneIsIndependent(t *testing.T) {
+	d := newShopDesign()
+	d.relationships[0].Tags = []string{"web"}
+	d.relationships[0].Properties = map[string]any{"sla_ms": 200}
+	before := d.Fingerprint()
+
+	c := d.Clone("design_ShopEU", "Shop EU")
+	if c.ID != "design_ShopEU" || c.Name != "Shop EU" {
+		t.Errorf("the clone is %s %q", c.ID, c.Name)
+	}
+	if root := c.lookupNode("design_ShopEU"); root == nil || root.FullId() != "design_ShopEU" || root.Name != "Shop EU" {
+		t.Errorf("the clone root is %+v", root)
+	}
+	if c.lookupNode(d.ID) != nil {
+		t.Errorf("the clone still has the root %s", d.ID)
+	}
+	if c.Fingerprint() == before {
+		t.Error("the clone has the fingerprint of the original")
+	}
+	if got, want := c.RelationshipCount(), d.RelationshipCount(); got != want {
+		t.Errorf("the clone has %d relationships, want %d", got, want)
+	}
+	api := c.lookupNode("Shop.Shop.API")
+	if api == nil || api == d.lookupNode("Shop.Shop.API") || api.design != c {
+		t.Fatalf("the clone API is %+v", api)
+	}
+	if orders := c.lookupNode("Shop.Shop.API.Shop.API.Orders"); orders.ParentNode != api {
+		t.Errorf("the clone Orders belongs to %v, want the clone API", orders.ParentNode)
+	}
+
+	// The EU variant: swap a container, change the external system and edit
+	// the copied slices and maps in place
+	shop := &System{Node: c.lookupNode("Shop"), design: c}
+	(&Container{Node: api, system: shop}).Tag("eu").WithTechnology("Rust")
+	if err := c.RemoveNode("Shop.Shop.DB"); err != nil {
+		t.Fatal(err)
+	}
+	db := shop.Container("DB", "EU orders").WithTechnology("Spanner")
+	(&Component{Node: c.lookupNode("Shop.Shop.API.Shop.API.Orders")}).Uses(db, "Reads and writes")
+	c.lookupNode("Payments").Description = "Takes EU payments"
+	c.lookupNode("Payments").Labels = append(c.lookupNode("Payments").Labels, "Psp")
+	c.relationships[0].Tags[0] = "eu"
+	c.relationships[0].Properties["sla_ms"] = 100
+
+	if after := d.Fingerprint(); after != before {
+		t.Error("editing the clone changed the original")
+	}
+	if n := d.lookupNode("Shop.Shop.API"); slices.Contains(n.Tags, "eu") || n.Technology != "Go" {
+		t.Errorf("the original API is %+v", n)
+	}
+	if n := d.lookupNode("Shop.Shop.DB"); n == nil || n.Technology != "Postgres" {
+		t.Errorf("the original DB is %+v", n)
+	}
+	if rel := d.relationships[0]; rel.Tags[0] != "web" || rel.Properties["sla_ms"] != 200 {
+		t.Errorf("the original relationship is %+v", rel)
+	}
+
+	// And the other way around
+	cloned := c.Fingerprint()
+	d.lookupNode("Shop.Shop.Web").Tag("us")
+	d.relationships[0].Tags[0] = "us"
+	if c.Fingerprint() != cloned {
+		t.Error("editing the original changed the clone")
+	}
+	if issues := c.Validate(); len(issues) != 0 {
+		t.Errorf("unexpected issues in the clone: %v", issues)
+	}
+}
+
+func TestRemoveNode(t *testing.T) {
+	d := newShopDesign()
+	if err := d.RemoveNode("Shop.Shop.API"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"Shop.Shop.API", "Shop.Shop.API.Shop.API.Orders", "Shop.Shop.API.Shop.API.Billing"} {
+		if d.lookupNode(id) != nil {
+			t.Errorf("%s was not removed", id)
+		}
+	}
+	for _, rel := range d.relationships {
+		for _, id := range []string{rel.StartID, rel.EndID} {
+			if d.lookupNode(id) == nil {
+				t.Errorf("relationship %s -> %s touches the removed %s", rel.StartID, rel.EndID, id)
+			}
+		}
+	}
+	if got := usesDescriptions(d); !slices.Equal(got, []string{"Browses"}) {
+		t.Errorf("got USES relationships %v, want Browses only", got)
+	}
+
+	if err := d.RemoveNode(d.ID); err == nil {
+		t.Error("the design root was removed")
+	}
+	if err := d.RemoveNode("Nowhere"); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("removing an unknown node returned %v, want ErrUnknownNode", err)
+	}
+	if issues := d.Validate(); len(issues) != 0 {
+		t.Errorf("unexpected issues after removing: %v", issues)
+	}
+}
+
+func TestRemoveRelationship(t *testing.T) {
+	d := newShopDesign()
+	ref := RelationshipRef{StartID: "Shop.Shop.Web", EndID: "Shop.Shop.API", Type: RelUses, Description: "Calls"}
+	if !d.RemoveRelationship(ref) {
+		t.Fatal("the relationship was not removed")
+	}
+	if d.RemoveRelationship(ref) {
+		t.Error("removing the relationship twice succeeded")
+	}
+	if slices.Contains(usesDescriptions(d), "Calls") {
+		t.Error("the relationship is still stored")
+	}
+
+	// The duplicate index forgets it too, so it can be added again
+	web := &Container{Node: d.lookupNode("Shop.Shop.Web")}
+	web.Uses(d.lookupNode("Shop.Shop.API"), "Calls")
+	if !slices.Contains(usesDescriptions(d), "Calls") {
+		t.Error("the relationship could not be added again")
+	}
+}
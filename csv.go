@@ -0,0 +1,223 @@
+package neoarch
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// CSV files
+// -----------------------------------------------------------------------------
+
+// csvNodeHeader and csvRelationshipHeader are the columns of the two CSV files
+// written by ToCSV and read by ImportCSV.
+var (
+	csvNodeHeader         = []string{"id", "parent", "type", "name", "description", "technology", "tags", "external"}
+	csvRelationshipHeader = []string{"start", "end", "type", "description", "technology", "tags"}
+)
+
+// csvTagSeparator separates the tags of an element in a single cell.
+const csvTagSeparator = ";"
+
+// ToCSV writes the design as two CSV files, e.g. to edit it in a spreadsheet and
+// read it back with ImportCSV. The nodes file lists one node per row, design
+// root included, ordered by FullId: its FullId, the FullId of its parent ("" for
+// top-level nodes), type, name, description, technology, tags separated by ";"
+// and whether it is external. The relationships file lists the explicit
+// relationships, BELONGS_TO included, ordered by start, end, type and
+// description: start and end FullIds, type, description, technology and tags.
+// Both start with a header row. Styles, labels, snippets, layout hints and the
+// other relationship attributes are not written.
+func (d *Design) ToCSV(nodes, rels io.Writer) error {
+	nw := csv.NewWriter(nodes)
+	nw.Write(csvNodeHeader)
+	for _, node := range d.sortedNodes() {
+		parent := ""
+		if p := d.parentOf(node); p != nil {
+			parent = p.FullId()
+		}
+		nw.Write([]string{
+			node.FullId(), parent, string(node.NodeType), node.Name, node.Description, node.Technology,
+			strings.Join(node.Tags, csvTagSeparator), strconv.FormatBool(node.IsExternal),
+		})
+	}
+	nw.Flush()
+	if err := nw.Error(); err != nil {
+		return err
+	}
+
+	rw := csv.NewWriter(rels)
+	rw.Write(csvRelationshipHeader)
+	for _, rel := range sortedRelationships(d.relationships) {
+		rw.Write([]string{rel.StartID, rel.EndID, string(rel.Type), rel.Description, rel.Technology, strings.Join(rel.Tags, csvTagSeparator)})
+	}
+	rw.Flush()
+	return rw.Error()
+}
+
+// ImportCSV rebuilds a design from the two CSV files written by ToCSV. The
+// design takes the id, name and description of the Design node without a
+// parent. A node id must extend the id of its parent, e.g. "Shop.Shop.API"
+// under "Shop", as ToCSV writes them. Every problem found is reported, with the
+// file and line it is on: unknown columns, duplicate ids, unknown parents,
+// node types that are not valid labels (see ValidLabel), invalid relationship
+// types and relationships whose endpoints are not in the nodes file.
+func ImportCSV(nodes, rels io.Reader) (*Design, error) {
+	var errs []error
+	fail := func(file string, line int, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("csv: %s line %d: %s", file, line, fmt.Sprintf(format, args...)))
+	}
+	splitTags := func(cell string) []string {
+		var tags []string
+		for _, tag := range strings.Split(cell, csvTagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		return tags
+	}
+
+	nodeRows, err := readCSV("nodes", nodes, csvNodeHeader)
+	if err != nil {
+		return nil, err
+	}
+	relRows, err := readCSV("relationships", rels, csvRelationshipHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	type importedNode struct {
+		node   *Node
+		parent string // FullId
+		line   int
+	}
+	d := &Design{nodes: map[string]*Node{}}
+	byFullId := map[string]*Node{}
+	var imported []importedNode
+	for _, row := range nodeRows {
+		id := row.cells[0]
+		if id == "" {
+			fail("nodes", row.line, "empty id")
+			continue
+		}
+		if _, ok := byFullId[id]; ok {
+			fail("nodes", row.line, "duplicate id %q", id)
+			continue
+		}
+		node := &Node{
+			ID:          id,
+			Name:        row.cells[3],
+			Description: row.cells[4],
+			NodeType:    NodeType(row.cells[2]),
+			Technology:  row.cells[5],
+			Tags:        splitTags(row.cells[6]),
+			design:      d,
+		}
+		if row.cells[7] != "" {
+			external, err := strconv.ParseBool(row.cells[7])
+			if err != nil {
+				fail("nodes", row.line, "invalid external value %q", row.cells[7])
+			}
+			node.IsExternal = external
+		}
+		switch {
+		case node.NodeType == "":
+			fail("nodes", row.line, "node %q has no type", id)
+		case !ValidLabel(string(node.NodeType)):
+			// The type is saved as the Neo4j label of the node
+			fail("nodes", row.line, "node %q has the invalid type %q", id, node.NodeType)
+		}
+		if node.NodeType == NodeTypeDesign && row.cells[1] == "" && d.ID == "" {
+			d.ID, d.Name, d.Description = id, node.Name, node.Description
+		}
+		byFullId[id] = node
+		imported = append(imported, importedNode{node: node, parent: row.cells[1], line: row.line})
+	}
+	if d.ID == "" && len(nodeRows) > 0 {
+		fail("nodes", nodeRows[0].line, "no Design node without a parent")
+	}
+
+	// Parents may be listed after their children
+	for _, in := range imported {
+		node := in.node
+		if in.parent != "" {
+			parent, ok := byFullId[in.parent]
+			switch {
+			case !ok:
+				fail("nodes", in.line, "node %q has an unknown parent %q", node.ID, in.parent)
+			case !strings.HasPrefix(node.ID, in.parent+"."):
+				fail("nodes", in.line, "node %q does not extend the id of its parent %q", node.ID, in.parent)
+			default:
+				node.ParentNode = parent
+				node.ID = strings.TrimPrefix(node.ID, in.parent+".")
+			}
+		}
+		d.nodes[node.ID] = node
+	}
+
+	for _, row := range relRows {
+		rel := Relationship{
+			StartID:     row.cells[0],
+			EndID:       row.cells[1],
+			Type:        RelationshipType(row.cells[2]),
+			Description: row.cells[3],
+			Technology:  row.cells[4],
+			Tags:        splitTags(row.cells[5]),
+		}
+		if rel.Type == "" || SanitizeRelationshipType(string(rel.Type)) != rel.Type {
+			fail("relationships", row.line, "invalid relationship type %q", rel.Type)
+			continue
+		}
+		for _, id := range []string{rel.StartID, rel.EndID} {
+			if _, ok := byFullId[id]; !ok {
+				fail("relationships", row.line, "%s relationship %s -> %s references the unknown node %q", rel.Type, rel.StartID, rel.EndID, id)
+			}
+		}
+		d.relationships = append(d.relationships, rel)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return d, nil
+}
+
+// csvRow is a data row of a CSV file with its line number.
+type csvRow struct {
+	line  int
+	cells []string
+}
+
+// readCSV reads the data rows of a CSV file whose header must be the given one.
+func readCSV(file string, r io.Reader, header []string) ([]csvRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(header)
+	got, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("csv: %s: empty file", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csv: %s: %w", file, err)
+	}
+	if !slices.Equal(got, header) {
+		return nil, fmt.Errorf("csv: %s line 1: header is %q, want %q", file, got, header)
+	}
+
+	var rows []csvRow
+	for {
+		cells, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv: %s: %w", file, err)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, csvRow{line: line, cells: cells})
+	}
+}
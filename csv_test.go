@@ -0,0 +1,84 @@
+package neoarch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	d := newShopDesign()
+	d.lookupNode("Shop.Shop.API").Tag("grpc")
+	d.lookupNode("Shop.Shop.API").Tag("core")
+	api := &Container{Node: d.lookupNode("Shop.Shop.API")}
+	api.Custom("KafkaTopic", "Events", "Order events")
+
+	var nodes, rels strings.Builder
+	if err := d.ToCSV(&nodes, &rels); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportCSV(strings.NewReader(nodes.String()), strings.NewReader(rels.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported.Equal(d) {
+		t.Errorf("the imported design differs: %+v", Diff(d, imported))
+	}
+
+	var nodesAgain, relsAgain strings.Builder
+	if err := imported.ToCSV(&nodesAgain, &relsAgain); err != nil {
+		t.Fatal(err)
+	}
+	if nodesAgain.String() != nodes.String() || relsAgain.String() != rels.String() {
+		t.Errorf("writing the imported design gave\n%s\n%s\nwant\n%s\n%s", &nodesAgain, &relsAgain, &nodes, &rels)
+	}
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	nodes := strings.Join([]string{
+		"id,parent,type,name,description,technology,tags,external",
+		"design_Shop,,Design,Shop,Online shop,,,false",
+		"Shop,,System,Shop,Sells things,,,false",
+		"Shop.Shop.API,Shop,Container) DETACH DELETE n //,API,Backend,,,false",
+		"Shop.Shop.Web,Shop,,Web,Storefront,,,false",
+		"Shop,,System,Shop,Again,,,false",
+		"Shop.Shop.DB,Nowhere,Container,DB,Orders,,,false",
+		"Shop.Shop.Cache,Shop,Container,Cache,Hot orders,,,maybe",
+	}, "\n")
+	rels := strings.Join([]string{
+		"start,end,type,description,technology,tags",
+		"Shop.Shop.Web,Shop.Shop.API,USES,Calls,,",
+		"Shop.Shop.Web,Shop.Shop.Gone,USES,Calls,,",
+		"Shop.Shop.Web,Shop.Shop.API,USES; DROP,Calls,,",
+	}, "\n")
+
+	_, err := ImportCSV(strings.NewReader(nodes), strings.NewReader(rels))
+	if err == nil {
+		t.Fatal("the import succeeded")
+	}
+	for _, want := range []string{
+		`csv: nodes line 4: node "Shop.Shop.API" has the invalid type "Container) DETACH DELETE n //"`,
+		`csv: nodes line 5: node "Shop.Shop.Web" has no type`,
+		`csv: nodes line 6: duplicate id "Shop"`,
+		`csv: nodes line 7: node "Shop.Shop.DB" has an unknown parent "Nowhere"`,
+		`csv: nodes line 8: invalid external value "maybe"`,
+		`csv: relationships line 3: USES relationship Shop.Shop.Web -> Shop.Shop.Gone references the unknown node "Shop.Shop.Gone"`,
+		`csv: relationships line 4: invalid relationship type "USES; DROP"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("the error lacks %s:\n%v", want, err)
+		}
+	}
+	if got := strings.Count(err.Error(), "\n") + 1; got != 7 {
+		t.Errorf("got %d errors, want 7:\n%v", got, err)
+	}
+
+	for _, tt := range []struct{ name, nodes, rels, want string }{
+		{"header", "id,name\n", "start,end,type,description,technology,tags\n", "csv: nodes"},
+		{"empty", "", "start,end,type,description,technology,tags\n", "csv: nodes: empty file"},
+		{"no design", "id,parent,type,name,description,technology,tags,external\nShop,,System,Shop,,,,\n", "start,end,type,description,technology,tags\n", "csv: nodes line 2: no Design node without a parent"},
+	} {
+		if _, err := ImportCSV(strings.NewReader(tt.nodes), strings.NewReader(tt.rels)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %s", tt.name, err, tt.want)
+		}
+	}
+}
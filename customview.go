@@ -0,0 +1,59 @@
+package neoarch
+
+import "slices"
+
+// -----------------------------------------------------------------------------
+// Custom views
+// -----------------------------------------------------------------------------
+
+// CustomView is a static view of hand-picked elements, e.g. those taking part
+// in checkout, rendered next to the generated Structurizr views: a system
+// landscape view, or a container or component view when scoped to a system or
+// a container. Elements that were not emitted, e.g. filtered out by an export
+// option, are left out of the view, and a view left with nothing to show is
+// skipped; both are reported by ToStructurizrDSLWithWarnings.
+type CustomView struct {
+	Key         string
+	Description string
+	scope       string   // FullId of the system or container, "" for the whole landscape
+	include     []string // FullIds, in the order they were included
+}
+
+// CustomView returns the custom view with the given key, creating it on first
+// use. scope is the system or container the view is about; nil scopes it to
+// the whole landscape. The scope and description of an existing view are kept.
+func (d *Design) CustomView(key, description string, scope INode) *CustomView {
+	for _, view := range d.customViews {
+		if view.Key == key {
+			return view
+		}
+	}
+	view := &CustomView{Key: key, Description: description}
+	if scope != nil {
+		view.scope = scope.FullId()
+	}
+	d.customViews = append(d.customViews, view)
+	return view
+}
+
+// Include adds elements to the view. Elements already included are ignored.
+func (v *CustomView) Include(nodes ...INode) *CustomView {
+	for _, n := range nodes {
+		if id := n.FullId(); !slices.Contains(v.include, id) {
+			v.include = append(v.include, id)
+		}
+	}
+	return v
+}
+
+// Scope returns the FullId of the system or container the view is about, ""
+// for the whole landscape.
+func (v *CustomView) Scope() string {
+	return v.scope
+}
+
+// Elements returns the FullIds of the included elements, in the order they
+// were included.
+func (v *CustomView) Elements() []string {
+	return slices.Clone(v.include)
+}
@@ -0,0 +1,118 @@
+package neoarch
+
+import (
+	"cmp"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Diff
+// -----------------------------------------------------------------------------
+
+// NodeChange is a node property with different values in two designs.
+type NodeChange struct {
+	NodeID   string // FullId of the node
+	Property string // "name", "description", "tags", "external" or "technology"
+	Old      any
+	New      any
+}
+
+// DesignDiff lists what changed from one design to another. Nodes are matched
+// by FullId and relationships by Key.
+type DesignDiff struct {
+	AddedNodes           []*Node // Nodes of the new design, sorted by FullId
+	RemovedNodes         []*Node // Nodes of the old design, sorted by FullId
+	ChangedNodes         []NodeChange
+	AddedRelationships   []Relationship // Ordered by start, end, type and description
+	RemovedRelationships []Relationship // Ordered by start, end, type and description
+}
+
+// IsEmpty reports whether the designs model the same nodes and relationships.
+func (diff DesignDiff) IsEmpty() bool {
+	return len(diff.AddedNodes) == 0 && len(diff.RemovedNodes) == 0 && len(diff.ChangedNodes) == 0 &&
+		len(diff.AddedRelationships) == 0 && len(diff.RemovedRelationships) == 0
+}
+
+// Diff compares two designs in memory, e.g. a design and one of its scenarios
+// (see Design.Materialize). The design root nodes are not compared. Both
+// designs are compared in their canonical form (see Design.Canonicalize), so
+// whitespace around names and the order of tags are not changes, and the nodes
+// and relationships reported are those of the canonical copies.
+func Diff(from, to *Design) DesignDiff {
+	from, to = from.Canonicalize(), to.Canonicalize()
+	oldNodes, newNodes := from.nodesByFullId(), to.nodesByFullId()
+
+	diff := DesignDiff{}
+	for id, node := range newNodes {
+		if node.NodeType == NodeTypeDesign {
+			continue
+		}
+		old, ok := oldNodes[id]
+		if !ok {
+			diff.AddedNodes = append(diff.AddedNodes, node)
+			continue
+		}
+		diff.ChangedNodes = append(diff.ChangedNodes, nodeChanges(old, node)...)
+	}
+	for id, node := range oldNodes {
+		if _, ok := newNodes[id]; !ok && node.NodeType != NodeTypeDesign {
+			diff.RemovedNodes = append(diff.RemovedNodes, node)
+		}
+	}
+	sortNodes(diff.AddedNodes)
+	sortNodes(diff.RemovedNodes)
+	slices.SortStableFunc(diff.ChangedNodes, func(a, b NodeChange) int { return cmp.Compare(a.NodeID, b.NodeID) })
+
+	oldRels, newRels := from.relIndex().byKey, to.relIndex().byKey
+	for _, rel := range to.relationships {
+		if _, ok := oldRels[rel.Key()]; !ok {
+			diff.AddedRelationships = append(diff.AddedRelationships, rel)
+		}
+	}
+	for _, rel := range from.relationships {
+		if _, ok := newRels[rel.Key()]; !ok {
+			diff.RemovedRelationships = append(diff.RemovedRelationships, rel)
+		}
+	}
+	return diff
+}
+
+// nodeChanges lists the properties of a node that differ between two versions.
+func nodeChanges(old, node *Node) []NodeChange {
+	var changes []NodeChange
+	change := func(property string, o, n any) {
+		changes = append(changes, NodeChange{NodeID: node.FullId(), Property: property, Old: o, New: n})
+	}
+	if old.Name != node.Name {
+		change("name", old.Name, node.Name)
+	}
+	if old.Description != node.Description {
+		change("description", old.Description, node.Description)
+	}
+	if !slices.Equal(old.Tags, node.Tags) {
+		change("tags", slices.Clone(old.Tags), slices.Clone(node.Tags))
+	}
+	if old.IsExternal != node.IsExternal {
+		change("external", old.IsExternal, node.IsExternal)
+	}
+	if old.Technology != node.Technology {
+		change("technology", old.Technology, node.Technology)
+	}
+	return changes
+}
+
+// applyNodeChange sets a property reported by nodeChanges.
+func applyNodeChange(n *Node, change NodeChange) {
+	switch change.Property {
+	case "name":
+		n.Name = change.New.(string)
+	case "description":
+		n.Description = change.New.(string)
+	case "tags":
+		n.Tags = slices.Clone(change.New.([]string))
+	case "external":
+		n.IsExternal = change.New.(bool)
+	case "technology":
+		n.Technology = change.New.(string)
+	}
+}
@@ -0,0 +1,215 @@
+package neoarch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Graphviz DOT export
+// -----------------------------------------------------------------------------
+
+// ToDOT renders the design as a Graphviz graph (https://graphviz.org), e.g. to
+// render it with `dot -Tsvg`. Elements with children, such as systems and
+// containers, are rendered as clusters holding them, code elements included;
+// the others as nodes labeled with their name, type and technology, filled
+// with their style (see Node.Style), or the default of their type. Each
+// relationship is an edge of its own, dashed when asynchronous (see UsesAsync)
+// and dotted when optional (see UsesOptional), optional winning when both; an
+// edge to or from an element rendered as a cluster is clipped at the cluster
+// boundary.
+//
+// The layout hints of nodes (see Node.LayoutHint) become rank constraints: a
+// Rank puts the node on the first (source) or last (sink) rank, and the nodes
+// of a Group without a Rank share a rank. Clusters can't be ranked, so hints
+// on elements with children are ignored.
+//
+// Failures, such as a missing design node, are logged and rendered as a DOT
+// comment. It is the same as d.Export("dot", w, opts...).
+func (d *Design) ToDOT(opts ...ExportOption) string {
+	b := strings.Builder{}
+	if err := d.Export(DOTExporter{}.Name(), &b, opts...); err != nil {
+		d.logger().Error("dot: export failed", "design", d.ID, "error", err)
+		return "// " + err.Error() + "\n"
+	}
+	return b.String()
+}
+
+// DOTExporter is the Exporter behind ToDOT, registered as "dot".
+type DOTExporter struct{}
+
+func init() {
+	RegisterExporter(DOTExporter{})
+}
+
+// Name implements Exporter.
+func (DOTExporter) Name() string {
+	return "dot"
+}
+
+// Export implements Exporter.
+func (DOTExporter) Export(v *DesignView, out io.Writer) error {
+	root := v.Root()
+	if root == nil {
+		return fmt.Errorf("%w: %s", ErrDesignNodeNotFound, v.ID())
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(root.Name))
+	fmt.Fprintln(w, "    compound=true")
+	// Lets rank constraints apply to nodes of different clusters
+	fmt.Fprintln(w, "    newrank=true")
+	fmt.Fprintln(w, "    rankdir=LR")
+	fmt.Fprintln(w, `    node [shape=box, style="rounded,filled", fillcolor="#ffffff", fontname="Helvetica"]`)
+	fmt.Fprintln(w, `    edge [fontname="Helvetica", fontsize=10]`)
+
+	clusters := map[string]bool{} // FullIds of the elements rendered as clusters
+	indent := func(depth int) string { return strings.Repeat("    ", depth) }
+	var emit func(n *Node, depth int)
+	emit = func(n *Node, depth int) {
+		children := v.Children(n)
+		if len(children) == 0 {
+			fmt.Fprintf(w, "%s%s [label=%s%s]\n", indent(depth), dotQuote(n.FullId()), dotQuote(dotLabel(n, v.Technology(n))), dotStyle(v.Style(n)))
+			return
+		}
+		clusters[n.FullId()] = true
+		fmt.Fprintf(w, "%ssubgraph %s {\n", indent(depth), dotQuote("cluster_"+n.FullId()))
+		fmt.Fprintf(w, "%slabel=%s\n", indent(depth+1), dotQuote(dotLabel(n, v.Technology(n))))
+		if stroke := v.Style(n).Stroke; stroke != "" {
+			fmt.Fprintf(w, "%scolor=%s\n", indent(depth+1), dotQuote(stroke))
+		}
+		// Edges of the element start or end at this invisible anchor, clipped at the cluster
+		fmt.Fprintf(w, "%s%s [shape=point, style=invis, width=0]\n", indent(depth+1), dotQuote(n.FullId()))
+		for _, child := range children {
+			emit(child, depth+1)
+		}
+		fmt.Fprintf(w, "%s}\n", indent(depth))
+	}
+	emitted := map[string]bool{}
+	v.Walk(func(n *Node, depth int) error {
+		emitted[n.FullId()] = true
+		if depth == 1 {
+			emit(n, 1)
+		}
+		return nil
+	})
+	writeDOTRanks(w, v, clusters)
+
+	for _, rel := range v.Relationships() {
+		if !emitted[rel.StartID] || !emitted[rel.EndID] || v.IsParentEdge(rel) {
+			continue
+		}
+		var attrs []string
+		label := rel.Description
+		if rel.Technology != "" {
+			label += "\n[" + rel.Technology + "]"
+		}
+		if label != "" {
+			attrs = append(attrs, "label="+dotQuote(label))
+		}
+		switch {
+		case rel.Optional:
+			attrs = append(attrs, "style=dotted")
+		case rel.InteractionStyle == InteractionAsynchronous:
+			attrs = append(attrs, "style=dashed")
+		}
+		if clusters[rel.StartID] {
+			attrs = append(attrs, "ltail="+dotQuote("cluster_"+rel.StartID))
+		}
+		if clusters[rel.EndID] {
+			attrs = append(attrs, "lhead="+dotQuote("cluster_"+rel.EndID))
+		}
+		fmt.Fprintf(w, "    %s -> %s", dotQuote(rel.StartID), dotQuote(rel.EndID))
+		if len(attrs) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// writeDOTRanks writes the rank constraints of the layout hints of the nodes
+// that aren't clusters: the source and sink ranks, then a shared rank per
+// group, by group name.
+func writeDOTRanks(w io.Writer, v *DesignView, clusters map[string]bool) {
+	ranks := map[LayoutRank][]string{}
+	groups := map[string][]string{}
+	v.Walk(func(n *Node, _ int) error {
+		switch h := n.Layout; {
+		case clusters[n.FullId()]:
+		case h.Rank == RankSource || h.Rank == RankSink:
+			ranks[h.Rank] = append(ranks[h.Rank], dotQuote(n.FullId()))
+		case h.Group != "":
+			groups[h.Group] = append(groups[h.Group], dotQuote(n.FullId()))
+		}
+		return nil
+	})
+	for _, rank := range []LayoutRank{RankSource, RankSink} {
+		if ids := ranks[rank]; len(ids) > 0 {
+			fmt.Fprintf(w, "    { rank=%s; %s }\n", rank, strings.Join(ids, "; "))
+		}
+	}
+	names := slices.Sorted(maps.Keys(groups))
+	for _, name := range names {
+		fmt.Fprintf(w, "    { rank=same; %s } // %s\n", strings.Join(groups[name], "; "), strings.ReplaceAll(name, "\n", " "))
+	}
+}
+
+// dotShapes maps the shapes of element styles to Graphviz shapes. Shapes
+// missing here are drawn as boxes.
+var dotShapes = map[string]string{
+	ShapeBox:       "box",
+	ShapeCircle:    "circle",
+	ShapeEllipse:   "ellipse",
+	ShapeHexagon:   "hexagon",
+	ShapeCylinder:  "cylinder",
+	ShapePerson:    "ellipse",
+	ShapeFolder:    "folder",
+	ShapeComponent: "component",
+}
+
+// dotStyle returns the attributes drawing a node with the given style: its
+// shape, fillcolor and a readable fontcolor, and its stroke as color. Nodes
+// without a shape keep the default rounded box.
+func dotStyle(style ElementStyle) string {
+	var attrs string
+	if shape, ok := dotShapes[style.Shape]; ok {
+		attrs += ", shape=" + shape
+		if style.Shape == ShapeBox {
+			attrs += `, style="filled"`
+		}
+	}
+	if style.Background != "" {
+		attrs += ", fillcolor=" + dotQuote(style.Background) + ", fontcolor=" + dotQuote(textColor(style.Background))
+	}
+	if style.Stroke != "" {
+		attrs += ", color=" + dotQuote(style.Stroke)
+	}
+	return attrs
+}
+
+// dotLabel returns the label of a node: its name, then its type and technology.
+func dotLabel(n *Node, technology string) string {
+	kind := string(n.NodeType)
+	if technology != "" {
+		kind += ": " + technology
+	}
+	return n.Name + "\n[" + kind + "]"
+}
+
+// dotQuoteReplacer escapes a string for a quoted DOT id; newlines become the
+// \n line break of labels.
+var dotQuoteReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+// dotQuote returns s as a quoted DOT id.
+func dotQuote(s string) string {
+	return `"` + dotQuoteReplacer.Replace(s) + `"`
+}
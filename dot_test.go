@@ -0,0 +1,164 @@
+package neoarch
+
+import (
+	"strings"
+	"testing"
+)
+
+// newCodeDesign returns the shop fixture with two code elements in the Orders
+// component, one using the other.
+func newCodeDesign() *Design {
+	d := newShopDesign()
+	orders := &Component{Node: d.lookupNode("Shop.Shop.API.Shop.API.Orders")}
+	handler := orders.Code("OrderHandler", "HTTP handler", "Go struct")
+	repo := orders.Code("OrderRepo", "Repository", "Go struct")
+	handler.Uses(repo, "Loads orders")
+	return d
+}
+
+func TestToDOT(t *testing.T) {
+	out := newShopDesign().ToDOT()
+	for _, want := range []string{
+		"digraph \"Shop\" {\n",
+		`    subgraph "cluster_Shop" {`,
+		`        subgraph "cluster_Shop.Shop.API" {`,
+		`    "person_Customer" [label="Customer\n[Person]", shape=ellipse, fillcolor="#08427b", fontcolor="#ffffff"]`,
+		`    "person_Customer" -> "Shop.Shop.Web" [label="Browses"]`,
+		`    "Shop.Shop.Web" -> "Shop.Shop.API" [label="Calls", lhead="cluster_Shop.Shop.API"]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Is part of") {
+		t.Errorf("BELONGS_TO relationships are rendered as edges:\n%s", out)
+	}
+}
+
+func TestToDOTParallelEdges(t *testing.T) {
+	out := newParallelEdgesDesign().ToDOT()
+	for _, want := range []string{
+		`"Shop.Shop.API" -> "Shop.Shop.DB" [label="Reads orders"]`,
+		`"Shop.Shop.API" -> "Shop.Shop.DB" [label="Writes orders"]`,
+	} {
+		if strings.Count(out, want) != 1 {
+			t.Errorf("%q does not appear once:\n%s", want, out)
+		}
+	}
+}
+
+func TestToDOTIncludesCodeElements(t *testing.T) {
+	out := newCodeDesign().ToDOT()
+	handler := `"Shop.Shop.API.Shop.API.Orders.Shop.API.Orders.OrderHandler"`
+	repo := `"Shop.Shop.API.Shop.API.Orders.Shop.API.Orders.OrderRepo"`
+	for _, want := range []string{
+		`subgraph "cluster_Shop.Shop.API.Shop.API.Orders" {`,
+		handler + ` [label="OrderHandler\n[Code: Go struct]", fillcolor="#c5dcf5", fontcolor="#000000"]`,
+		handler + " -> " + repo + ` [label="Loads orders"]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestToDOTStyles(t *testing.T) {
+	d := newShopDesign()
+	d.lookupNode("Shop.Shop.DB").Style(ShapeCylinder, "", "#ff0000")
+	d.TagDefaults("cache", TagDefaults{Style: ElementStyle{Background: "#ffeeaa"}})
+	d.lookupNode("Shop.Shop.Web").Tag("cache")
+	out := d.ToDOT()
+
+	for _, want := range []string{
+		// Explicit shape and stroke, type background
+		`"Shop.Shop.DB" [label="DB\n[Container: Postgres]", shape=cylinder, fillcolor="#438dd5", fontcolor="#ffffff", color="#ff0000"]`,
+		// Background of the tag, with a dark font
+		`"Shop.Shop.Web" [label="Web\n[Container: React]", fillcolor="#ffeeaa", fontcolor="#000000"]`,
+		// External elements are grey
+		`"Payments" [label="Payments\n[System]", fillcolor="#999999"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	if got, want := dotQuote("a \"b\" \\ c\nd"), `"a \"b\" \\ c\nd"`; got != want {
+		t.Errorf("dotQuote = %s, want %s", got, want)
+	}
+}
+
+func TestToDOTAsyncEdges(t *testing.T) {
+	d := newShopDesign()
+	api := &Container{Node: d.lookupNode("Shop.Shop.API")}
+	api.UsesAsync(d.lookupNode("Payments"), "Publishes refunds")
+	out := d.ToDOT()
+
+	if want := `"Shop.Shop.API" -> "Payments" [label="Publishes refunds", style=dashed, ltail="cluster_Shop.Shop.API"]`; !strings.Contains(out, want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+	if want := `"Shop.Shop.Web" -> "Shop.Shop.API" [label="Calls", lhead="cluster_Shop.Shop.API"]`; !strings.Contains(out, want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+}
+
+func TestToDOTOptionalEdges(t *testing.T) {
+	d := newShopDesign()
+	web := &Container{Node: d.lookupNode("Shop.Shop.Web")}
+	web.UsesOptional(d.lookupNode("Payments"), "Shows saved cards")
+	api := &Container{Node: d.lookupNode("Shop.Shop.API")}
+	api.UsesRel(d.lookupNode("Payments"), "Publishes refunds").Async().Optional()
+	out := d.ToDOT()
+
+	for _, want := range []string{
+		`"Shop.Shop.Web" -> "Payments" [label="Shows saved cards", style=dotted]`,
+		// DOT draws one line style: optional wins over asynchronous
+		`"Shop.Shop.API" -> "Payments" [label="Publishes refunds", style=dotted, ltail="cluster_Shop.Shop.API"]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+// newTieredDesign returns a three-tier design: a gateway in front of two
+// services sharing a database, with layout hints on each tier.
+func newTieredDesign() *Design {
+	d := NewDesign("Tiers", "Three-tier shop")
+	shop := d.System("Shop", "Online shop")
+	gateway := shop.Container("Gateway", "API gateway").LayoutHint(LayoutHint{Rank: RankSource})
+	orders := shop.Container("Orders", "Order service").LayoutHint(LayoutHint{Group: "services"})
+	billing := shop.Container("Billing", "Billing service").LayoutHint(LayoutHint{Group: "services"})
+	db := shop.Container("DB", "Database").LayoutHint(LayoutHint{Rank: RankSink, Group: "services"})
+	gateway.Uses(orders, "Routes orders")
+	gateway.Uses(billing, "Routes invoices")
+	orders.Uses(db, "Stores orders")
+	billing.Uses(db, "Stores invoices")
+	return d
+}
+
+func TestToDOTLayoutRanks(t *testing.T) {
+	out := newTieredDesign().ToDOT()
+	for _, want := range []string{
+		"    newrank=true\n",
+		`    { rank=source; "Shop.Shop.Gateway" }`,
+		`    { rank=sink; "Shop.Shop.DB" }`,
+		`    { rank=same; "Shop.Shop.Orders"; "Shop.Shop.Billing" } // services`,
+	} {
+		if strings.Count(out, want) != 1 {
+			t.Errorf("%q does not appear once:\n%s", want, out)
+		}
+	}
+	// Ranks come after the nodes they constrain, and the cluster isn't ranked
+	if strings.Index(out, "rank=source") < strings.Index(out, `"Shop.Shop.DB" [label=`) {
+		t.Errorf("rank constraints precede the nodes:\n%s", out)
+	}
+	if strings.Count(out, "{ rank=") != 3 {
+		t.Errorf("got %d rank constraints, want 3:\n%s", strings.Count(out, "{ rank="), out)
+	}
+
+	if out := newShopDesign().ToDOT(); strings.Contains(out, "{ rank=") {
+		t.Errorf("a design without hints has rank constraints:\n%s", out)
+	}
+}
@@ -0,0 +1,67 @@
+package neoarch
+
+import (
+	"cmp"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Dynamic views
+// -----------------------------------------------------------------------------
+
+// DynamicView is a sequence of interactions between elements, e.g. how a
+// request flows through the system, rendered as a Structurizr dynamic view.
+// Steps are rendered by their order key, not in the order they were added.
+type DynamicView struct {
+	Key         string
+	Description string
+	scope       string // FullId of the system or container, "" for the whole landscape
+	steps       []DynamicStep
+}
+
+// DynamicStep is an interaction of a dynamic view, see DynamicView.AddStep.
+type DynamicStep struct {
+	Order       float64
+	From, To    string // FullIds
+	Description string
+}
+
+// DynamicView returns the dynamic view with the given key, creating it on first
+// use. scope is the system or container the view is about; nil scopes it to
+// the whole landscape. The scope and description of an existing view are kept.
+func (d *Design) DynamicView(key, description string, scope INode) *DynamicView {
+	for _, view := range d.dynamicViews {
+		if view.Key == key {
+			return view
+		}
+	}
+	view := &DynamicView{Key: key, Description: description}
+	if scope != nil {
+		view.scope = scope.FullId()
+	}
+	d.dynamicViews = append(d.dynamicViews, view)
+	return view
+}
+
+// AddStep adds an interaction from one element to another. Steps are rendered
+// sorted by order, and steps with the same order in the order they were added,
+// so a step can be inserted between 1 and 2 with order 1.5 without renumbering.
+// Structurizr expects a relationship between the two elements in the model.
+func (v *DynamicView) AddStep(order float64, from, to INode, description string) *DynamicView {
+	v.steps = append(v.steps, DynamicStep{Order: order, From: from.FullId(), To: to.FullId(), Description: description})
+	return v
+}
+
+// Scope returns the FullId of the system or container the view is about, ""
+// for the whole landscape.
+func (v *DynamicView) Scope() string {
+	return v.scope
+}
+
+// Steps returns the steps of the view in the order they are rendered: by
+// order, then in the order they were added.
+func (v *DynamicView) Steps() []DynamicStep {
+	steps := slices.Clone(v.steps)
+	slices.SortStableFunc(steps, func(a, b DynamicStep) int { return cmp.Compare(a.Order, b.Order) })
+	return steps
+}
@@ -0,0 +1,73 @@
+package neoarch
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Equal reports whether two designs model the same thing: the same nodes, by
+// ID, with the same attributes and parents, and the same relationships. The
+// order in which nodes and relationships were added does not matter, the order
+// of tags does.
+func (d *Design) Equal(other *Design) bool {
+	if d == nil || other == nil {
+		return d == other
+	}
+	if d.ID != other.ID || d.Name != other.Name || d.Description != other.Description || d.Version != other.Version || !maps.Equal(d.meta, other.meta) {
+		return false
+	}
+	if len(d.nodes) != len(other.nodes) {
+		return false
+	}
+	for id, a := range d.nodes {
+		b, ok := other.nodes[id]
+		if !ok || !nodesEqual(a, b) {
+			return false
+		}
+	}
+
+	if len(d.relationships) != len(other.relationships) {
+		return false
+	}
+	ours, theirs := sortedRelationships(d.relationships), sortedRelationships(other.relationships)
+	for i := range ours {
+		a, b := ours[i], theirs[i]
+		if a.Key() != b.Key() || a.Technology != b.Technology || a.NoImplied != b.NoImplied || a.InteractionStyle != b.InteractionStyle || a.Weight != b.Weight || a.Optional != b.Optional || !sameRef(a.DerivedFrom, b.DerivedFrom) || !slices.Equal(a.Tags, b.Tags) || !reflect.DeepEqual(a.Properties, b.Properties) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodesEqual compares the attributes of two nodes, and the FullIds of their parents.
+func nodesEqual(a, b *Node) bool {
+	parentID := func(n *Node) string {
+		if n.ParentNode == nil {
+			return ""
+		}
+		return n.ParentNode.FullId()
+	}
+	return a.ID == b.ID &&
+		a.Name == b.Name &&
+		a.Description == b.Description &&
+		a.NodeType == b.NodeType &&
+		a.IsExternal == b.IsExternal &&
+		a.Technology == b.Technology &&
+		a.Appearance == b.Appearance &&
+		a.Snippet == b.Snippet &&
+		a.Layout == b.Layout &&
+		a.Deprecated == b.Deprecated &&
+		a.DeprecationReason == b.DeprecationReason &&
+		slices.Equal(a.Tags, b.Tags) &&
+		slices.Equal(a.Labels, b.Labels) &&
+		parentID(a) == parentID(b)
+}
+
+// sameRef compares two optional relationship references.
+func sameRef(a, b *RelationshipRef) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
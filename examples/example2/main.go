@@ -32,7 +32,7 @@ func main() {
 		Tag("grpc").
 		Uses(userDB, "Reads/writes user data").
 		Uses(userS3, "Stores profile images").
-		Uses(userTemporal, "Schedules background jobs")
+		UsesAsync(userTemporal, "Schedules background jobs")
 
 	// Cross-layer
 	userGraphQL.Uses(userService, "Resolves user operations")
@@ -70,12 +70,12 @@ func main() {
 		Tag("grpc").
 		Uses(socialDB, "Reads/writes tweet data").
 		Uses(socialS3, "Stores media").
-		Uses(socialTemporal, "Schedules tweet workflows")
+		UsesAsync(socialTemporal, "Schedules tweet workflows")
 
 	followService := socialSystem.Container("Follow gRPC Service", "Follow/unfollow logic").
 		Tag("grpc").
 		Uses(socialDB, "Updates following/follower lists").
-		Uses(socialTemporal, "Schedules notifications")
+		UsesAsync(socialTemporal, "Schedules notifications")
 
 	//Cross - layer
 	socialGraphQL.Uses(tweetService, "Resolves tweet ops")
@@ -0,0 +1,286 @@
+package neoarch
+
+import (
+	"regexp"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Export options shared by the exporters
+// -----------------------------------------------------------------------------
+
+// ViewOptions controls how a design is rendered by the exporters.
+// The zero value renders the design as modeled.
+type ViewOptions struct {
+	// CollapsePersonGroups renders every PersonGroup as a single person element.
+	// Relationships of its members are re-pointed to the group and deduplicated.
+	// Without it, members are rendered individually inside a group boundary.
+	CollapsePersonGroups bool
+
+	// IncludeImplied also renders the implied relationships between persons and
+	// systems (see Design.ImpliedRelationships), tagged with ImpliedTag so styles
+	// can render them differently. Implied relationships between a pair that
+	// already has an explicit one are never rendered.
+	IncludeImplied bool
+	ImpliedTag     string
+
+	// CollapseRelationships only renders the most specific relationship between
+	// two branches of the hierarchy: an explicit USES relationship is dropped when
+	// a relationship between their descendants already implies it, and Structurizr
+	// infers it back in higher-level views. This removes duplicate arrows, at the
+	// cost of the dropped relationship's own description: higher-level views show
+	// the descriptions of the specific relationships instead.
+	CollapseRelationships bool
+
+	// ReadableViewKeys builds view keys from element names ("system_context_<name>")
+	// instead of the default stable keys derived from the MD5 of the element FullId.
+	// Readable keys change whenever an element is renamed, which invalidates any
+	// layout saved for the view.
+	ReadableViewKeys bool
+
+	// ReadableIDs makes the JSON exporter use the FullIds of elements as their
+	// ids instead of the default stable ids derived from their MD5. See ToJSON.
+	ReadableIDs bool
+
+	// GroupNestedContainers renders a container and its nested containers inside
+	// a group named after it. By default nested containers are rendered as
+	// sibling containers tagged "Nested Container" and with their parent's name,
+	// since Structurizr does not nest containers.
+	GroupNestedContainers bool
+
+	// IncludeSnippets renders the code snippets of components (see
+	// Component.Snippet) as element properties, "codeLanguage" and "codeSnippet".
+	IncludeSnippets bool
+
+	// ManualLayout leaves autolayout out of the views, so elements can be placed
+	// by hand in Structurizr, e.g. following their LayoutHint.
+	ManualLayout bool
+
+	// ExtendsWorkspace makes the Structurizr workspace extend the workspace at
+	// this path or URL, e.g. the platform workspace composing every system. See
+	// Design.ToStructurizrDSLExtending.
+	ExtendsWorkspace string
+
+	// BundleLevel replaces the USES relationships between the descendants of
+	// two elements of this level by a single one labeled with their count, to
+	// keep dense diagrams readable. See Design.EdgeBundles.
+	BundleLevel NodeType
+
+	// Scenario renders the named scenario of the design instead of the design
+	// itself. See Design.Materialize.
+	Scenario string
+
+	// Filter removes modeling noise from the rendered design. See ExportFilter.
+	Filter ExportFilter
+
+	// ExcludeTags leaves out the elements having any of these tags, e.g.
+	// "internal-only" infrastructure in stakeholder diagrams, with every
+	// relationship touching them. Excluding an element also excludes its
+	// children. It adds to Filter.ExcludeNodeTags.
+	ExcludeTags []string
+
+	// AllSystemViews renders the system context and container views of every
+	// system even when one is in scope (see System.InScope). By default only the
+	// system in scope and its subsystems get views.
+	AllSystemViews bool
+}
+
+// ExportFilter removes nodes and relationships from what the exporters render,
+// e.g. health-check endpoints that belong in Neo4j but not in diagrams. It is
+// ignored by SaveToNeo4j.
+type ExportFilter struct {
+	// ExcludeNodeTags removes the nodes having any of these tags, together with
+	// their descendants and every relationship touching them. Nothing is reparented.
+	ExcludeNodeTags []string
+
+	// ExcludeRelDescriptionsMatching removes the relationships whose description
+	// matches the expression.
+	ExcludeRelDescriptionsMatching *regexp.Regexp
+
+	// ExcludeDeprecated removes the deprecated nodes (see Node.Deprecate), like
+	// ExcludeNodeTags.
+	ExcludeDeprecated bool
+}
+
+// IsZero reports whether the filter removes nothing.
+func (f ExportFilter) IsZero() bool {
+	return len(f.ExcludeNodeTags) == 0 && f.ExcludeRelDescriptionsMatching == nil && !f.ExcludeDeprecated
+}
+
+// ExportOption configures the ViewOptions of an export.
+type ExportOption func(*ViewOptions)
+
+// WithViewOptions replaces the options of an export with the given ones.
+func WithViewOptions(o ViewOptions) ExportOption {
+	return func(v *ViewOptions) {
+		*v = o
+	}
+}
+
+// CollapsePersonGroups renders each PersonGroup as a single person element.
+func CollapsePersonGroups() ExportOption {
+	return func(v *ViewOptions) {
+		v.CollapsePersonGroups = true
+	}
+}
+
+// IncludeImplied renders the implied system-level relationships, tagged with asTag.
+func IncludeImplied(asTag string) ExportOption {
+	return func(v *ViewOptions) {
+		v.IncludeImplied = true
+		v.ImpliedTag = asTag
+	}
+}
+
+// CollapseRelationships only renders the most specific relationship between
+// any pair of elements. See ViewOptions.CollapseRelationships.
+func CollapseRelationships() ExportOption {
+	return func(v *ViewOptions) {
+		v.CollapseRelationships = true
+	}
+}
+
+// ReadableViewKeys builds view keys from element names. See ViewOptions.ReadableViewKeys.
+func ReadableViewKeys() ExportOption {
+	return func(v *ViewOptions) {
+		v.ReadableViewKeys = true
+	}
+}
+
+// ReadableIDs uses FullIds as JSON element ids. See ViewOptions.ReadableIDs.
+func ReadableIDs() ExportOption {
+	return func(v *ViewOptions) {
+		v.ReadableIDs = true
+	}
+}
+
+// GroupNestedContainers renders nested containers in a group named after their
+// top-level container. See ViewOptions.GroupNestedContainers.
+func GroupNestedContainers() ExportOption {
+	return func(v *ViewOptions) {
+		v.GroupNestedContainers = true
+	}
+}
+
+// IncludeSnippets renders component code snippets. See ViewOptions.IncludeSnippets.
+func IncludeSnippets() ExportOption {
+	return func(v *ViewOptions) {
+		v.IncludeSnippets = true
+	}
+}
+
+// ManualLayout leaves autolayout out of the views. See ViewOptions.ManualLayout.
+func ManualLayout() ExportOption {
+	return func(v *ViewOptions) {
+		v.ManualLayout = true
+	}
+}
+
+// ExtendWorkspace extends the Structurizr workspace at path. See
+// ViewOptions.ExtendsWorkspace.
+func ExtendWorkspace(path string) ExportOption {
+	return func(v *ViewOptions) {
+		v.ExtendsWorkspace = path
+	}
+}
+
+// BundleEdges bundles the relationships between elements of the given level.
+// See ViewOptions.BundleLevel.
+func BundleEdges(level NodeType) ExportOption {
+	return func(v *ViewOptions) {
+		v.BundleLevel = level
+	}
+}
+
+// OmitDeprecated leaves the deprecated elements out. See ExportFilter.ExcludeDeprecated.
+func OmitDeprecated() ExportOption {
+	return func(v *ViewOptions) {
+		v.Filter.ExcludeDeprecated = true
+	}
+}
+
+// ForScenario renders the named scenario. See ViewOptions.Scenario.
+func ForScenario(name string) ExportOption {
+	return func(v *ViewOptions) {
+		v.Scenario = name
+	}
+}
+
+// WithExportFilter applies the filter to the export. See ExportFilter.
+func WithExportFilter(f ExportFilter) ExportOption {
+	return func(v *ViewOptions) {
+		v.Filter = f
+	}
+}
+
+// ExcludeTags leaves out the elements with any of the tags, and their
+// children. See ViewOptions.ExcludeTags.
+func ExcludeTags(tags ...string) ExportOption {
+	return func(v *ViewOptions) {
+		v.ExcludeTags = append(v.ExcludeTags, tags...)
+	}
+}
+
+// AllSystemViews renders views for every system. See ViewOptions.AllSystemViews.
+func AllSystemViews() ExportOption {
+	return func(v *ViewOptions) {
+		v.AllSystemViews = true
+	}
+}
+
+func newViewOptions(opts []ExportOption) ViewOptions {
+	v := ViewOptions{}
+	for _, opt := range opts {
+		opt(&v)
+	}
+	return v
+}
+
+// filter returns the export filter of the options, including ExcludeTags.
+func (o ViewOptions) filter() ExportFilter {
+	f := o.Filter
+	if len(o.ExcludeTags) > 0 {
+		f.ExcludeNodeTags = slices.Concat(f.ExcludeNodeTags, o.ExcludeTags)
+	}
+	return f
+}
+
+// applyExportFilter returns the design the exporters render under the filter:
+// d itself when the filter is empty, otherwise a filtered copy.
+func (d *Design) applyExportFilter(f ExportFilter) *Design {
+	if f.IsZero() {
+		return d
+	}
+
+	excluded := map[*Node]struct{}{}
+	for _, node := range d.nodes {
+		for _, cur := range d.ancestorsOrSelf(node) {
+			if (f.ExcludeDeprecated && cur.Deprecated) || slices.ContainsFunc(cur.Tags, func(tag string) bool { return slices.Contains(f.ExcludeNodeTags, tag) }) {
+				excluded[node] = struct{}{}
+				break
+			}
+		}
+	}
+	byFullId := d.nodesByFullId()
+	isExcluded := func(id string) bool {
+		node, ok := byFullId[id]
+		if !ok {
+			return false
+		}
+		_, ok = excluded[node]
+		return ok
+	}
+
+	return d.subgraph(
+		func(n *Node) bool {
+			_, ok := excluded[n]
+			return !ok
+		},
+		func(rel Relationship) bool {
+			if isExcluded(rel.StartID) || isExcluded(rel.EndID) {
+				return false
+			}
+			return f.ExcludeRelDescriptionsMatching == nil || !f.ExcludeRelDescriptionsMatching.MatchString(rel.Description)
+		},
+	)
+}
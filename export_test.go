@@ -0,0 +1,105 @@
+package neoarch
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// newNoisyDesign returns the shop fixture with a worker container tagged
+// "infra-noise", holding a component, and a health check relationship.
+func newNoisyDesign() *Design {
+	d := newShopDesign()
+	shop := &System{Node: d.lookupNode("Shop"), design: d}
+	api := &Container{Node: d.lookupNode("Shop.Shop.API"), system: shop}
+	db := &Container{Node: d.lookupNode("Shop.Shop.DB"), system: shop}
+	worker := shop.Container("Worker", "Temporal worker").Tag("infra-noise")
+	worker.Component("Cleanup", "Purges old orders").Uses(db, "Deletes old orders")
+	api.Uses(worker, "Schedules jobs")
+	api.Uses(db, "Health check")
+	return d
+}
+
+func TestApplyExportFilter(t *testing.T) {
+	d := newNoisyDesign()
+	before := d.Fingerprint()
+	filtered := d.applyExportFilter(ExportFilter{
+		ExcludeNodeTags:                []string{"infra-noise"},
+		ExcludeRelDescriptionsMatching: regexp.MustCompile(`(?i)health`),
+	})
+
+	for _, id := range []string{"Shop.Shop.Worker", "Shop.Shop.Worker.Shop.Worker.Cleanup"} {
+		if filtered.lookupNode(id) != nil {
+			t.Errorf("%s is not filtered out", id)
+		}
+	}
+	if filtered.lookupNode("Shop.Shop.API") == nil {
+		t.Error("Shop.Shop.API is filtered out")
+	}
+	for _, rel := range filtered.relationships {
+		if strings.Contains(rel.StartID, "Worker") || strings.Contains(rel.EndID, "Worker") {
+			t.Errorf("relationship %s -> %s touches a filtered node", rel.StartID, rel.EndID)
+		}
+		if rel.Description == "Health check" {
+			t.Errorf("relationship %s -> %s matches the description filter", rel.StartID, rel.EndID)
+		}
+	}
+	if got, want := len(filtered.relationships), len(newShopDesign().relationships); got != want {
+		t.Errorf("filtered design has %d relationships, want the %d of the shop", got, want)
+	}
+	if d.Fingerprint() != before {
+		t.Error("filtering changed the design")
+	}
+	if empty := d.applyExportFilter(ExportFilter{}); empty != d {
+		t.Error("an empty filter copied the design")
+	}
+}
+
+func TestExportFilterAppliesToEveryExporter(t *testing.T) {
+	d := newNoisyDesign()
+	opts := []ExportOption{WithExportFilter(ExportFilter{
+		ExcludeNodeTags:                []string{"infra-noise"},
+		ExcludeRelDescriptionsMatching: regexp.MustCompile(`(?i)health`),
+	})}
+
+	for _, name := range Exporters() {
+		b := strings.Builder{}
+		if err := d.Export(name, &b, opts...); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		for _, excluded := range []string{"Worker", "Cleanup", "Health check"} {
+			if strings.Contains(b.String(), excluded) {
+				t.Errorf("%s output contains %q:\n%s", name, excluded, b.String())
+			}
+		}
+	}
+}
+
+func TestExcludeTagsAddsToTheFilter(t *testing.T) {
+	dsl := newNoisyDesign().ToStructurizrDSL(ExcludeTags("infra-noise"))
+	if strings.Contains(dsl, "Worker") {
+		t.Errorf("ExcludeTags did not exclude the worker:\n%s", dsl)
+	}
+}
+
+func TestSaveToNeo4jIgnoresTheExportFilter(t *testing.T) {
+	d := newNoisyDesign()
+	d.ToStructurizrDSL(ExcludeTags("infra-noise"))
+	driver := &recordingDriver{}
+	if err := d.SaveToNeo4j(context.Background(), driver, neo4j.SessionConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	var saved bool
+	for _, stmt := range driver.statements {
+		if stmt.Params["id"] == "Shop.Shop.Worker" {
+			saved = true
+		}
+	}
+	if !saved {
+		t.Error("the filtered worker is not saved")
+	}
+}
@@ -0,0 +1,282 @@
+package neoarch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+// Exporter registry
+// -----------------------------------------------------------------------------
+
+// Exporter renders a design in some output format. Exporters registered with
+// RegisterExporter are available through Design.Export, which hands them the
+// design as a DesignView with the export options already applied.
+type Exporter interface {
+	Name() string
+	Export(v *DesignView, w io.Writer) error
+}
+
+// ErrUnknownExporter is returned by Design.Export for a name no exporter was
+// registered under.
+var ErrUnknownExporter = errors.New("unknown exporter")
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]Exporter{}
+)
+
+// RegisterExporter makes the exporter available under its name. Like
+// database/sql.Register, it panics if the exporter is nil or the name is
+// already taken, so it is meant to be called from init functions.
+func RegisterExporter(e Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	if e == nil {
+		panic("neoarch: RegisterExporter exporter is nil")
+	}
+	if _, dup := exporters[e.Name()]; dup {
+		panic("neoarch: RegisterExporter called twice for exporter " + e.Name())
+	}
+	exporters[e.Name()] = e
+}
+
+// Exporters returns the names of the registered exporters, sorted.
+func Exporters() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export renders the design with the exporter registered under name.
+func (d *Design) Export(name string, w io.Writer, opts ...ExportOption) error {
+	exportersMu.RLock()
+	e, ok := exporters[name]
+	exportersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownExporter, name)
+	}
+	v, err := d.View(opts...)
+	if err != nil {
+		return err
+	}
+	return e.Export(v, w)
+}
+
+// DesignView is the design as an exporter renders it: the scenario and filter
+// of the options are applied, and nodes and relationships are listed in a
+// stable order. It is shared with the design when nothing had to be copied,
+// so the nodes it returns must not be modified.
+type DesignView struct {
+	design    *Design
+	options   ViewOptions
+	aliasOnce sync.Once
+	aliases   map[*Node]string // built by Alias
+}
+
+// View returns the design as rendered with the given options. It fails for an
+// unknown scenario.
+func (d *Design) View(opts ...ExportOption) (*DesignView, error) {
+	options := newViewOptions(opts)
+	if options.Scenario != "" {
+		m := d.Materialize(options.Scenario)
+		if m == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScenario, options.Scenario)
+		}
+		d = m
+	}
+	d = d.applyExportFilter(options.filter())
+	if options.BundleLevel != "" {
+		d = d.bundled(options.BundleLevel)
+	}
+	return &DesignView{design: d, options: options}, nil
+}
+
+// Options returns the options the view was built with.
+func (v *DesignView) Options() ViewOptions {
+	return v.options
+}
+
+// ID returns the ID of the design, e.g. to report that its root is missing.
+func (v *DesignView) ID() string {
+	return v.design.ID
+}
+
+// Logger returns the logger of the design (see Design.SetLogger), for
+// exporters reporting what they had to leave out.
+func (v *DesignView) Logger() *slog.Logger {
+	return v.design.logger()
+}
+
+// Root returns the Design node of the design, or nil if it is missing.
+func (v *DesignView) Root() *Node {
+	return v.design.nodes[v.design.ID]
+}
+
+// Node returns the node with the given FullId, or nil.
+func (v *DesignView) Node(fullId string) *Node {
+	return v.design.nodesByFullId()[fullId]
+}
+
+// Parent returns the parent of n, or nil for top-level nodes.
+func (v *DesignView) Parent(n *Node) *Node {
+	return v.design.nodesByFullId()[v.design.hierarchyIndex().parents[n.FullId()]]
+}
+
+// Children returns the nodes that belong to n, in the order they were added.
+func (v *DesignView) Children(n *Node) []*Node {
+	return slices.Clone(v.design.hierarchyIndex().children[n.FullId()])
+}
+
+// Nodes returns every node, the root included, ordered by FullId.
+func (v *DesignView) Nodes() []*Node {
+	return v.design.sortedNodes()
+}
+
+// Ancestors returns the parent of n, its parent and so on up to a top-level
+// node, nearest first.
+func (v *DesignView) Ancestors(n *Node) []*Node {
+	var ancestors []*Node
+	for cur := v.Parent(n); cur != nil && cur != n && !slices.Contains(ancestors, cur); cur = v.Parent(cur) {
+		// BELONGS_TO cycles of imported designs end the walk
+		ancestors = append(ancestors, cur)
+	}
+	return ancestors
+}
+
+// Kind returns the C4 element type n is rendered as: its own type for the
+// built-in types, the registered kind for custom labels (see
+// Design.RegisterCustomLabel).
+func (v *DesignView) Kind(n *Node) NodeType {
+	return v.design.elementKind(n)
+}
+
+// CustomKind returns how the nodes of the custom label of n are rendered, and
+// false for nodes of a built-in type.
+func (v *DesignView) CustomKind(n *Node) (CustomKind, bool) {
+	if v.Kind(n) == n.NodeType {
+		return CustomKind{}, false
+	}
+	return v.design.CustomKindOf(string(n.NodeType))
+}
+
+// InScopeSystem returns the system in scope (see System.InScope), or nil.
+func (v *DesignView) InScopeSystem() *Node {
+	return v.design.InScopeSystem()
+}
+
+// ImpliedUseEnabled reports whether the design derives implied relationships
+// (see Design.EnableImpliedUse).
+func (v *DesignView) ImpliedUseEnabled() bool {
+	return !v.design.impliedUseDisabled
+}
+
+// CustomViews returns the custom views, in the order they were created. Like
+// the nodes, they must not be modified.
+func (v *DesignView) CustomViews() []*CustomView {
+	return slices.Clone(v.design.customViews)
+}
+
+// DynamicViews returns the dynamic views, in the order they were created. Like
+// the nodes, they must not be modified.
+func (v *DesignView) DynamicViews() []*DynamicView {
+	return slices.Clone(v.design.dynamicViews)
+}
+
+// Walk calls fn for every node but the root, depth first: top-level nodes by
+// FullId, each followed by its children in the order they were added. depth is
+// 1 for top-level nodes. It stops at the first error fn returns.
+func (v *DesignView) Walk(fn func(n *Node, depth int) error) error {
+	h := v.design.hierarchyIndex()
+	var walk func(n *Node, depth int) error
+	walk = func(n *Node, depth int) error {
+		if err := fn(n, depth); err != nil {
+			return err
+		}
+		for _, child := range h.children[n.FullId()] {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, node := range v.design.sortedNodes() {
+		if node.NodeType == NodeTypeDesign || h.parents[node.FullId()] != "" {
+			continue
+		}
+		if err := walk(node, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Relationships returns the explicit relationships, BELONGS_TO included,
+// ordered by start, end, type and description.
+func (v *DesignView) Relationships() []Relationship {
+	return sortedRelationships(v.design.relationships)
+}
+
+// IsParentEdge reports whether rel is the BELONGS_TO relationship of an element
+// to its parent, which exporters render as nesting rather than as an edge.
+func (v *DesignView) IsParentEdge(rel Relationship) bool {
+	if rel.Type != RelBelongsTo {
+		return false
+	}
+	return v.design.hierarchyIndex().parents[rel.StartID] == rel.EndID
+}
+
+// ImpliedRelationships returns the implied relationships of the design (see
+// Design.ImpliedRelationships) in the same order as Relationships.
+func (v *DesignView) ImpliedRelationships() []Relationship {
+	return sortedRelationships(v.design.ImpliedRelationships())
+}
+
+// Alias returns an identifier for n, unique among its siblings, made of
+// letters, digits and underscores: its own id for top-level nodes, its id
+// relative to its parent otherwise. Ids made the same by the replaced
+// characters, e.g. "a-b" and "a_b", get a numeric suffix in Walk order:
+// "a_b" and "a_b_2".
+func (v *DesignView) Alias(n *Node) string {
+	v.aliasOnce.Do(func() {
+		v.aliases = map[*Node]string{}
+		taken := map[*Node]map[string]bool{} // parent, nil for top-level nodes -> aliases
+		v.Walk(func(n *Node, _ int) error {
+			parent := v.Parent(n)
+			if taken[parent] == nil {
+				taken[parent] = map[string]bool{}
+			}
+			base := v.baseAlias(n, parent)
+			alias := base
+			for i := 2; taken[parent][alias]; i++ {
+				alias = fmt.Sprintf("%s_%d", base, i)
+			}
+			taken[parent][alias] = true
+			v.aliases[n] = alias
+			return nil
+		})
+	})
+	if alias, ok := v.aliases[n]; ok {
+		return alias
+	}
+	// Not walked, e.g. the root
+	return v.baseAlias(n, v.Parent(n))
+}
+
+// baseAlias returns the alias of n before deduplication.
+func (v *DesignView) baseAlias(n, parent *Node) string {
+	if parent == nil {
+		return sanitizeIdentifier(n.ID)
+	}
+	return sanitizeIdentifier(localID(n))
+}
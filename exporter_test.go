@@ -0,0 +1,84 @@
+package neoarch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// aliasExporter writes the alias and kind of every node, using only the
+// public API of DesignView as exporters of other packages would.
+type aliasExporter struct{ name string }
+
+func (e aliasExporter) Name() string { return e.name }
+
+func (e aliasExporter) Export(v *DesignView, w io.Writer) error {
+	return v.Walk(func(n *Node, depth int) error {
+		_, err := fmt.Fprintf(w, "%s%s %s\n", strings.Repeat("  ", depth-1), v.Alias(n), v.Kind(n))
+		return err
+	})
+}
+
+func TestRegisterExporter(t *testing.T) {
+	if !slices.Contains(Exporters(), "test-aliases") {
+		// Registered once per process, whatever -count
+		RegisterExporter(aliasExporter{name: "test-aliases"})
+	}
+	if !slices.Contains(Exporters(), "test-aliases") {
+		t.Fatalf("the exporter is not listed: %v", Exporters())
+	}
+
+	b := strings.Builder{}
+	if err := newShopDesign().Export("test-aliases", &b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Shop System\n  Web Container\n  API Container\n    Orders Component\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("output does not contain %q:\n%s", want, b.String())
+	}
+
+	err := newShopDesign().Export("test-missing", io.Discard)
+	if !errors.Is(err, ErrUnknownExporter) {
+		t.Errorf("exporting with an unknown exporter returned %v, want ErrUnknownExporter", err)
+	}
+
+	for name, e := range map[string]Exporter{
+		"duplicate name": aliasExporter{name: "test-aliases"},
+		"builtin name":   aliasExporter{name: "structurizr"},
+		"nil":            nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("RegisterExporter did not panic")
+				}
+			}()
+			RegisterExporter(e)
+		})
+	}
+}
+
+func TestDesignViewAliasIsUniqueAmongSiblings(t *testing.T) {
+	d := NewDesign("Shop", "Online shop")
+	shop := d.System("a-b", "Sells things")
+	d.System("a_b", "Also sells things")
+	shop.Container("web-app", "Storefront")
+	shop.Container("web_app", "Admin")
+	shop.Container("web_app_2", "Back office")
+	v, err := d.View()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	v.Walk(func(n *Node, _ int) error {
+		got = append(got, v.Alias(n))
+		return nil
+	})
+	want := []string{"a_b", "web_app", "web_app_2", "web_app_2_2", "a_b_2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got aliases %v, want %v", got, want)
+	}
+}
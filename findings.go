@@ -0,0 +1,244 @@
+package neoarch
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Findings
+// -----------------------------------------------------------------------------
+
+// Finding is a problem reported by one of the checks of the package: validation
+// issues, lint results, rule violations, layer cycles and export warnings. It
+// lets CI tooling render all of them the same way, see FindingsJSON and
+// FindingsSARIF.
+type Finding interface {
+	Describe() FindingInfo
+}
+
+// FindingInfo is the machine-readable form of a Finding.
+type FindingInfo struct {
+	Code          string   `json:"code"` // e.g. "containment", "C4-001", "encapsulation"
+	Severity      Severity `json:"severity"`
+	Message       string   `json:"message"`
+	NodeIDs       []string `json:"nodeIds,omitempty"`       // FullIds of the nodes involved
+	Relationships []string `json:"relationships,omitempty"` // Keys of the relationships involved
+	// Location is the file:line of the call that declared the offending
+	// element, when known.
+	Location string `json:"location,omitempty"`
+}
+
+// Findings converts the results of a check to findings, e.g.
+// Findings(d.Validate()).
+func Findings[T Finding](results []T) []Finding {
+	findings := make([]Finding, 0, len(results))
+	for _, result := range results {
+		findings = append(findings, result)
+	}
+	return findings
+}
+
+// Location returns the file:line of the call that declared the node, outside
+// of this package, or "" when unknown, e.g. for nodes loaded from Neo4j.
+func (n *Node) Location() string {
+	return n.callSite
+}
+
+// Location returns the file:line of the call that added the relationship,
+// outside of this package, or "" when unknown.
+func (r Relationship) Location() string {
+	return r.callSite
+}
+
+// packagePath is the import path of this package, used to skip its frames.
+var packagePath = reflect.TypeOf(Design{}).PkgPath()
+
+// callerLocation returns the file:line of the closest caller outside of this
+// package, or "".
+func callerLocation() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		// Functions of the package are named "<path>.<func>" or "<path>.(*T).<method>"
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, packagePath+".") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func (i ValidationIssue) Describe() FindingInfo {
+	return FindingInfo{Code: i.Code, Severity: i.Severity, Message: i.Message, NodeIDs: i.NodeIDs, Location: i.Location}
+}
+
+func (r LintResult) Describe() FindingInfo {
+	info := FindingInfo{Code: r.Rule, Severity: SeverityWarning, Message: r.Message}
+	if r.Node != nil {
+		info.NodeIDs = []string{r.Node.FullId()}
+		info.Location = r.Node.Location()
+	}
+	if r.Relationship != nil {
+		info.NodeIDs = []string{r.Relationship.StartID, r.Relationship.EndID}
+		info.Relationships = []string{r.Relationship.Key()}
+		info.Location = r.Relationship.Location()
+	}
+	return info
+}
+
+func (f LintFinding) Describe() FindingInfo {
+	return LintResult{Rule: f.Code, Message: f.Message, Node: f.Node, Relationship: f.Relationship}.Describe()
+}
+
+func (v RuleViolation) Describe() FindingInfo {
+	return FindingInfo{
+		Code:          v.Rule,
+		Severity:      SeverityError,
+		Message:       v.Message,
+		NodeIDs:       []string{v.Relationship.StartID, v.Relationship.EndID},
+		Relationships: []string{v.Relationship.Key()},
+		Location:      v.Relationship.Location(),
+	}
+}
+
+func (e *LayerCycleError) Describe() FindingInfo {
+	return FindingInfo{Code: "layer-cycle", Severity: SeverityError, Message: e.Error(), NodeIDs: e.NodeIDs}
+}
+
+// FindingsJSON renders findings as a JSON array of FindingInfo.
+func FindingsJSON(findings []Finding) []byte {
+	infos := make([]FindingInfo, 0, len(findings))
+	for _, f := range findings {
+		infos = append(infos, f.Describe())
+	}
+	out, _ := json.Marshal(infos)
+	return out
+}
+
+// SARIF 2.1.0, limited to what FindingsSARIF writes.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+		LogicalLocations []sarifLogical         `json:"logicalLocations,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifact `json:"artifactLocation"`
+		Region           *sarifRegion  `json:"region,omitempty"`
+	}
+	sarifArtifact struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+	sarifLogical struct {
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+	}
+)
+
+// FindingsSARIF renders findings as a SARIF 2.1.0 log, the format GitHub code
+// scanning uses to annotate pull requests. Findings with a location point to
+// the declaring line, with paths made relative to root (usually the repository
+// root) when they are under it; the nodes involved are listed as logical
+// locations. Codes become the rules of the neoarch tool.
+func FindingsSARIF(findings []Finding, root string) []byte {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "neoarch"}}, Results: []sarifResult{}}
+	codes := map[string]struct{}{}
+	for _, f := range findings {
+		info := f.Describe()
+		code := info.Code
+		if code == "" {
+			code = "neoarch"
+		}
+		codes[code] = struct{}{}
+
+		level := "warning"
+		if info.Severity == SeverityError {
+			level = "error"
+		}
+		result := sarifResult{RuleID: code, Level: level, Message: sarifMessage{Text: info.Message}}
+		location := sarifLocation{}
+		if file, line, ok := splitLocation(info.Location); ok {
+			if rel, err := filepath.Rel(root, file); root != "" && err == nil && !strings.HasPrefix(rel, "..") {
+				file = rel
+			}
+			location.PhysicalLocation = &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact{URI: filepath.ToSlash(file)},
+				Region:           &sarifRegion{StartLine: line},
+			}
+		}
+		for _, id := range info.NodeIDs {
+			location.LogicalLocations = append(location.LogicalLocations, sarifLogical{FullyQualifiedName: id})
+		}
+		if location.PhysicalLocation != nil || len(location.LogicalLocations) > 0 {
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	ids := make([]string, 0, len(codes))
+	for code := range codes {
+		ids = append(ids, code)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+	}
+
+	out, _ := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	return out
+}
+
+// splitLocation splits a "file:line" location.
+func splitLocation(location string) (string, int, bool) {
+	i := strings.LastIndexByte(location, ':')
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(location[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return location[:i], line, true
+}
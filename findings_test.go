@@ -0,0 +1,53 @@
+package neoarch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/wricardo/neoarch"
+)
+
+// TestBuildErrorsAreLocated is an external test: locations are those of the
+// closest calls outside the package.
+func TestBuildErrorsAreLocated(t *testing.T) {
+	d := neoarch.NewDesign("Located", "Located errors")
+	shop := d.System("Shop", "Sells things")
+	_, file, line, _ := runtime.Caller(0)
+	shop.Container("  ", "Blank name")
+	want := fmt.Sprintf("%s:%d", file, line+1)
+
+	issues := d.Validate()
+	if len(issues) != 1 || issues[0].Code != "build" || issues[0].Location != want {
+		t.Fatalf("got issues %+v, want a build issue at %s", issues, want)
+	}
+
+	var be *neoarch.BuildError
+	if err := d.SaveToNeo4j(context.Background(), nil, neo4j.SessionConfig{}); !errors.As(err, &be) || be.Location != want {
+		t.Errorf("SaveToNeo4j returned %v, want a BuildError at %s", err, want)
+	}
+
+	var infos []neoarch.FindingInfo
+	if err := json.Unmarshal(neoarch.FindingsJSON(neoarch.Findings(issues)), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Location != want {
+		t.Errorf("FindingsJSON gave %+v, want the location %s", infos, want)
+	}
+
+	sarif := string(neoarch.FindingsSARIF(neoarch.Findings(issues), filepath.Dir(file)))
+	for _, want := range []string{
+		`"uri": "findings_test.go"`,
+		fmt.Sprintf(`"startLine": %d`, line+1),
+	} {
+		if !strings.Contains(sarif, want) {
+			t.Errorf("the SARIF log lacks %s:\n%s", want, sarif)
+		}
+	}
+}
@@ -2,4 +2,8 @@ module github.com/wricardo/neoarch
 
 go 1.24.2
 
-require github.com/neo4j/neo4j-go-driver/v5 v5.28.0
+require (
+	github.com/neo4j/neo4j-go-driver/v5 v5.28.0
+	google.golang.org/protobuf v1.36.9
+	gopkg.in/yaml.v3 v3.0.1
+)
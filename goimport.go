@@ -0,0 +1,129 @@
+package neoarch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Go package importer
+// -----------------------------------------------------------------------------
+
+// GoImportOptions controls ImportGoPackages.
+type GoImportOptions struct {
+	// Patterns are the package patterns passed to go list. Defaults to "./...".
+	Patterns []string
+	// IncludeTests also turns the imports of test files into relationships.
+	IncludeTests bool
+	// IncludeVendored keeps packages under a vendor directory.
+	IncludeVendored bool
+}
+
+// goListPackage is the part of the `go list -json` output ImportGoPackages uses.
+type goListPackage struct {
+	ImportPath   string
+	Name         string
+	Doc          string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	Module       *struct{ Path string }
+}
+
+// ImportGoPackages seeds container with the packages of the Go module at
+// moduleRoot: one Component per package, described by the package doc, and a
+// "USES" relationship for every import of another package of the module.
+// Imports outside the module are left out.
+//
+// It runs `go list -json`, so the go command must be installed. Component ids
+// are derived from the import path relative to the module (e.g.
+// "internal_store" for "example.com/svc/internal/store"), so they stay stable
+// across runs and can be referenced when completing the model by hand.
+func ImportGoPackages(container *Container, moduleRoot string, opts GoImportOptions) error {
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	cmd := exec.Command("go", append([]string{"list", "-json"}, patterns...)...)
+	cmd.Dir = moduleRoot
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list in %s: %w: %s", moduleRoot, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []goListPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("go list in %s: %w", moduleRoot, err)
+		}
+		if pkg.Module == nil {
+			continue // standard library
+		}
+		if !opts.IncludeVendored && (strings.HasPrefix(pkg.ImportPath, "vendor/") || strings.Contains(pkg.ImportPath, "/vendor/")) {
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	components := make(map[string]*Component, len(pkgs))
+	for _, pkg := range pkgs {
+		components[pkg.ImportPath] = container.ComponentWithId(goPackageID(pkg), goPackageName(pkg), pkg.Doc)
+	}
+	for _, pkg := range pkgs {
+		imports := pkg.Imports
+		if opts.IncludeTests {
+			imports = append(append(append([]string{}, imports...), pkg.TestImports...), pkg.XTestImports...)
+		}
+		seen := map[string]struct{}{}
+		for _, imported := range imports {
+			target, ok := components[imported]
+			if !ok || imported == pkg.ImportPath {
+				continue
+			}
+			if _, ok := seen[imported]; ok {
+				continue
+			}
+			seen[imported] = struct{}{}
+			components[pkg.ImportPath].Uses(target, "Imports")
+		}
+	}
+	return nil
+}
+
+// goPackageName returns the import path of pkg relative to its module, or the
+// last element of the module path for the root package.
+func goPackageName(pkg goListPackage) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(pkg.ImportPath, pkg.Module.Path), "/")
+	if rel == "" {
+		rel = pkg.Module.Path[strings.LastIndex(pkg.Module.Path, "/")+1:]
+	}
+	return rel
+}
+
+// goPackageID turns the name of pkg into a component id.
+func goPackageID(pkg goListPackage) string {
+	return sanitizeID(goPackageName(pkg))
+}
+
+// sanitizeID turns s into an element id for the importers: anything but
+// letters, digits and underscores becomes "_", as "." separates the levels of
+// an id and the exporters use ids as identifiers.
+func sanitizeID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,106 @@
+package neoarch
+
+import (
+	"sort"
+	"strings"
+)
+
+// RebuildHierarchy sets the ParentNode of every node from its BELONGS_TO edge,
+// for designs built by adding nodes and relationships directly (e.g. after an
+// import) rather than with the nesting DSL. When a node has several BELONGS_TO
+// edges the first one wins, and edges that would create a cycle are ignored.
+//
+// FullIds are derived from parents, so node IDs are made relative to their new
+// parent where the old FullId allows it (keeping the FullId unchanged), and
+// relationships are re-pointed to the new FullIds otherwise.
+func (d *Design) RebuildHierarchy() {
+	byFullId := map[string]*Node{}
+	oldFullId := map[*Node]string{}
+	for _, node := range d.nodes {
+		byFullId[node.FullId()] = node
+		oldFullId[node] = node.FullId()
+	}
+
+	parent := map[*Node]*Node{}
+	for _, rel := range d.relationships {
+		if rel.Type != RelBelongsTo {
+			continue
+		}
+		child, okChild := byFullId[rel.StartID]
+		p, okParent := byFullId[rel.EndID]
+		if !okChild || !okParent || child == p || child.NodeType == NodeTypeDesign {
+			continue
+		}
+		if _, ok := parent[child]; ok {
+			continue
+		}
+		cycle := false
+		for cur := p; cur != nil; cur = parent[cur] {
+			if cur == child {
+				cycle = true
+				break
+			}
+		}
+		if !cycle {
+			parent[child] = p
+		}
+	}
+
+	// Parents first, so that their FullIds are final when placing their children
+	nodes := make([]*Node, 0, len(d.nodes))
+	for _, node := range d.nodes {
+		nodes = append(nodes, node)
+	}
+	depth := func(n *Node) int {
+		depth := 0
+		for cur := parent[n]; cur != nil; cur = parent[cur] {
+			depth++
+		}
+		return depth
+	}
+	sortNodes(nodes)
+	sort.SliceStable(nodes, func(i, j int) bool { return depth(nodes[i]) < depth(nodes[j]) })
+
+	renamed := map[string]string{}
+	ids := map[string]*Node{} // keeps node IDs unique while they change
+	for _, node := range nodes {
+		ids[node.ID] = node
+	}
+	for _, node := range nodes {
+		p := parent[node]
+		old := oldFullId[node]
+		if p == nil {
+			node.ParentNode = nil
+		} else {
+			if rest, ok := strings.CutPrefix(old, oldFullId[p]+"."); ok {
+				if other, taken := ids[rest]; !taken || other == node {
+					delete(ids, node.ID)
+					node.ID = rest
+					ids[rest] = node
+				}
+			}
+			node.ParentNode = p
+		}
+		if node.FullId() != old {
+			renamed[old] = node.FullId()
+		}
+	}
+
+	d.nodes = make(map[string]*Node, len(nodes))
+	for _, node := range nodes {
+		d.nodes[node.ID] = node
+	}
+	if len(renamed) > 0 {
+		for i := range d.relationships {
+			rel := &d.relationships[i]
+			if id, ok := renamed[rel.StartID]; ok {
+				rel.StartID = id
+			}
+			if id, ok := renamed[rel.EndID]; ok {
+				rel.EndID = id
+			}
+		}
+	}
+	d.hierarchy = nil
+	d.relationshipIndex = nil
+}
@@ -0,0 +1,158 @@
+package neoarch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// JSON export
+// -----------------------------------------------------------------------------
+
+// JSONWorkspace is the document written by ToJSON.
+type JSONWorkspace struct {
+	ID            string             `json:"id"`
+	Name          string             `json:"name"`
+	Description   string             `json:"description,omitempty"`
+	Elements      []JSONElement      `json:"elements"`
+	Relationships []JSONRelationship `json:"relationships"`
+}
+
+// JSONElement is a node of a JSONWorkspace. Parent is the id of the element it
+// belongs to, "" for top-level elements.
+type JSONElement struct {
+	ID                string   `json:"id"`
+	FullID            string   `json:"fullId"`
+	Parent            string   `json:"parent,omitempty"`
+	Type              NodeType `json:"type"`
+	Name              string   `json:"name"`
+	Description       string   `json:"description,omitempty"`
+	Technology        string   `json:"technology,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	External          bool     `json:"external,omitempty"`
+	Deprecated        bool     `json:"deprecated,omitempty"`
+	DeprecationReason string   `json:"deprecationReason,omitempty"`
+}
+
+// JSONRelationship is a relationship of a JSONWorkspace, between the elements
+// with the ids Source and Destination.
+type JSONRelationship struct {
+	Source      string           `json:"source"`
+	Destination string           `json:"destination"`
+	Type        RelationshipType `json:"type"`
+	Description string           `json:"description,omitempty"`
+	Technology  string           `json:"technology,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+	Interaction string           `json:"interaction,omitempty"`
+	Optional    bool             `json:"optional,omitempty"`
+}
+
+// ToJSON renders the canonical form of the design (see Canonicalize) as a
+// JSONWorkspace, e.g. for web front ends, so designs built in different orders
+// render the same. Elements are listed depth first, like DesignView.Walk, with
+// children ordered by id, and relationships in the order of
+// DesignView.Relationships; the BELONGS_TO relationship of an element to its
+// parent is left out, since Parent holds it.
+//
+// Element ids are the type of the element followed by the first 12 hex digits
+// of the MD5 of its FullId, e.g. "container_3f2a9c0e1b7d", so they survive
+// renames that keep the id. The ReadableIDs option uses the FullIds instead.
+// It is the same as d.Export("json", w, opts...).
+func (d *Design) ToJSON(opts ...ExportOption) ([]byte, error) {
+	b := bytes.Buffer{}
+	if err := d.Export(JSONExporter{}.Name(), &b, opts...); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// JSONExporter is the Exporter behind ToJSON, registered as "json".
+type JSONExporter struct{}
+
+func init() {
+	RegisterExporter(JSONExporter{})
+}
+
+// Name implements Exporter.
+func (JSONExporter) Name() string {
+	return "json"
+}
+
+// Export implements Exporter.
+func (JSONExporter) Export(v *DesignView, w io.Writer) error {
+	v = &DesignView{design: v.design.Canonicalize(), options: v.options}
+	root := v.Root()
+	if root == nil {
+		return fmt.Errorf("%w: %s", ErrDesignNodeNotFound, v.design.ID)
+	}
+	id := func(n *Node) string {
+		if v.options.ReadableIDs {
+			return n.FullId()
+		}
+		return hashKey(strings.ToLower(string(n.NodeType)), n.FullId())
+	}
+
+	ws := JSONWorkspace{
+		ID:            id(root),
+		Name:          root.Name,
+		Description:   root.Description,
+		Elements:      []JSONElement{},
+		Relationships: []JSONRelationship{},
+	}
+	ids := map[string]string{} // FullId -> element id
+	v.Walk(func(n *Node, _ int) error {
+		element := JSONElement{
+			ID:                id(n),
+			FullID:            n.FullId(),
+			Type:              n.NodeType,
+			Name:              n.Name,
+			Description:       n.Description,
+			Technology:        v.Technology(n),
+			Tags:              n.Tags,
+			External:          n.IsExternal,
+			Deprecated:        n.Deprecated,
+			DeprecationReason: n.DeprecationReason,
+		}
+		if parent := v.Parent(n); parent != nil {
+			element.Parent = ids[parent.FullId()]
+		}
+		ids[n.FullId()] = element.ID
+		ws.Elements = append(ws.Elements, element)
+		return nil
+	})
+
+	for _, rel := range v.Relationships() {
+		if v.IsParentEdge(rel) {
+			continue
+		}
+		source, okSource := ids[rel.StartID]
+		destination, okDestination := ids[rel.EndID]
+		if !okSource || !okDestination {
+			continue
+		}
+		ws.Relationships = append(ws.Relationships, JSONRelationship{
+			Source:      source,
+			Destination: destination,
+			Type:        rel.Type,
+			Description: rel.Description,
+			Technology:  rel.Technology,
+			Tags:        rel.Tags,
+			Interaction: string(rel.InteractionStyle),
+			Optional:    rel.Optional,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ws)
+}
+
+// hashKey returns a key for the element with the given FullId that survives
+// renames: the prefix followed by the first 12 hex digits of the MD5 of the
+// FullId.
+func hashKey(prefix, fullId string) string {
+	return prefix + "_" + MD5(fullId)[:12]
+}
@@ -0,0 +1,121 @@
+package neoarch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, d *Design, opts ...ExportOption) JSONWorkspace {
+	t.Helper()
+	out, err := d.ToJSON(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ws JSONWorkspace
+	if err := json.Unmarshal(out, &ws); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	return ws
+}
+
+func TestToJSONHashedIDs(t *testing.T) {
+	ws := decodeJSON(t, newShopDesign())
+	if len(ws.Elements) != 8 {
+		t.Fatalf("got %d elements, want 8", len(ws.Elements))
+	}
+
+	ids := map[string]JSONElement{}
+	for _, element := range ws.Elements {
+		if want := hashKey(strings.ToLower(string(element.Type)), element.FullID); element.ID != want {
+			t.Errorf("%s has id %s, want %s", element.FullID, element.ID, want)
+		}
+		ids[element.ID] = element
+	}
+	for _, element := range ws.Elements {
+		if element.Parent != "" && ids[element.Parent].ID == "" {
+			t.Errorf("%s has the unknown parent %s", element.FullID, element.Parent)
+		}
+	}
+	// The five USES relationships, the BELONGS_TO ones being held by Parent
+	if len(ws.Relationships) != 5 {
+		t.Errorf("got %d relationships, want 5: %+v", len(ws.Relationships), ws.Relationships)
+	}
+	for _, rel := range ws.Relationships {
+		if ids[rel.Source].ID == "" || ids[rel.Destination].ID == "" {
+			t.Errorf("relationship %s -> %s references an unknown element", rel.Source, rel.Destination)
+		}
+	}
+}
+
+func TestToJSONIDsSurviveRenames(t *testing.T) {
+	before := decodeJSON(t, newShopDesign())
+	renamed := newShopDesign()
+	renamed.lookupNode("Shop.Shop.API").Name = "Backend API"
+	after := decodeJSON(t, renamed)
+
+	for i := range before.Elements {
+		if before.Elements[i].ID != after.Elements[i].ID {
+			t.Errorf("id of %s changed from %s to %s", before.Elements[i].FullID, before.Elements[i].ID, after.Elements[i].ID)
+		}
+	}
+}
+
+func TestToJSONReadableIDs(t *testing.T) {
+	ws := decodeJSON(t, newShopDesign(), ReadableIDs())
+	for _, element := range ws.Elements {
+		if element.ID != element.FullID {
+			t.Errorf("element id = %s, want its FullId %s", element.ID, element.FullID)
+		}
+	}
+}
+
+func TestToJSONIsCanonical(t *testing.T) {
+	// The shop fixture, declared in another order with untrimmed descriptions
+	reordered := func() *Design {
+		d := NewDesign("Shop", "Online shop")
+		payments := d.System("Payments", "Takes payments ").External()
+		shop := d.System("Shop", "Sells things")
+		db := shop.Container("DB", "Orders").WithTechnology("Postgres")
+		api := shop.Container("API", " Backend").WithTechnology("Go")
+		web := shop.Container("Web", "Storefront").WithTechnology("React")
+		billing := api.Component("Billing", "Bills orders")
+		orders := api.Component("Orders", "Handles orders")
+		customer := d.Person("Customer", "Buys things")
+		billing.Uses(payments, "Charges")
+		orders.Uses(billing, "Bills")
+		orders.Uses(db, "Reads and writes ")
+		web.Uses(api, "Calls")
+		customer.Uses(web, "Browses")
+		return d
+	}
+
+	first, err := newShopDesign().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := reordered().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("designs built in different orders render differently:\n%s\nand:\n%s", first, second)
+	}
+}
+
+func TestToJSONTagTechnologies(t *testing.T) {
+	d := newShopDesign()
+	d.TagDefaults("postgres", TagDefaults{Technology: "PostgreSQL 16"})
+	d.lookupNode("Shop.Shop.DB").Technology = ""
+	d.lookupNode("Shop.Shop.DB").Tag("postgres")
+	d.lookupNode("Shop.Shop.API").Tag("postgres")
+
+	got := map[string]string{}
+	for _, element := range decodeJSON(t, d).Elements {
+		got[element.FullID] = element.Technology
+	}
+	// The tag fills in the technology; an explicit one wins
+	if got["Shop.Shop.DB"] != "PostgreSQL 16" || got["Shop.Shop.API"] != "Go" {
+		t.Errorf("got technologies DB %q and API %q, want PostgreSQL 16 and Go", got["Shop.Shop.DB"], got["Shop.Shop.API"])
+	}
+}
@@ -0,0 +1,71 @@
+package neoarch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Rendering via Kroki
+// -----------------------------------------------------------------------------
+
+// DefaultKrokiURL is the public Kroki server used by RenderViaKroki when no URL
+// is given.
+const DefaultKrokiURL = "https://kroki.io"
+
+// KrokiError is returned by RenderViaKroki when the Kroki server does not
+// answer with an image, e.g. for an unsupported format or a DSL it rejects.
+type KrokiError struct {
+	StatusCode int
+	Message    string // Body of the response, usually Kroki's explanation
+}
+
+func (e *KrokiError) Error() string {
+	return fmt.Sprintf("kroki: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// RenderViaKroki renders the design to an image with a Kroki server
+// (https://kroki.io), e.g. for embedding in a wiki without running Structurizr.
+// The design is sent as its Structurizr DSL export; format is one of the output
+// formats Kroki supports for Structurizr, such as "svg" or "png". An empty
+// krokiURL uses DefaultKrokiURL; pass the URL of a self-hosted server to keep
+// the design private.
+func (d *Design) RenderViaKroki(ctx context.Context, krokiURL, format string, opts ...ExportOption) ([]byte, error) {
+	if krokiURL == "" {
+		krokiURL = DefaultKrokiURL
+	}
+	endpoint, err := url.JoinPath(krokiURL, "structurizr", strings.ToLower(format))
+	if err != nil {
+		return nil, fmt.Errorf("kroki: invalid URL %q: %w", krokiURL, err)
+	}
+	dsl, err := d.ToStructurizrDSLErr(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(dsl))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kroki: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Kroki explains the failure in the body; keep a bounded amount of it
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &KrokiError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("kroki: reading response: %w", err)
+	}
+	return image, nil
+}
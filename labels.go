@@ -0,0 +1,129 @@
+package neoarch
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// -----------------------------------------------------------------------------
+// Custom labels
+// -----------------------------------------------------------------------------
+
+// LabelPolicy tells what Custom does with a label that isn't a valid Neo4j
+// label, i.e. not only letters, digits and underscores.
+type LabelPolicy int
+
+const (
+	LabelPascalCase LabelPolicy = iota // Convert it, e.g. "kafka topic" -> "KafkaTopic" (default)
+	LabelStrict                        // Record an error, reported by Validate and SaveToNeo4j
+)
+
+// CustomKind tells the exporters how to render the nodes of a custom label.
+type CustomKind struct {
+	Kind NodeType // NodeTypeComponent (default) or NodeTypeContainer
+	Tags []string // Added to the exported elements, after the label itself
+}
+
+// LabelPolicy sets how invalid custom labels are handled. See LabelPolicy.
+func (d *Design) LabelPolicy(policy LabelPolicy) *Design {
+	d.labelPolicy = policy
+	return d
+}
+
+// RegisterCustomLabel maps a custom label to the C4 element kind exporters
+// render its nodes as, with default tags. Labels used with Custom without being
+// registered are rendered as components, and so are nodes of a container label
+// declared in a container, which the Structurizr DSL can't nest.
+func (d *Design) RegisterCustomLabel(label string, kind NodeType, tags ...string) *Design {
+	if kind != NodeTypeComponent && kind != NodeTypeContainer {
+		d.recordError(fmt.Errorf("custom label %q: kind must be %s or %s, got %q", label, NodeTypeComponent, NodeTypeContainer, kind))
+		return d
+	}
+	if d.customKinds == nil {
+		d.customKinds = map[string]CustomKind{}
+	}
+	d.customKinds[string(d.customLabel(label))] = CustomKind{Kind: kind, Tags: slices.Clone(tags)}
+	return d
+}
+
+// CustomKindOf returns how the nodes of a custom label are exported, and
+// whether the label was used or registered in the design.
+func (d *Design) CustomKindOf(label string) (CustomKind, bool) {
+	kind, ok := d.customKinds[label]
+	return kind, ok
+}
+
+// customLabel validates a label passed to Custom according to the label
+// policy, registers it, and returns it as a NodeType.
+func (d *Design) customLabel(label string) NodeType {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	valid := ValidLabel(label)
+	if !valid {
+		sanitized := PascalCaseLabel(label)
+		if d.labelPolicy == LabelStrict || sanitized == "" {
+			d.recordErrorLocked(fmt.Errorf("invalid custom label %q: use letters, digits and underscores", label))
+		}
+		if sanitized != "" {
+			label = sanitized
+		}
+	}
+	if d.customKinds == nil {
+		d.customKinds = map[string]CustomKind{}
+	}
+	if _, ok := d.customKinds[label]; !ok {
+		d.customKinds[label] = CustomKind{Kind: NodeTypeComponent}
+	}
+	return NodeType(label)
+}
+
+// ValidLabel reports whether label can be used as a Neo4j label without quoting:
+// letters, digits and underscores, not starting with a digit.
+func ValidLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	for i, r := range label {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// PascalCaseLabel converts label to a valid label, splitting words on anything
+// but letters, digits and underscores: "kafka topic" becomes "KafkaTopic".
+// Labels starting with a digit are prefixed with "_". It returns "" when
+// nothing is left.
+func PascalCaseLabel(label string) string {
+	words := strings.FieldsFunc(label, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	})
+	b := strings.Builder{}
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	out := b.String()
+	if out != "" && unicode.IsDigit([]rune(out)[0]) {
+		out = "_" + out
+	}
+	return out
+}
+
+// elementKind returns the C4 element type n is exported as: its own type for
+// the built-in types, the registered kind for custom labels.
+func (d *Design) elementKind(n *Node) NodeType {
+	switch n.NodeType {
+	case NodeTypeUnknown, NodeTypeDesign, NodeTypePerson, NodeTypeSystem, NodeTypeContainer,
+		NodeTypeComponent, NodeTypePersonGroup, NodeTypeCode:
+		return n.NodeType
+	}
+	if kind, ok := d.customKinds[string(n.NodeType)]; ok {
+		return kind.Kind
+	}
+	return n.NodeType
+}
@@ -0,0 +1,100 @@
+package neoarch
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// newCustomLabelsDesign returns a design whose API container holds custom
+// elements with messy labels; "event bus" is registered as a container.
+func newCustomLabelsDesign(policy LabelPolicy) *Design {
+	d := NewDesign("Events", "Event-driven shop").LabelPolicy(policy)
+	d.RegisterCustomLabel("event bus", NodeTypeContainer, "Queue")
+	shop := d.System("Shop", "Online shop")
+	api := shop.Container("API", "Backend")
+	topic := api.Custom("kafka topic", "Orders", "Order events")
+	bus := api.Custom("event-bus", "Bus", "Event bus")
+	vendor := api.Custom("3rd party: api", "Stripe", "Payments")
+	api.Custom("Queue_v2", "Jobs", "Background jobs")
+	topic.Rel("PUBLISHES_TO", bus, "Publishes")
+	bus.Uses(vendor, "Charges")
+	return d
+}
+
+func TestCustomLabelsAreSanitized(t *testing.T) {
+	d := newCustomLabelsDesign(LabelPascalCase)
+	if issues := d.Validate(); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+
+	labels := map[string]bool{}
+	label := regexp.MustCompile(`^MERGE \(n:([^ {]+) \{`)
+	for _, stmt := range BuildNodeStatements(d) {
+		m := label.FindStringSubmatch(stmt.Query)
+		if m == nil {
+			t.Fatalf("statement does not merge a labeled node:\n%s", stmt.Query)
+		}
+		if !ValidLabel(m[1]) {
+			t.Errorf("statement merges the invalid label %q:\n%s", m[1], stmt.Query)
+		}
+		labels[m[1]] = true
+	}
+	for _, want := range []string{"KafkaTopic", "EventBus", "_3rdPartyApi", "Queue_v2"} {
+		if !labels[want] {
+			t.Errorf("no node saved with the label %s, got %v", want, labels)
+		}
+	}
+
+	if kind, ok := d.CustomKindOf("EventBus"); !ok || kind.Kind != NodeTypeContainer {
+		t.Errorf("EventBus kind = %v, %v; want the registered container", kind, ok)
+	}
+	if kind, ok := d.CustomKindOf("KafkaTopic"); !ok || kind.Kind != NodeTypeComponent {
+		t.Errorf("KafkaTopic kind = %v, %v; want the component default", kind, ok)
+	}
+
+	dsl, warnings := d.ToStructurizrDSLWithWarnings()
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	for _, want := range []string{
+		// Registered as a container, but declared in one
+		"Bus = component \"Bus\" \"Event bus\" {\n                    tags \"EventBus\" \"Queue\"",
+		"Orders = component \"Orders\" \"Order events\" {\n                    tags \"KafkaTopic\"",
+		"Stripe = component \"Stripe\" \"Payments\" {\n                    tags \"_3rdPartyApi\"",
+		`Shop.API.Orders -> Shop.API.Bus "Publishes"`,
+	} {
+		if !strings.Contains(dsl, want) {
+			t.Errorf("the DSL lacks %s:\n%s", want, dsl)
+		}
+	}
+	if !dslBlocksBalanced(dsl) {
+		t.Errorf("the DSL has unbalanced blocks or unclosed strings:\n%s", dsl)
+	}
+	declared, relationships := structurizrModel(t, dsl)
+	if len(relationships) != 2 {
+		t.Errorf("got %d relationships, want 2:\n%s", len(relationships), dsl)
+	}
+	for _, rel := range relationships {
+		for _, id := range rel {
+			if !declared[id] {
+				t.Errorf("relationship %s -> %s references the undeclared identifier %s:\n%s", rel[0], rel[1], id, dsl)
+			}
+		}
+	}
+}
+
+func TestCustomLabelsStrictPolicy(t *testing.T) {
+	d := newCustomLabelsDesign(LabelStrict)
+	var invalid []string
+	for _, issue := range d.Validate() {
+		if issue.Code == "build" && strings.Contains(issue.Message, "invalid custom label") {
+			invalid = append(invalid, issue.Message)
+		}
+	}
+	// "event bus" is registered before use, then "kafka topic", "event-bus"
+	// and "3rd party: api"; "Queue_v2" is valid
+	if len(invalid) != 4 {
+		t.Errorf("got %d invalid label errors, want 4: %v", len(invalid), invalid)
+	}
+}
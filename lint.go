@@ -0,0 +1,240 @@
+package neoarch
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Lint
+// -----------------------------------------------------------------------------
+
+// LintRule is a team convention checked by Lint, e.g. "every gRPC container
+// name ends with Service". Node and Relationship are predicates reporting
+// whether a node or relationship passes the rule; either may be nil.
+type LintRule struct {
+	Name         string
+	Message      string
+	Node         func(d *Design, n *Node) bool
+	Relationship func(d *Design, rel Relationship) bool
+}
+
+// LintResult is a node or relationship failing a LintRule.
+type LintResult struct {
+	Rule         string
+	Message      string
+	Node         *Node         // The failing node, nil for relationship results
+	Relationship *Relationship // The failing relationship, nil for node results
+}
+
+func (r LintResult) String() string {
+	if r.Node != nil {
+		return fmt.Sprintf("%s: %s: %s", r.Rule, r.Node.FullId(), r.Message)
+	}
+	return fmt.Sprintf("%s: %s -[%s]-> %s: %s", r.Rule, r.Relationship.StartID, r.Relationship.Type, r.Relationship.EndID, r.Message)
+}
+
+// Lint checks every node, except the design root, and every relationship
+// against the rules. Results are grouped by rule, in the order of rules, then
+// sorted by node FullId or in the order the relationships were added.
+func (d *Design) Lint(rules []LintRule) []LintResult {
+	var nodes []*Node
+	for _, node := range d.nodes {
+		if node.NodeType != NodeTypeDesign {
+			nodes = append(nodes, node)
+		}
+	}
+	sortNodes(nodes)
+
+	var results []LintResult
+	for _, rule := range rules {
+		if rule.Node != nil {
+			for _, node := range nodes {
+				if !rule.Node(d, node) {
+					results = append(results, LintResult{Rule: rule.Name, Message: rule.Message, Node: node})
+				}
+			}
+		}
+		if rule.Relationship != nil {
+			for i := range d.relationships {
+				if !rule.Relationship(d, d.relationships[i]) {
+					rel := d.relationships[i]
+					results = append(results, LintResult{Rule: rule.Name, Message: rule.Message, Relationship: &rel})
+				}
+			}
+		}
+	}
+	return results
+}
+
+// LintExternalDescription requires external nodes to have a description, since
+// readers know least about them.
+var LintExternalDescription = LintRule{
+	Name:    "external-description",
+	Message: "external elements must have a description",
+	Node: func(d *Design, n *Node) bool {
+		return !n.IsExternal || strings.TrimSpace(n.Description) != ""
+	},
+}
+
+// LintGatewayUsedByPerson requires gateways, the nodes tagged "gateway" or named
+// "... Gateway", to be used by at least one person, directly or through one of
+// their descendants.
+var LintGatewayUsedByPerson = LintRule{
+	Name:    "gateway-used-by-person",
+	Message: "gateways must be used by a person",
+	Node: func(d *Design, n *Node) bool {
+		if !isGateway(n) {
+			return true
+		}
+		byFullId := d.nodesByFullId()
+		for _, rel := range d.relationships {
+			if rel.Type != RelUses {
+				continue
+			}
+			start, okStart := byFullId[rel.StartID]
+			end, okEnd := byFullId[rel.EndID]
+			if okStart && okEnd && start.NodeType == NodeTypePerson && slices.Contains(d.ancestorsOrSelf(end), n) {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+// isGateway reports whether n is tagged "gateway" or its name ends with "Gateway".
+func isGateway(n *Node) bool {
+	for _, tag := range n.Tags {
+		if strings.EqualFold(tag, "gateway") {
+			return true
+		}
+	}
+	return strings.HasSuffix(strings.ToLower(n.Name), "gateway")
+}
+
+// DefaultLintRules returns the built-in lint rules.
+func DefaultLintRules() []LintRule {
+	return []LintRule{LintExternalDescription, LintGatewayUsedByPerson}
+}
+
+// -----------------------------------------------------------------------------
+// C4 completeness preset
+// -----------------------------------------------------------------------------
+
+// LintOptions configures the C4 preset of LintC4.
+type LintOptions struct {
+	// Disable lists the codes of the rules to skip, e.g. "C4-002".
+	Disable []string
+	// MaxDescriptionLength is the longest description accepted by C4-005, in
+	// characters. Zero means DefaultMaxDescriptionLength.
+	MaxDescriptionLength int
+}
+
+// DefaultMaxDescriptionLength is the description length limit of C4-005 when
+// LintOptions leaves it unset.
+const DefaultMaxDescriptionLength = 200
+
+// C4LintRules returns the rules of the C4 completeness preset, named after
+// their codes, without the ones disabled by opts:
+//
+//   - C4-001: every internal system has at least one container.
+//   - C4-002: every container tagged "grpc" or "graphql" has at least one component.
+//   - C4-003: every person uses at least one system, directly or through its elements.
+//   - C4-004: USES relationships have a real description, not an empty one or
+//     a containment one such as "Is part of" or "Belongs to".
+//   - C4-005: descriptions are at most MaxDescriptionLength characters long.
+func C4LintRules(opts LintOptions) []LintRule {
+	maxLength := opts.MaxDescriptionLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxDescriptionLength
+	}
+	hasChild := func(d *Design, n *Node, nodeType NodeType) bool {
+		return slices.ContainsFunc(d.hierarchyIndex().children[n.FullId()], func(c *Node) bool { return c.NodeType == nodeType })
+	}
+
+	rules := []LintRule{
+		{
+			Name:    "C4-001",
+			Message: "systems must have at least one container",
+			Node: func(d *Design, n *Node) bool {
+				return n.NodeType != NodeTypeSystem || d.isExternal(n) || hasChild(d, n, NodeTypeContainer)
+			},
+		},
+		{
+			Name:    "C4-002",
+			Message: "gRPC and GraphQL containers must have at least one component",
+			Node: func(d *Design, n *Node) bool {
+				api := slices.ContainsFunc(n.Tags, func(tag string) bool {
+					return strings.EqualFold(tag, "grpc") || strings.EqualFold(tag, "graphql")
+				})
+				return n.NodeType != NodeTypeContainer || !api || hasChild(d, n, NodeTypeComponent)
+			},
+		},
+		{
+			Name:    "C4-003",
+			Message: "persons must use at least one system",
+			Node: func(d *Design, n *Node) bool {
+				if n.NodeType != NodeTypePerson {
+					return true
+				}
+				byFullId := d.nodesByFullId()
+				for _, rel := range d.relationships {
+					if !usesLike(rel) || rel.StartID != n.FullId() {
+						continue
+					}
+					if end, ok := byFullId[rel.EndID]; ok && d.ancestorAt(end, NodeTypeSystem) != nil {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		{
+			Name:    "C4-004",
+			Message: `USES relationships must be described, not left empty or as "Is part of"/"Belongs to"`,
+			Relationship: func(d *Design, rel Relationship) bool {
+				if rel.Type != RelUses {
+					return true
+				}
+				desc := strings.TrimSpace(rel.Description)
+				return desc != "" && !strings.EqualFold(desc, "Is part of") && !strings.EqualFold(desc, "Belongs to")
+			},
+		},
+		{
+			Name:    "C4-005",
+			Message: fmt.Sprintf("descriptions must be at most %d characters long", maxLength),
+			Node: func(d *Design, n *Node) bool {
+				return len([]rune(n.Description)) <= maxLength
+			},
+			Relationship: func(d *Design, rel Relationship) bool {
+				return len([]rune(rel.Description)) <= maxLength
+			},
+		},
+	}
+	return slices.DeleteFunc(rules, func(rule LintRule) bool { return slices.Contains(opts.Disable, rule.Name) })
+}
+
+// LintFinding is an element or relationship failing a check of the C4
+// completeness preset.
+type LintFinding struct {
+	Code         string // Code of the failing check, e.g. "C4-001"
+	Message      string
+	Node         *Node         // The failing node, nil for relationship findings
+	Relationship *Relationship // The failing relationship, nil for node findings
+}
+
+func (f LintFinding) String() string {
+	return LintResult{Rule: f.Code, Message: f.Message, Node: f.Node, Relationship: f.Relationship}.String()
+}
+
+// LintC4 checks the design against the C4 completeness preset (see
+// C4LintRules), in the order of Lint.
+func LintC4(d *Design, opts LintOptions) []LintFinding {
+	results := d.Lint(C4LintRules(opts))
+	findings := make([]LintFinding, 0, len(results))
+	for _, result := range results {
+		findings = append(findings, LintFinding{Code: result.Rule, Message: result.Message, Node: result.Node, Relationship: result.Relationship})
+	}
+	return findings
+}
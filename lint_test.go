@@ -0,0 +1,58 @@
+package neoarch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newIncompleteDesign returns a design failing every check of the C4 preset
+// once: an empty system, a gRPC container without components, an idle person,
+// a USES relationship described as containment and an overlong description.
+func newIncompleteDesign() *Design {
+	d := NewDesign("Incomplete", "Fails the C4 preset")
+	d.System("Empty", "No containers")
+	shop := d.System("Shop", "Online shop")
+	api := shop.Container("API", "Backend").Tag("grpc")
+	web := shop.Container("Web", strings.Repeat("x", DefaultMaxDescriptionLength+1))
+	d.Person("Idle", "Uses nothing")
+	web.Uses(api, "Is part of")
+	return d
+}
+
+func TestLintC4(t *testing.T) {
+	d := newIncompleteDesign()
+	findings := LintC4(d, LintOptions{})
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Code)
+		if (f.Node == nil) == (f.Relationship == nil) {
+			t.Errorf("%s: finding has both or neither a node and a relationship: %+v", f.Code, f)
+		}
+	}
+	if want := []string{"C4-001", "C4-002", "C4-003", "C4-004", "C4-005"}; !slices.Equal(got, want) {
+		t.Errorf("got findings %v, want %v", got, want)
+	}
+	if f := findings[0]; f.Node == nil || f.Node.Name != "Empty" || f.String() != "C4-001: Empty: systems must have at least one container" {
+		t.Errorf("C4-001 finding = %s", f)
+	}
+
+	info := Findings(findings)[3].Describe()
+	if info.Code != "C4-004" || info.Severity != SeverityWarning || len(info.Relationships) != 1 || info.Location == "" {
+		t.Errorf("C4-004 finding describes as %+v", info)
+	}
+
+	disabled := LintC4(d, LintOptions{Disable: []string{"C4-001", "C4-005"}, MaxDescriptionLength: 10})
+	got = nil
+	for _, f := range disabled {
+		got = append(got, f.Code)
+	}
+	if want := []string{"C4-002", "C4-003", "C4-004"}; !slices.Equal(got, want) {
+		t.Errorf("with C4-001 and C4-005 disabled, got findings %v, want %v", got, want)
+	}
+
+	if findings := LintC4(newShopDesign(), LintOptions{}); len(findings) != 0 {
+		t.Errorf("the shop design has findings: %v", findings)
+	}
+}
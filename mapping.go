@@ -0,0 +1,323 @@
+package neoarch
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// -----------------------------------------------------------------------------
+// Importing a foreign Neo4j graph
+// -----------------------------------------------------------------------------
+
+// ImportMapping tells ImportFromNeo4jWithMapping how to read a graph created by
+// another tool, e.g. Service and Database nodes linked by CALLS.
+type ImportMapping struct {
+	// Labels of the nodes that become persons, systems, containers and
+	// components. A node with several mapped labels takes the first kind in
+	// this order.
+	Persons    []string
+	Systems    []string
+	Containers []string
+	Components []string
+
+	// Uses lists the relationship types that become USES relationships.
+	Uses []string
+	// BelongsTo lists the relationship types, from child to parent, that place
+	// containers in systems and components in containers.
+	BelongsTo []string
+
+	// Properties read from the nodes. They default to "name", "description"
+	// and "tags". Relationships are described by their DescriptionProperty, or
+	// by their type when they have none. Tags may be a list or a single string.
+	NameProperty        string
+	DescriptionProperty string
+	TagsProperty        string
+}
+
+func (m ImportMapping) withDefaults() ImportMapping {
+	m.NameProperty = cmp.Or(m.NameProperty, "name")
+	m.DescriptionProperty = cmp.Or(m.DescriptionProperty, "description")
+	m.TagsProperty = cmp.Or(m.TagsProperty, "tags")
+	return m
+}
+
+// nodeType returns the kind a node with the given labels is imported as.
+func (m ImportMapping) nodeType(labels []string) (NodeType, bool) {
+	for _, kind := range []struct {
+		labels   []string
+		nodeType NodeType
+	}{
+		{m.Persons, NodeTypePerson},
+		{m.Systems, NodeTypeSystem},
+		{m.Containers, NodeTypeContainer},
+		{m.Components, NodeTypeComponent},
+	} {
+		if slices.ContainsFunc(labels, func(l string) bool { return slices.Contains(kind.labels, l) }) {
+			return kind.nodeType, true
+		}
+	}
+	return "", false
+}
+
+// foreignNode is a node read by ImportFromNeo4jWithMapping.
+type foreignNode struct {
+	elementID string
+	labels    []string
+	props     map[string]any
+}
+
+type foreignEdge struct {
+	start, end string // element ids
+	relType    string
+	props      map[string]any
+}
+
+// ImportReport lists what ImportFromNeo4jWithMapping left out of the design.
+type ImportReport struct {
+	// UnmappedLabels are the labels, joined by ":", of the nodes touched by a
+	// mapped relationship without being mapped themselves; those relationships
+	// were skipped. Sorted.
+	UnmappedLabels []string
+	// MissingParents are the containers and components without a parent of the
+	// expected kind, as "<kind> <name>": containers first, then components, by
+	// name.
+	MissingParents []string
+	// Unnamed are the element ids of the mapped nodes without a name.
+	Unnamed []string
+}
+
+// Clean reports whether the whole graph was imported.
+func (r *ImportReport) Clean() bool {
+	return len(r.UnmappedLabels) == 0 && len(r.MissingParents) == 0 && len(r.Unnamed) == 0
+}
+
+func (r *ImportReport) String() string {
+	b := strings.Builder{}
+	if len(r.UnmappedLabels) > 0 {
+		fmt.Fprintf(&b, "relationships touching nodes with unmapped labels were skipped: %s\n", strings.Join(r.UnmappedLabels, ", "))
+	}
+	for _, element := range r.MissingParents {
+		fmt.Fprintf(&b, "%s has no parent of the expected kind\n", element)
+	}
+	for _, id := range r.Unnamed {
+		fmt.Fprintf(&b, "node %s has no name\n", id)
+	}
+	return b.String()
+}
+
+// ImportFromNeo4jWithMapping adds to design the elements of a graph that was
+// not saved by neoarch, as described by mapping. Elements are created with the
+// usual constructors, named after their name property, so ids are stable
+// across imports and the design can be exported or saved like any other.
+//
+// Containers need a system and components a container, through one of the
+// BelongsTo relationships. Elements missing theirs or a name, and mapped
+// relationships touching a node whose labels are not mapped, are left out and
+// listed in the report, so nothing is dropped silently. Since they are
+// problems of the foreign graph rather than of the design, they are not
+// recorded as design errors and don't prevent saving it.
+func ImportFromNeo4jWithMapping(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, design *Design, mapping ImportMapping) (*ImportReport, error) {
+	mapping = mapping.withDefaults()
+	nodes, edges, err := readForeignGraph(ctx, driver, sessConfig, mapping)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{}
+
+	byElementID := make(map[string]foreignNode, len(nodes))
+	for _, node := range nodes {
+		byElementID[node.elementID] = node
+	}
+	parents := map[string]string{}
+	unmapped := map[string]struct{}{}
+	for _, edge := range edges {
+		for _, id := range []string{edge.start, edge.end} {
+			if node, ok := byElementID[id]; ok {
+				if _, mapped := mapping.nodeType(node.labels); !mapped {
+					unmapped[strings.Join(node.labels, ":")] = struct{}{}
+				}
+			}
+		}
+		if slices.Contains(mapping.BelongsTo, edge.relType) {
+			if _, ok := parents[edge.start]; !ok {
+				parents[edge.start] = edge.end
+			}
+		}
+	}
+	for label := range unmapped {
+		report.UnmappedLabels = append(report.UnmappedLabels, label)
+	}
+	sort.Strings(report.UnmappedLabels)
+
+	// Parents are created before their children: persons and systems first,
+	// then containers, then components, each by name.
+	text := func(node foreignNode, prop string) string {
+		s, _ := node.props[prop].(string)
+		return s
+	}
+	created := map[string]INode{}
+	for _, level := range []NodeType{NodeTypePerson, NodeTypeSystem, NodeTypeContainer, NodeTypeComponent} {
+		var batch []foreignNode
+		for _, node := range nodes {
+			if t, ok := mapping.nodeType(node.labels); ok && t == level {
+				batch = append(batch, node)
+			}
+		}
+		sort.SliceStable(batch, func(i, j int) bool {
+			return text(batch[i], mapping.NameProperty) < text(batch[j], mapping.NameProperty)
+		})
+		for _, node := range batch {
+			name, desc := text(node, mapping.NameProperty), text(node, mapping.DescriptionProperty)
+			if name == "" {
+				report.Unnamed = append(report.Unnamed, node.elementID)
+				continue
+			}
+			var element INode
+			switch level {
+			case NodeTypePerson:
+				element = design.Person(name, desc)
+			case NodeTypeSystem:
+				element = design.System(name, desc)
+			case NodeTypeContainer:
+				if system, ok := created[parents[node.elementID]].(*System); ok {
+					element = system.Container(name, desc)
+				}
+			case NodeTypeComponent:
+				if container, ok := created[parents[node.elementID]].(*Container); ok {
+					element = container.Component(name, desc)
+				}
+			}
+			if element == nil {
+				report.MissingParents = append(report.MissingParents, fmt.Sprintf("%s %s", level, name))
+				continue
+			}
+			stored := design.lookupNode(element.GetID())
+			for _, tag := range foreignTags(node.props[mapping.TagsProperty]) {
+				tagOnce(stored, tag)
+			}
+			created[node.elementID] = element
+		}
+	}
+
+	for _, edge := range edges {
+		if !slices.Contains(mapping.Uses, edge.relType) {
+			continue
+		}
+		start, okStart := created[edge.start]
+		end, okEnd := created[edge.end]
+		if !okStart || !okEnd {
+			continue
+		}
+		desc, _ := edge.props[mapping.DescriptionProperty].(string)
+		if desc == "" {
+			desc = strings.ReplaceAll(strings.ToLower(edge.relType), "_", " ")
+		}
+		design.addRelationship(start, end, RelUses, desc)
+	}
+	return report, nil
+}
+
+// readForeignGraph reads the nodes with a mapped label and the relationships of
+// a mapped type, with their endpoints.
+func readForeignGraph(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, mapping ImportMapping) ([]foreignNode, []foreignEdge, error) {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	labels := slices.Concat(mapping.Persons, mapping.Systems, mapping.Containers, mapping.Components)
+	relTypes := slices.Concat(mapping.Uses, mapping.BelongsTo)
+
+	type graph struct {
+		nodes []foreignNode
+		edges []foreignEdge
+	}
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		g := graph{}
+		seen := map[string]struct{}{}
+		addNode := func(node neo4j.Node) {
+			if _, ok := seen[node.ElementId]; ok {
+				return
+			}
+			seen[node.ElementId] = struct{}{}
+			g.nodes = append(g.nodes, foreignNode{elementID: node.ElementId, labels: node.Labels, props: node.Props})
+		}
+
+		result, e := tx.Run(ctx, `
+MATCH (n)
+WHERE any(label IN labels(n) WHERE label IN $labels)
+RETURN n
+`, map[string]any{"labels": labels})
+		if e != nil {
+			return nil, e
+		}
+		for result.Next(ctx) {
+			node, _, e := neo4j.GetRecordValue[neo4j.Node](result.Record(), "n")
+			if e != nil {
+				return nil, e
+			}
+			addNode(node)
+		}
+		if e := result.Err(); e != nil {
+			return nil, e
+		}
+
+		result, e = tx.Run(ctx, `
+MATCH (a)-[r]->(b)
+WHERE type(r) IN $types
+RETURN a, r, b
+`, map[string]any{"types": relTypes})
+		if e != nil {
+			return nil, e
+		}
+		for result.Next(ctx) {
+			record := result.Record()
+			start, _, e := neo4j.GetRecordValue[neo4j.Node](record, "a")
+			if e != nil {
+				return nil, e
+			}
+			rel, _, e := neo4j.GetRecordValue[neo4j.Relationship](record, "r")
+			if e != nil {
+				return nil, e
+			}
+			end, _, e := neo4j.GetRecordValue[neo4j.Node](record, "b")
+			if e != nil {
+				return nil, e
+			}
+			// Endpoints without a mapped label are kept to report them
+			addNode(start)
+			addNode(end)
+			g.edges = append(g.edges, foreignEdge{start: start.ElementId, end: end.ElementId, relType: rel.Type, props: rel.Props})
+		}
+		return g, result.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	g := res.(graph)
+	return g.nodes, g.edges, nil
+}
+
+// foreignTags reads a tags property holding a list or a single string.
+func foreignTags(value any) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []any:
+		var tags []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				tags = append(tags, s)
+			}
+		}
+		return tags
+	}
+	return nil
+}
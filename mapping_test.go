@@ -0,0 +1,91 @@
+package neoarch
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// foreignDriver serves a foreign graph to ImportFromNeo4jWithMapping: the nodes
+// with their element id as key, and the relationships as (start, type, end)
+// triples, described by their fourth element when set.
+func foreignDriver(nodes map[string]neo4j.Node, rels [][]string) *stubDriver {
+	return &stubDriver{records: func(cypher string) []*neo4j.Record {
+		var records []*neo4j.Record
+		if strings.Contains(cypher, "RETURN n") {
+			for _, id := range slices.Sorted(maps.Keys(nodes)) {
+				records = append(records, &neo4j.Record{Keys: []string{"n"}, Values: []any{nodes[id]}})
+			}
+			return records
+		}
+		for _, rel := range rels {
+			props := map[string]any{}
+			if len(rel) > 3 {
+				props["description"] = rel[3]
+			}
+			records = append(records, &neo4j.Record{Keys: []string{"a", "r", "b"}, Values: []any{nodes[rel[0]], neo4j.Relationship{Type: rel[1], Props: props}, nodes[rel[2]]}})
+		}
+		return records
+	}}
+}
+
+func TestImportFromNeo4jWithMapping(t *testing.T) {
+	node := func(id, label, name string) neo4j.Node {
+		props := map[string]any{"description": "The " + name}
+		if name != "" {
+			props["name"] = name
+		}
+		return neo4j.Node{ElementId: id, Labels: []string{label}, Props: props}
+	}
+	driver := foreignDriver(map[string]neo4j.Node{
+		"e1": node("e1", "Product", "Shop"),
+		"e2": node("e2", "Service", "API"),
+		"e3": node("e3", "Service", "Web"),
+		"e4": node("e4", "Module", "Orders"),
+		"e5": node("e5", "Service", "Worker"), // no parent
+		"e6": node("e6", "Service", ""),       // no name
+		"e7": node("e7", "Queue", "Jobs"),     // not mapped
+	}, [][]string{
+		{"e2", "PART_OF", "e1"},
+		{"e3", "PART_OF", "e1"},
+		{"e4", "PART_OF", "e2"},
+		{"e3", "CALLS", "e2", "Places orders"},
+		{"e4", "CALLS", "e7"},
+	})
+	d := NewDesign("Imported", "From the service catalog")
+	report, err := ImportFromNeo4jWithMapping(context.Background(), driver, neo4j.SessionConfig{}, d, ImportMapping{
+		Systems:    []string{"Product"},
+		Containers: []string{"Service"},
+		Components: []string{"Module"},
+		Uses:       []string{"CALLS"},
+		BelongsTo:  []string{"PART_OF"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"Shop", "Shop.Shop.API", "Shop.Shop.Web", "Shop.Shop.API.Shop.API.Orders"} {
+		if d.lookupNode(id) == nil {
+			t.Errorf("%s was not imported", id)
+		}
+	}
+	if got := usesDescriptions(d); !slices.Equal(got, []string{"Places orders"}) {
+		t.Errorf("got USES relationships %v, want Places orders only", got)
+	}
+	if !slices.Equal(report.UnmappedLabels, []string{"Queue"}) || !slices.Equal(report.MissingParents, []string{"Container Worker"}) ||
+		!slices.Equal(report.Unnamed, []string{"e6"}) || report.Clean() {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// What was left out is about the foreign graph: the design can be saved
+	if len(d.errs) != 0 {
+		t.Errorf("the import recorded design errors: %v", d.errs)
+	}
+	if err := d.SaveToNeo4j(context.Background(), &recordingDriver{}, neo4j.SessionConfig{}); err != nil {
+		t.Errorf("saving the imported design failed: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package neoarch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Markdown report
+// -----------------------------------------------------------------------------
+
+// ToMarkdown renders the design as a Markdown report, e.g. for a wiki page
+// next to the diagrams: a section per top-level element listing it and its
+// descendants, code elements included, followed by a table of the
+// relationships and one of the relationships between systems (see
+// CrossSystemRelationships), when there are any. Elements are named by their
+// path of names, e.g. "Shop / API / Orders".
+//
+// Failures, such as a missing design node, are logged and rendered as an HTML
+// comment. It is the same as d.Export("markdown", w, opts...).
+func (d *Design) ToMarkdown(opts ...ExportOption) string {
+	b := strings.Builder{}
+	if err := d.Export(MarkdownExporter{}.Name(), &b, opts...); err != nil {
+		d.logger().Error("markdown: export failed", "design", d.ID, "error", err)
+		return "<!-- " + err.Error() + " -->\n"
+	}
+	return b.String()
+}
+
+// MarkdownExporter is the Exporter behind ToMarkdown, registered as "markdown".
+type MarkdownExporter struct{}
+
+func init() {
+	RegisterExporter(MarkdownExporter{})
+}
+
+// Name implements Exporter.
+func (MarkdownExporter) Name() string {
+	return "markdown"
+}
+
+// Export implements Exporter.
+func (MarkdownExporter) Export(v *DesignView, out io.Writer) error {
+	root := v.Root()
+	if root == nil {
+		return fmt.Errorf("%w: %s", ErrDesignNodeNotFound, v.ID())
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "# %s\n", markdownCell(root.Name))
+	if root.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", markdownCell(root.Description))
+	}
+
+	paths := map[string]string{} // FullId -> path of names
+	v.Walk(func(n *Node, depth int) error {
+		path := n.Name
+		if parent := v.Parent(n); parent != nil {
+			path = paths[parent.FullId()] + " / " + n.Name
+		}
+		paths[n.FullId()] = path
+
+		if depth == 1 {
+			fmt.Fprintf(w, "\n## %s %s\n\n", n.NodeType, markdownCell(n.Name))
+			if n.Description != "" {
+				fmt.Fprintf(w, "%s\n\n", markdownCell(n.Description))
+			}
+			fmt.Fprintln(w, "| Element | Type | Technology | Description | Tags |")
+			fmt.Fprintln(w, "|---|---|---|---|---|")
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCell(path), n.NodeType, markdownCell(v.Technology(n)),
+			markdownCell(n.Description), markdownCell(strings.Join(n.Tags, ", ")))
+		return nil
+	})
+
+	fmt.Fprintln(w, "\n## Relationships")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| From | To | Type | Description | Technology |")
+	fmt.Fprintln(w, "|---|---|---|---|---|")
+	for _, rel := range v.Relationships() {
+		from, okFrom := paths[rel.StartID]
+		to, okTo := paths[rel.EndID]
+		if !okFrom || !okTo || v.IsParentEdge(rel) {
+			continue
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCell(from), markdownCell(to), rel.Type,
+			markdownCell(rel.Description), markdownCell(rel.Technology))
+	}
+
+	if cross := v.design.CrossSystemRelationships(); len(cross) > 0 {
+		fmt.Fprintln(w, "\n## Cross-system integrations")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| From | To | Type | Description | Technology |")
+		fmt.Fprintln(w, "|---|---|---|---|---|")
+		for _, rel := range cross {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCell(paths[rel.StartID]), markdownCell(paths[rel.EndID]), rel.Type,
+				markdownCell(rel.Description), markdownCell(rel.Technology))
+		}
+	}
+	return w.Flush()
+}
+
+// markdownCellReplacer escapes the characters that would end a Markdown table
+// cell or row.
+var markdownCellReplacer = strings.NewReplacer(
+	"|", `\|`,
+	"\n", " ",
+)
+
+// markdownCell escapes s for a Markdown table cell or heading.
+func markdownCell(s string) string {
+	return markdownCellReplacer.Replace(s)
+}
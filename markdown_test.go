@@ -0,0 +1,34 @@
+package neoarch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToMarkdown(t *testing.T) {
+	out := newCodeDesign().ToMarkdown()
+	for _, want := range []string{
+		"# Shop\n\nOnline shop\n",
+		"## System Shop\n\nSells things\n",
+		"| Shop / API / Orders | Component |  | Handles orders |  |\n",
+		"| Shop / API / Orders / OrderHandler | Code | Go struct | HTTP handler |  |\n",
+		"| Shop / API / Orders / OrderHandler | Shop / API / Orders / OrderRepo | USES | Loads orders |  |\n",
+		"| Customer | Shop / Web | USES | Browses |  |\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "BELONGS_TO") {
+		t.Errorf("BELONGS_TO relationships to parents are listed:\n%s", out)
+	}
+}
+
+func TestToMarkdownEscapesCells(t *testing.T) {
+	d := NewDesign("Escapes", "Escapes")
+	d.System("A|B", "First line\nsecond line")
+	out := d.ToMarkdown()
+	if !strings.Contains(out, `| A\|B | System |  | First line second line |  |`) {
+		t.Errorf("cells are not escaped:\n%s", out)
+	}
+}
@@ -0,0 +1,202 @@
+package neoarch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Mermaid export
+// -----------------------------------------------------------------------------
+
+// ToMermaid renders the design as a Mermaid flowchart
+// (https://mermaid.js.org/syntax/flowchart.html), e.g. to embed it in Markdown
+// rendered by GitHub. Elements with children, such as systems and containers,
+// are rendered as subgraphs holding them, code elements included; the others
+// as nodes labeled with their name, type and technology, drawn with a classDef
+// of their style (see Node.Style), or the default of their type. Each
+// relationship is an edge of its own, so parallel relationships render as
+// separate labeled edges; asynchronous ones (see UsesAsync) are dashed, and
+// optional ones (see UsesOptional) are labeled "(optional)", since Mermaid has
+// no dotted edge of its own.
+// Combined with FilterByTechnology, it draws protocol-specific views:
+//
+//	d.FilterByTechnology("gRPC").ToMermaid()
+//
+// Failures, such as a missing design node, are logged and rendered as a
+// Mermaid comment. It is the same as d.Export("mermaid", w, opts...).
+func (d *Design) ToMermaid(opts ...ExportOption) string {
+	b := strings.Builder{}
+	if err := d.Export(MermaidExporter{}.Name(), &b, opts...); err != nil {
+		d.logger().Error("mermaid: export failed", "design", d.ID, "error", err)
+		return "%% " + err.Error() + "\n"
+	}
+	return b.String()
+}
+
+// MermaidExporter is the Exporter behind ToMermaid, registered as "mermaid".
+type MermaidExporter struct{}
+
+func init() {
+	RegisterExporter(MermaidExporter{})
+}
+
+// Name implements Exporter.
+func (MermaidExporter) Name() string {
+	return "mermaid"
+}
+
+// Export implements Exporter.
+func (MermaidExporter) Export(v *DesignView, out io.Writer) error {
+	root := v.Root()
+	if root == nil {
+		return fmt.Errorf("%w: %s", ErrDesignNodeNotFound, v.ID())
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "---\ntitle: \"%s\"\n---\n", mermaidText(root.Name))
+	fmt.Fprintln(w, "flowchart LR")
+
+	ids := mermaidIDs(v)
+	classes := map[string]ElementStyle{} // classDef name -> style
+	var classOrder []string
+	members := map[string][]string{} // classDef name -> node ids
+	var subgraphStrokes [][2]string  // subgraph id, stroke
+	indent := func(depth int) string { return strings.Repeat("    ", depth) }
+	var emit func(n *Node, depth int)
+	emit = func(n *Node, depth int) {
+		children := v.Children(n)
+		style := v.Style(n)
+		if len(children) == 0 {
+			fmt.Fprintf(w, "%s%s%s\n", indent(depth), ids[n.FullId()], mermaidShape(style.Shape, mermaidLabel(n, v.Technology(n))))
+			class := mermaidClass(style)
+			if _, ok := classes[class]; !ok {
+				classes[class] = style
+				classOrder = append(classOrder, class)
+			}
+			members[class] = append(members[class], ids[n.FullId()])
+			return
+		}
+		if style.Stroke != "" {
+			subgraphStrokes = append(subgraphStrokes, [2]string{ids[n.FullId()], style.Stroke})
+		}
+		fmt.Fprintf(w, "%ssubgraph %s[\"%s\"]\n", indent(depth), ids[n.FullId()], mermaidLabel(n, v.Technology(n)))
+		for _, child := range children {
+			emit(child, depth+1)
+		}
+		fmt.Fprintf(w, "%send\n", indent(depth))
+	}
+	v.Walk(func(n *Node, depth int) error {
+		if depth == 1 {
+			emit(n, 1)
+		}
+		return nil
+	})
+
+	for _, class := range classOrder {
+		style := classes[class]
+		def := "fill:" + style.Background + ",color:" + textColor(style.Background)
+		if style.Stroke != "" {
+			def += ",stroke:" + style.Stroke
+		}
+		fmt.Fprintf(w, "    classDef %s %s\n", class, def)
+		fmt.Fprintf(w, "    class %s %s\n", strings.Join(members[class], ","), class)
+	}
+	for _, stroke := range subgraphStrokes {
+		fmt.Fprintf(w, "    style %s stroke:%s\n", stroke[0], stroke[1])
+	}
+
+	for _, rel := range v.Relationships() {
+		start, okStart := ids[rel.StartID]
+		end, okEnd := ids[rel.EndID]
+		if !okStart || !okEnd || v.IsParentEdge(rel) {
+			continue
+		}
+		label := rel.Description
+		if rel.Optional {
+			label = strings.TrimSpace(label + " (optional)")
+		}
+		if rel.Technology != "" {
+			label += " [" + rel.Technology + "]"
+		}
+		arrow := "-->"
+		if rel.InteractionStyle == InteractionAsynchronous {
+			arrow = "-.->"
+		}
+		if label == "" {
+			fmt.Fprintf(w, "    %s %s %s\n", start, arrow, end)
+		} else {
+			fmt.Fprintf(w, "    %s %s|\"%s\"| %s\n", start, arrow, mermaidText(label), end)
+		}
+	}
+	return w.Flush()
+}
+
+// mermaidIDs returns the Mermaid node ids of the elements of the view, by
+// FullId: the sanitized FullId, with a numeric suffix when two FullIds
+// sanitize to the same id, or when it is the keyword "end".
+func mermaidIDs(v *DesignView) map[string]string {
+	ids := map[string]string{}
+	taken := map[string]bool{}
+	v.Walk(func(n *Node, _ int) error {
+		base := sanitizeIdentifier(n.FullId())
+		id := base
+		for i := 2; taken[id] || strings.EqualFold(id, "end"); i++ {
+			id = fmt.Sprintf("%s_%d", base, i)
+		}
+		taken[id] = true
+		ids[n.FullId()] = id
+		return nil
+	})
+	return ids
+}
+
+// mermaidShape returns a node of the given shape with the given label:
+// persons and ellipses are stadiums, the other shapes their Mermaid
+// equivalent, and rectangles when there is none.
+func mermaidShape(shape, label string) string {
+	switch shape {
+	case ShapePerson, ShapeEllipse:
+		return `(["` + label + `"])`
+	case ShapeCylinder:
+		return `[("` + label + `")]`
+	case ShapeCircle:
+		return `(("` + label + `"))`
+	case ShapeHexagon:
+		return `{{"` + label + `"}}`
+	case ShapeRoundedBox:
+		return `("` + label + `")`
+	}
+	return `["` + label + `"]`
+}
+
+// mermaidClass returns the name of the classDef drawing nodes with the given
+// style, derived from its colors.
+func mermaidClass(style ElementStyle) string {
+	return "style_" + MD5(style.Background + "|" + style.Stroke)[:8]
+}
+
+// mermaidLabel returns the label of a node: its name in bold, then its type
+// and technology.
+func mermaidLabel(n *Node, technology string) string {
+	kind := string(n.NodeType)
+	if technology != "" {
+		kind += ": " + technology
+	}
+	return "<b>" + mermaidText(n.Name) + "</b><br/>[" + mermaidText(kind) + "]"
+}
+
+// mermaidTextReplacer escapes the characters that end a quoted Mermaid label
+// or are read as markup.
+var mermaidTextReplacer = strings.NewReplacer(
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+	"\n", " ",
+)
+
+// mermaidText escapes s for a quoted Mermaid label.
+func mermaidText(s string) string {
+	return mermaidTextReplacer.Replace(s)
+}
@@ -0,0 +1,128 @@
+package neoarch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToMermaid(t *testing.T) {
+	out := newShopDesign().ToMermaid()
+	for _, want := range []string{
+		"flowchart LR\n",
+		`    subgraph Shop["<b>Shop</b><br/>[System]"]`,
+		`        subgraph Shop_Shop_API["<b>API</b><br/>[Container: Go]"]`,
+		`            Shop_Shop_API_Shop_API_Orders["<b>Orders</b><br/>[Component]"]`,
+		`    person_Customer(["<b>Customer</b><br/>[Person]"])`,
+		`    person_Customer -->|"Browses"| Shop_Shop_Web`,
+		`    Shop_Shop_API_Shop_API_Orders -->|"Reads and writes"| Shop_Shop_DB`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Is part of") {
+		t.Errorf("BELONGS_TO relationships are rendered as edges:\n%s", out)
+	}
+}
+
+func TestToMermaidParallelEdges(t *testing.T) {
+	out := newParallelEdgesDesign().ToMermaid()
+	for _, want := range []string{
+		`Shop_Shop_API -->|"Reads orders"| Shop_Shop_DB`,
+		`Shop_Shop_API -->|"Writes orders"| Shop_Shop_DB`,
+	} {
+		if strings.Count(out, want) != 1 {
+			t.Errorf("%q does not appear once:\n%s", want, out)
+		}
+	}
+}
+
+func TestToMermaidFilterByTechnology(t *testing.T) {
+	d := newShopDesign()
+	d.lookupNode("Shop.Shop.Web").Technology = "gRPC-Web"
+	out := d.FilterByTechnology("grpc").ToMermaid()
+
+	if !strings.Contains(out, "Shop_Shop_Web") || strings.Contains(out, "Shop_Shop_DB") {
+		t.Errorf("filtered output should hold the gRPC-Web container only:\n%s", out)
+	}
+}
+
+func TestToMermaidEscapesLabels(t *testing.T) {
+	d := NewDesign("Escapes", "Escapes")
+	d.System(`Say "hi" <now>`, "Quotes")
+	d.System("end", "Keyword")
+	out := d.ToMermaid()
+
+	if !strings.Contains(out, `Say #quot;hi#quot; #lt;now#gt;`) {
+		t.Errorf("label is not escaped:\n%s", out)
+	}
+	if strings.Contains(out, "\n    end[") {
+		t.Errorf("a node has the id end:\n%s", out)
+	}
+}
+
+func TestToMermaidIncludesCodeElements(t *testing.T) {
+	out := newCodeDesign().ToMermaid()
+	for _, want := range []string{
+		`subgraph Shop_Shop_API_Shop_API_Orders["<b>Orders</b><br/>[Component]"]`,
+		`Shop_Shop_API_Shop_API_Orders_Shop_API_Orders_OrderHandler["<b>OrderHandler</b><br/>[Code: Go struct]"]`,
+		`Shop_Shop_API_Shop_API_Orders_Shop_API_Orders_OrderHandler -->|"Loads orders"| Shop_Shop_API_Shop_API_Orders_Shop_API_Orders_OrderRepo`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestToMermaidStyles(t *testing.T) {
+	d := newShopDesign()
+	d.lookupNode("Shop.Shop.DB").Style(ShapeCylinder, "#ff0000", "#000000")
+	out := d.ToMermaid()
+
+	db := mermaidClass(ElementStyle{Background: "#ff0000", Stroke: "#000000"})
+	container := mermaidClass(ElementStyle{Background: "#438dd5"})
+	for _, want := range []string{
+		`Shop_Shop_DB[("<b>DB</b><br/>[Container: Postgres]")]`,
+		"classDef " + db + " fill:#ff0000,color:#ffffff,stroke:#000000\n",
+		"class Shop_Shop_DB " + db + "\n",
+		"classDef " + container + " fill:#438dd5,color:#ffffff\n",
+		"class Shop_Shop_Web " + container + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestToMermaidAsyncEdges(t *testing.T) {
+	d := newShopDesign()
+	api := &Container{Node: d.lookupNode("Shop.Shop.API")}
+	api.UsesAsync(d.lookupNode("Payments"), "Publishes refunds")
+	out := d.ToMermaid()
+
+	if want := `Shop_Shop_API -.->|"Publishes refunds"| Payments`; !strings.Contains(out, want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+	if want := `Shop_Shop_Web -->|"Calls"| Shop_Shop_API`; !strings.Contains(out, want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+}
+
+func TestToMermaidOptionalEdges(t *testing.T) {
+	d := newShopDesign()
+	web := &Container{Node: d.lookupNode("Shop.Shop.Web")}
+	web.UsesRel(d.lookupNode("Payments"), "Shows saved cards").Technology("HTTPS").Optional()
+	api := &Container{Node: d.lookupNode("Shop.Shop.API")}
+	api.UsesRel(d.lookupNode("Payments"), "Publishes refunds").Async().Optional()
+	out := d.ToMermaid()
+
+	for _, want := range []string{
+		`Shop_Shop_Web -->|"Shows saved cards (optional) [HTTPS]"| Payments`,
+		`Shop_Shop_API -.->|"Publishes refunds (optional)"| Payments`,
+		`Shop_Shop_Web -->|"Calls"| Shop_Shop_API`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
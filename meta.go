@@ -0,0 +1,66 @@
+package neoarch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Design metadata
+// -----------------------------------------------------------------------------
+
+// metaPrefix prefixes the properties of the Design node holding the metadata.
+const metaPrefix = "meta_"
+
+// SetMeta attaches a piece of governance information to the design, such as
+// its repository, owning team or last review date. It is saved as the
+// meta_<key> property of the Design node and read back by LoadFromNeo4j. Keys
+// must be valid property names (letters, digits and underscores, see
+// ValidLabel); other keys are recorded as errors reported by Validate. An empty
+// value removes the key.
+func (d *Design) SetMeta(key, value string) {
+	if !ValidLabel(key) {
+		d.recordError(fmt.Errorf("invalid metadata key %q: use letters, digits and underscores", key))
+		return
+	}
+	if value == "" {
+		delete(d.meta, key)
+		return
+	}
+	if d.meta == nil {
+		d.meta = map[string]string{}
+	}
+	d.meta[key] = value
+}
+
+// Meta returns the metadata value set for key, or "".
+func (d *Design) Meta(key string) string {
+	return d.meta[key]
+}
+
+// MetaKeys returns the keys of the design metadata, sorted.
+func (d *Design) MetaKeys() []string {
+	keys := make([]string, 0, len(d.meta))
+	for key := range d.meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metaFromProperties reads the metadata saved on a Design node.
+func metaFromProperties(props map[string]any) map[string]string {
+	var meta map[string]string
+	for prop, value := range props {
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(prop, metaPrefix) || s == "" {
+			continue
+		}
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		meta[strings.TrimPrefix(prop, metaPrefix)] = s
+	}
+	return meta
+}
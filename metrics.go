@@ -0,0 +1,61 @@
+package neoarch
+
+import (
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+// expvar metrics
+// -----------------------------------------------------------------------------
+
+// MetricsSnapshot is what RegisterExpvar publishes: the counts of the design as
+// of the last Refresh.
+type MetricsSnapshot struct {
+	Nodes               int                      `json:"nodes"`
+	NodesByType         map[NodeType]int         `json:"nodes_by_type"`
+	Relationships       int                      `json:"relationships"`
+	RelationshipsByType map[RelationshipType]int `json:"relationships_by_type"`
+	ExternalNodes       int                      `json:"external_nodes"`
+	LastBuild           int64                    `json:"last_build_unix"` // When Refresh was last called
+}
+
+// RegisterExpvar publishes the node and relationship counts of the design as
+// the expvar variable named prefix, served as JSON on /debug/vars, so a service
+// rebuilding its design can alert when it shrinks. The counts are a snapshot
+// taken now and on every call to Refresh; reading them is safe from any
+// goroutine, while the design itself keeps being built from one.
+// It fails when a variable with that name is already published.
+func (d *Design) RegisterExpvar(prefix string) error {
+	if expvar.Get(prefix) != nil {
+		return fmt.Errorf("expvar %q is already published", prefix)
+	}
+	if d.metrics == nil {
+		d.metrics = &atomic.Pointer[MetricsSnapshot]{}
+	}
+	d.Refresh()
+	metrics := d.metrics
+	expvar.Publish(prefix, expvar.Func(func() any {
+		return metrics.Load()
+	}))
+	return nil
+}
+
+// Refresh updates the snapshot published by RegisterExpvar. Call it after
+// rebuilding the design. It does nothing when the design was not registered.
+func (d *Design) Refresh() {
+	if d.metrics == nil {
+		return
+	}
+	stats := d.Stats()
+	d.metrics.Store(&MetricsSnapshot{
+		Nodes:               stats.Nodes,
+		NodesByType:         stats.NodesByType,
+		Relationships:       stats.Relationships,
+		RelationshipsByType: stats.RelationshipsByType,
+		ExternalNodes:       stats.ExternalNodes,
+		LastBuild:           time.Now().Unix(),
+	})
+}
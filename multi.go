@@ -0,0 +1,63 @@
+package neoarch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// maxParallelSaves bounds how many targets SaveToMultiple writes at once.
+const maxParallelSaves = 4
+
+// SaveTarget is one database SaveToMultiple writes the design to.
+type SaveTarget struct {
+	Name          string // Key of the target in the results, defaults to SessionConfig.DatabaseName
+	Driver        neo4j.DriverWithContext
+	SessionConfig neo4j.SessionConfig
+	Options       []SaveOption
+}
+
+func (t SaveTarget) key() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.SessionConfig.DatabaseName
+}
+
+// SaveToMultiple saves the design to every target, e.g. a "staging" and a
+// "prod" database, a few at a time. A failing target doesn't stop the others:
+// the result maps each target name to the error of its save, nil on success.
+// Once ctx is done, in-flight saves are cancelled and targets not started yet
+// report ctx.Err(). Target names must be unique, and the design must not be
+// modified until SaveToMultiple returns.
+func SaveToMultiple(ctx context.Context, targets []SaveTarget, d *Design) map[string]error {
+	results := make(map[string]error, len(targets))
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	slots := make(chan struct{}, maxParallelSaves)
+
+	for _, target := range targets {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			results[target.key()] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := d.SaveToNeo4j(ctx, target.Driver, target.SessionConfig, target.Options...)
+			mu.Lock()
+			results[target.key()] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,110 @@
+package neoarch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// gauge tracks how many saves run at once.
+type gauge struct {
+	mu          sync.Mutex
+	active, max int
+}
+
+func (g *gauge) add(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active += n
+	g.max = max(g.max, g.active)
+}
+
+// slowDriver is a recordingDriver whose writes take a while, fail with err when
+// set, and give up when their context is done.
+type slowDriver struct {
+	recordingDriver
+	gauge *gauge
+	err   error
+}
+
+func (d *slowDriver) NewSession(context.Context, neo4j.SessionConfig) neo4j.SessionWithContext {
+	return &slowSession{recordingSession: recordingSession{driver: &d.recordingDriver}, slow: d}
+}
+
+type slowSession struct {
+	recordingSession
+	slow *slowDriver
+}
+
+func (s *slowSession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork, configs ...func(*neo4j.TransactionConfig)) (any, error) {
+	s.slow.gauge.add(1)
+	defer s.slow.gauge.add(-1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(20 * time.Millisecond):
+	}
+	if s.slow.err != nil {
+		return nil, s.slow.err
+	}
+	return s.recordingSession.ExecuteWrite(ctx, work, configs...)
+}
+
+func TestSaveToMultiple(t *testing.T) {
+	d := newShopDesign()
+	g := &gauge{}
+	unavailable := errors.New("unavailable")
+	var targets []SaveTarget
+	drivers := map[string]*slowDriver{}
+	for i := range 10 {
+		name := fmt.Sprintf("db%d", i)
+		driver := &slowDriver{gauge: g}
+		if i == 3 {
+			driver.err = unavailable
+		}
+		drivers[name] = driver
+		targets = append(targets, SaveTarget{Name: name, Driver: driver})
+	}
+
+	results := SaveToMultiple(context.Background(), targets, d)
+	if len(results) != len(targets) {
+		t.Fatalf("got %d results, want %d", len(results), len(targets))
+	}
+	for name, err := range results {
+		switch {
+		case name == "db3" && !errors.Is(err, unavailable):
+			t.Errorf("db3 returned %v, want its own error", err)
+		case name != "db3" && err != nil:
+			t.Errorf("%s returned %v", name, err)
+		case name != "db3" && len(drivers[name].statements) != len(BuildNodeStatements(d))+len(BuildRelationshipStatements(d)):
+			t.Errorf("%s ran %d statements", name, len(drivers[name].statements))
+		}
+	}
+	if g.max > maxParallelSaves {
+		t.Errorf("%d saves ran at once, want at most %d", g.max, maxParallelSaves)
+	}
+}
+
+func TestSaveToMultipleCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g := &gauge{}
+	var targets []SaveTarget
+	for i := range 6 {
+		targets = append(targets, SaveTarget{SessionConfig: neo4j.SessionConfig{DatabaseName: fmt.Sprintf("db%d", i)}, Driver: &slowDriver{gauge: g}})
+	}
+	results := SaveToMultiple(ctx, targets, newShopDesign())
+	if len(results) != len(targets) {
+		t.Fatalf("got %d results, want %d", len(results), len(targets))
+	}
+	for name, err := range results {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s returned %v, want context.Canceled", name, err)
+		}
+	}
+}
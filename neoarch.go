@@ -8,8 +8,14 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
@@ -28,6 +34,9 @@ const (
 	NodeTypeSystem    NodeType = "System"
 	NodeTypeContainer NodeType = "Container"
 	NodeTypeComponent NodeType = "Component"
+
+	NodeTypePersonGroup NodeType = "PersonGroup"
+	NodeTypeCode        NodeType = "Code"
 )
 
 // RelationshipType is a type for naming relationships
@@ -37,14 +46,70 @@ const (
 	RelUses          RelationshipType = "USES"
 	RelBelongsTo     RelationshipType = "BELONGS_TO"
 	RelInteractsWith RelationshipType = "INTERACTS_WITH"
+	RelMemberOf      RelationshipType = "MEMBER_OF"
+	RelImpliedUse    RelationshipType = "IMPLIED_USE"
+)
+
+// InteractionStyle tells whether the start of a relationship waits for the end
+// (e.g. a gRPC call) or not (e.g. scheduling a workflow or publishing a message).
+// The zero value leaves it unspecified.
+type InteractionStyle string
+
+const (
+	InteractionSynchronous  InteractionStyle = "Synchronous"
+	InteractionAsynchronous InteractionStyle = "Asynchronous"
 )
 
 // Relationship represents a direction from "start" to "end" with a type & description.
+//
+// A relationship is identified by its start, end, type and description (see Key):
+// two USES relationships between the same nodes with different descriptions are
+// distinct parallel edges, both in Neo4j and in every exporter.
 type Relationship struct {
 	StartID     string
 	EndID       string
 	Type        RelationshipType
 	Description string
+	Technology  string   // e.g. "gRPC", "HTTPS/JSON"
+	Tags        []string // Arbitrary tags
+	NoImplied   bool     // Leave the relationship out of ImpliedRelationships
+
+	InteractionStyle InteractionStyle // Sync or async, unspecified by default
+	Weight           int              // Relative importance, e.g. calls per second; 0 when unset
+	Optional         bool             // The start works without the end, e.g. a feature-flagged cache
+
+	// DerivedFrom is the explicit relationship a derived one was computed from,
+	// e.g. by ImpliedRelationships or AtLevel. It is nil for explicit relationships.
+	DerivedFrom *RelationshipRef
+
+	// Properties are extra properties saved on the Neo4j relationship, e.g.
+	// sla_ms (see Design.AddRelationshipWithProps).
+	Properties map[string]any
+
+	callSite string // file:line of the call that added it, see Location
+}
+
+// Key returns the identity of the relationship: start, end, type and description.
+func (r Relationship) Key() string {
+	return r.Ref().Key()
+}
+
+// Ref returns the identity of the relationship.
+func (r Relationship) Ref() RelationshipRef {
+	return RelationshipRef{StartID: r.StartID, EndID: r.EndID, Type: r.Type, Description: r.Description}
+}
+
+// RelationshipRef identifies a relationship. See Relationship.Key.
+type RelationshipRef struct {
+	StartID     string
+	EndID       string
+	Type        RelationshipType
+	Description string
+}
+
+// Key returns the identity of the referenced relationship, as Relationship.Key.
+func (r RelationshipRef) Key() string {
+	return r.StartID + "|" + r.EndID + "|" + string(r.Type) + "|" + r.Description
 }
 
 // INode defines an interface for objects that can be identified uniquely in the design.
@@ -61,15 +126,22 @@ type INode interface {
 
 // Node is the shared struct for all C4 elements.
 type Node struct {
-	ID          string   // Unique identifier (could be the "name")
-	Name        string   // Display name
-	Labels      []string // Arbitrary extra labels that will be added to the node in addition to the node type
-	Description string   // Brief description
-	NodeType    NodeType // e.g. Person, System, Container, Component
-	Tags        []string // Arbitrary tags
-	IsExternal  bool     // For marking external nodes
-	design      *Design  // Link back to the containing Design
-	ParentNode  INode    // Parent node (if any)
+	ID                string       // Unique identifier (could be the "name")
+	Name              string       // Display name
+	Labels            []string     // Arbitrary extra labels that will be added to the node in addition to the node type
+	Description       string       // Brief description
+	NodeType          NodeType     // e.g. Person, System, Container, Component
+	Tags              []string     // Arbitrary tags
+	IsExternal        bool         // For marking external nodes
+	Technology        string       // e.g. "Go", "PostgreSQL", "gRPC"
+	Appearance        ElementStyle // Explicit visual attributes, independent of tags (see Style)
+	Snippet           CodeSnippet  // Implementation hint, e.g. the signature of a gRPC method (see Component.Snippet)
+	Layout            LayoutHint   // Placement hints for exporters (see LayoutHint)
+	Deprecated        bool         // Being decommissioned, see Deprecate
+	DeprecationReason string       // Why it is deprecated, e.g. "Replaced by Billing v2"
+	design            *Design      // Link back to the containing Design
+	ParentNode        INode        // Parent node (if any)
+	callSite          string       // file:line of the call that declared it, see Location
 }
 
 func NewNodeWithId(id string, design *Design, name, description string, nodeType NodeType) *Node {
@@ -135,6 +207,11 @@ func (n *Node) GetID() string {
 	return n.ID
 }
 
+// Parent returns the node n belongs to, or nil for top-level nodes.
+func (n *Node) Parent() INode {
+	return n.ParentNode
+}
+
 func (n *Node) Tag(tag string) {
 	n.Tags = append(n.Tags, tag)
 }
@@ -145,6 +222,32 @@ func (n *Node) Internal() {
 	n.IsExternal = false
 }
 
+// Deprecate marks the node as being decommissioned rather than removing it, so
+// it stays in the model, and in Neo4j with its deprecated and deprecationReason
+// properties, for history. Queries can filter on the property; the Structurizr
+// exporter tags the node "Deprecated" and renders it faded, and the
+// OmitDeprecated export option leaves it out.
+func (n *Node) Deprecate(reason string) *Node {
+	n.Deprecated = true
+	n.DeprecationReason = reason
+	return n
+}
+
+// Style sets explicit visual attributes on the node, rendered by the
+// Structurizr, DOT and Mermaid exporters. Empty values fall back to the style
+// of the node's tags (see Design.TagDefaults), then to the defaults of its type.
+func (n *Node) Style(shape, background, stroke string) *Node {
+	n.Appearance = ElementStyle{Shape: shape, Background: background, Stroke: stroke}
+	return n
+}
+
+// LayoutHint sets placement hints on the node, e.g. to keep API gateways at the
+// edge of diagrams. They replace any previous hints.
+func (n *Node) LayoutHint(h LayoutHint) *Node {
+	n.Layout = h
+	return n
+}
+
 // Tag appends a tag to the Person.
 func (p *Person) Tag(tag string) *Person {
 	p.Node.Tag(tag)
@@ -161,6 +264,18 @@ func (p *Person) Internal() *Person {
 	return p
 }
 
+// Style sets explicit visual attributes on the Person. See Node.Style.
+func (p *Person) Style(shape, background, stroke string) *Person {
+	p.Node.Style(shape, background, stroke)
+	return p
+}
+
+// LayoutHint sets placement hints on the Person. See Node.LayoutHint.
+func (p *Person) LayoutHint(h LayoutHint) *Person {
+	p.Node.LayoutHint(h)
+	return p
+}
+
 // Tag appends a tag to the Container.
 func (c *Container) Tag(tag string) *Container {
 	c.Node.Tag(tag)
@@ -172,6 +287,12 @@ func (n *Container) AddLabel(label string) *Container {
 	return n
 }
 
+// WithTechnology sets the technology of the Container.
+func (c *Container) WithTechnology(technology string) *Container {
+	c.Node.Technology = technology
+	return c
+}
+
 func (c *Container) External() *Container {
 	c.Node.External()
 	return c
@@ -182,12 +303,30 @@ func (c *Container) Internal() *Container {
 	return c
 }
 
+// Style sets explicit visual attributes on the Container. See Node.Style.
+func (c *Container) Style(shape, background, stroke string) *Container {
+	c.Node.Style(shape, background, stroke)
+	return c
+}
+
+// LayoutHint sets placement hints on the Container. See Node.LayoutHint.
+func (c *Container) LayoutHint(h LayoutHint) *Container {
+	c.Node.LayoutHint(h)
+	return c
+}
+
 // Tag appends a tag to the Component.
 func (c *Component) Tag(tag string) *Component {
 	c.Node.Tag(tag)
 	return c
 }
 
+// WithTechnology sets the technology of the Component.
+func (c *Component) WithTechnology(technology string) *Component {
+	c.Node.Technology = technology
+	return c
+}
+
 func (c *Component) External() *Component {
 	c.Node.External()
 	return c
@@ -198,6 +337,18 @@ func (c *Component) Internal() *Component {
 	return c
 }
 
+// Style sets explicit visual attributes on the Component. See Node.Style.
+func (c *Component) Style(shape, background, stroke string) *Component {
+	c.Node.Style(shape, background, stroke)
+	return c
+}
+
+// LayoutHint sets placement hints on the Component. See Node.LayoutHint.
+func (c *Component) LayoutHint(h LayoutHint) *Component {
+	c.Node.LayoutHint(h)
+	return c
+}
+
 // -----------------------------------------------------------------------------
 // DSL Structures: Person, System, Container, Component
 // Each is basically a wrapper around Node with chainable methods
@@ -220,8 +371,44 @@ func (p *Person) Uses(n INode, description string) *Person {
 	return p
 }
 
-// Person can also use "UsedBy" if you want to invert direction, but here we
-// only define InteractsWith, as per your example usage.
+// UsesNoImplied is like Uses but the relationship does not produce implied relationships.
+func (p *Person) UsesNoImplied(n INode, description string) *Person {
+	p.design.recordRelationship(Relationship{StartID: p.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, NoImplied: true})
+	return p
+}
+
+// UsedBy creates a "USES" relationship from the given node to this person,
+// e.g. a system notifying its users.
+func (p *Person) UsedBy(n INode, description string) *Person {
+	p.design.addRelationship(n, p, RelUses, description)
+	return p
+}
+
+// -----------------------------------------------------------------------------
+
+// PersonGroup groups persons (typically roles) under a single name,
+// e.g. "Internal Staff" for "Support Agent" and "Admin".
+type PersonGroup struct {
+	*Node
+	design *Design // Link back to the parent design
+}
+
+// Member records that the given person belongs to this group with a "MEMBER_OF" relationship.
+func (g *PersonGroup) Member(p *Person) *PersonGroup {
+	g.design.addRelationship(p, g, RelMemberOf, "Is member of")
+	return g
+}
+
+// Tag appends a tag to the PersonGroup.
+func (g *PersonGroup) Tag(tag string) *PersonGroup {
+	g.Node.Tag(tag)
+	return g
+}
+
+func (g *PersonGroup) External() *PersonGroup {
+	g.Node.External()
+	return g
+}
 
 // -----------------------------------------------------------------------------
 
@@ -231,9 +418,24 @@ type System struct {
 	design *Design // Link back to the parent design
 }
 
-// UsedBy creates a "USES" relationship from the given person to this system.
-func (s *System) UsedBy(p *Person, description string) *System {
-	s.design.addRelationship(p, s, RelUses, description)
+// UsedBy creates a "USES" relationship from the given node (person, system,
+// container, ...) to this system.
+func (s *System) UsedBy(n INode, description string) *System {
+	s.design.addRelationship(n, s, RelUses, description)
+	return s
+}
+
+// UsedByVia records that p uses the system through c, its entry point: a USES
+// relationship from p to the container, from which ImpliedRelationships
+// derives the one from p to the system. c must belong to the system or one of
+// its subsystems; otherwise nothing is added and the error is reported by
+// Validate.
+func (s *System) UsedByVia(p *Person, c *Container, description string) *System {
+	if !slices.Contains(s.design.ancestorsOrSelf(c.Node), s.Node) {
+		s.design.recordError(fmt.Errorf("container %s does not belong to system %s", c.FullId(), s.FullId()))
+		return s
+	}
+	s.design.addRelationship(p, c, RelUses, description)
 	return s
 }
 
@@ -242,17 +444,92 @@ func (s *System) Uses(n INode, description string) *System {
 	return s
 }
 
+// AllContainersUse creates a "USES" relationship from every container of the
+// system declared so far to target, for cross-cutting dependencies such as a
+// tracing collector. Nested containers are covered by their parent container.
+func (s *System) AllContainersUse(target INode, description string) *System {
+	for _, child := range s.design.Children(s.FullId()) {
+		if child.NodeType == NodeTypeContainer {
+			s.design.addRelationship(child, target, RelUses, description)
+		}
+	}
+	return s
+}
+
+// UsesNoImplied is like Uses but the relationship does not produce implied relationships.
+func (s *System) UsesNoImplied(n INode, description string) *System {
+	s.design.recordRelationship(Relationship{StartID: s.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, NoImplied: true})
+	return s
+}
+
 // Tag adds a tag (chainable).
 func (s *System) Tag(t string) *System {
 	s.Node.Tag(t)
 	return s
 }
 
+// InScope marks the system as the focus of the design, the software system in
+// scope of the C4 diagrams; the others are context. Only one system is in scope
+// at a time: calling it on another system moves the focus there.
+func (s *System) InScope() *System {
+	s.design.inScope = s.FullId()
+	return s
+}
+
+// InScopeSystem returns the system marked with System.InScope, or nil.
+func (d *Design) InScopeSystem() *Node {
+	return d.nodesByFullId()[d.inScope]
+}
+
 func (s *System) External() *System {
 	s.Node.External()
 	return s
 }
 
+// Style sets explicit visual attributes on the System. See Node.Style.
+func (s *System) Style(shape, background, stroke string) *System {
+	s.Node.Style(shape, background, stroke)
+	return s
+}
+
+// LayoutHint sets placement hints on the System. See Node.LayoutHint.
+func (s *System) LayoutHint(h LayoutHint) *System {
+	s.Node.LayoutHint(h)
+	return s
+}
+
+// System creates a nested subsystem and relates subsystem->system with "BELONGS_TO".
+// Use it to model enterprise systems that aggregate smaller ones.
+func (s *System) System(name, description string) *System {
+	subsystem := &System{
+		Node:   NewNodeWithParent(s, s.design, name, description, NodeTypeSystem),
+		design: s.design,
+	}
+	s.design.setNode(subsystem.Node)
+
+	// We record that the subsystem belongs to this system
+	s.design.addRelationship(subsystem, s, RelBelongsTo, "Is part of")
+
+	return subsystem
+}
+
+// Person adds a person scoped to the system, e.g. operations staff who only
+// work with it, instead of one at the design root. It belongs to the system in
+// Neo4j; the Structurizr exporter, whose persons can't nest, declares it at
+// workspace level tagged with the system's name, and named e.g. "Ops (Billing)"
+// when another person is named "Ops". What the person uses doesn't imply
+// anything for the system (see ImpliedRelationships), nor counts towards it in
+// DependencyMatrix and TopDependedOn.
+func (s *System) Person(name, description string) *Person {
+	p := &Person{
+		Node:   NewNodeWithIdAndParent("person_"+name, s, s.design, name, description, NodeTypePerson),
+		design: s.design,
+	}
+	s.design.setNode(p.Node)
+	s.design.addRelationship(p, s, RelBelongsTo, "Is part of")
+	return p
+}
+
 // Container creates a new Container and (by convention) relates the system->container
 // with "BELONGS_TO". You can adapt as needed.
 func (s *System) Container(name, description string) *Container {
@@ -276,10 +553,11 @@ type Container struct {
 	system *System // Link back to the parent system
 }
 
-// UsedBy creates a "USES" relationship from the given person to this container.
-func (c *Container) UsedBy(p INode, description string) *Container {
-	// p uses c: add explicit relationship: p -> container
-	c.design.addRelationship(p, c, RelUses, description)
+// UsedBy creates a "USES" relationship from the given node (person, system,
+// container, ...) to this container.
+func (c *Container) UsedBy(n INode, description string) *Container {
+	// n uses c: add explicit relationship: n -> container
+	c.design.addRelationship(n, c, RelUses, description)
 	return c
 }
 
@@ -290,6 +568,61 @@ func (c *Container) Uses(n INode, description string) *Container {
 	return c
 }
 
+// UsesNoImplied is like Uses but the relationship does not produce implied
+// relationships, e.g. a health check that should not link the systems.
+func (c *Container) UsesNoImplied(n INode, description string) *Container {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, NoImplied: true})
+	return c
+}
+
+// Rel creates a relationship of a custom type, e.g. "REPLICATES_TO". The type is
+// upper-cased and anything but letters, digits and underscores becomes "_".
+// See Design.addCustomRelationship for how it is saved and exported.
+func (c *Container) Rel(relType string, target INode, description string) *Container {
+	c.design.addCustomRelationship(c, target, relType, description, false)
+	return c
+}
+
+// RelNoImplied is like Rel but the relationship does not produce implied relationships.
+func (c *Container) RelNoImplied(relType string, target INode, description string) *Container {
+	c.design.addCustomRelationship(c, target, relType, description, true)
+	return c
+}
+
+// UsesWithTechnology creates a "USES" relationship over the given technology (e.g. "gRPC").
+func (c *Container) UsesWithTechnology(n INode, description, technology string) *Container {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, Technology: technology})
+	return c
+}
+
+// UsesAsync creates an asynchronous "USES" relationship, e.g. scheduling a workflow.
+func (c *Container) UsesAsync(n INode, description string) *Container {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, InteractionStyle: InteractionAsynchronous})
+	return c
+}
+
+// UsesOptional creates an optional "USES" relationship: the container can use
+// n but also works without it, e.g. a cache behind a feature flag.
+func (c *Container) UsesOptional(n INode, description string) *Container {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, Optional: true})
+	return c
+}
+
+// Container creates a nested Container and relates child->container with "BELONGS_TO".
+// Use it for sub-deployables of a container, e.g. the workers of a worker pool.
+func (c *Container) Container(name, description string) *Container {
+	container := &Container{
+		Node:   NewNodeWithParent(c, c.design, name, description, NodeTypeContainer),
+		system: c.system,
+	}
+	c.design.setNode(container.Node)
+
+	// We record that the nested container belongs to this container
+	c.design.addRelationship(container, c, RelBelongsTo, "Is part of")
+
+	return container
+}
+
 // Component creates a new Component and relates container->component with BELONGS_TO.
 func (c *Container) Component(name, description string) *Component {
 	return c.ComponentWithId(name, name, description)
@@ -310,7 +643,7 @@ func (c *Container) ComponentWithId(id string, name, description string) *Compon
 
 func (c *Container) Custom(label string, name string, description string, belongsToDescription ...string) *CustomComponent {
 	component := &CustomComponent{
-		Node:      NewNodeWithIdAndParent(name, c, c.design, name, description, NodeType(label)),
+		Node:      NewNodeWithIdAndParent(name, c, c.design, name, description, c.design.customLabel(label)),
 		container: c,
 	}
 	c.design.setNode(component.Node)
@@ -341,7 +674,7 @@ func (c *CustomComponent) BelongsTo(n INode, description string) *CustomComponen
 
 func (c *CustomComponent) CustomWithId(id string, label string, name string, description string, belongsToDescription ...string) *CustomComponent {
 	component := &CustomComponent{
-		Node:      NewNodeWithIdAndParent(id, c, c.design, name, description, NodeType(label)),
+		Node:      NewNodeWithIdAndParent(id, c, c.design, name, description, c.design.customLabel(label)),
 		container: c.container,
 	}
 	c.design.setNode(component.Node)
@@ -366,11 +699,24 @@ func (c *CustomComponent) Uses(n INode, description string) *CustomComponent {
 	return c
 }
 
+// UsedBy creates a "USES" relationship from the given node to this element.
 func (c *CustomComponent) UsedBy(p INode, description string) *CustomComponent {
 	c.design.addRelationship(p, c, RelUses, description)
 	return c
 }
 
+// Rel creates a relationship of a custom type. See Container.Rel.
+func (c *CustomComponent) Rel(relType string, target INode, description string) *CustomComponent {
+	c.design.addCustomRelationship(c, target, relType, description, false)
+	return c
+}
+
+// RelNoImplied is like Rel but the relationship does not produce implied relationships.
+func (c *CustomComponent) RelNoImplied(relType string, target INode, description string) *CustomComponent {
+	c.design.addCustomRelationship(c, target, relType, description, true)
+	return c
+}
+
 // -----------------------------------------------------------------------------
 
 // Component represents a "Component" node in C4.
@@ -385,8 +731,14 @@ func (c *Component) AddLabel(label string) *Component {
 }
 
 func (c *Component) Custom(label string, name string, description string, belongsToDescription ...string) *CustomComponent {
+	return c.CustomWithId(name, label, name, description, belongsToDescription...)
+}
+
+// CustomWithId is like Custom with an explicit id, for names that are not
+// valid ids, e.g. "GET /users/{id}".
+func (c *Component) CustomWithId(id string, label string, name string, description string, belongsToDescription ...string) *CustomComponent {
 	component := &CustomComponent{
-		Node:      NewNodeWithIdAndParent(name, c, c.design, name, description, NodeType(label)),
+		Node:      NewNodeWithIdAndParent(id, c, c.design, name, description, c.design.customLabel(label)),
 		container: c.container,
 	}
 	c.design.setNode(component.Node)
@@ -406,14 +758,108 @@ func (c *Component) Uses(n INode, description string) *Component {
 	return c
 }
 
+// UsesNoImplied is like Uses but the relationship does not produce implied relationships.
+func (c *Component) UsesNoImplied(n INode, description string) *Component {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, NoImplied: true})
+	return c
+}
+
+// Rel creates a relationship of a custom type. See Container.Rel.
+func (c *Component) Rel(relType string, target INode, description string) *Component {
+	c.design.addCustomRelationship(c, target, relType, description, false)
+	return c
+}
+
+// RelNoImplied is like Rel but the relationship does not produce implied relationships.
+func (c *Component) RelNoImplied(relType string, target INode, description string) *Component {
+	c.design.addCustomRelationship(c, target, relType, description, true)
+	return c
+}
+
+// UsesWithTags creates a "USES" relationship carrying the given tags
+// (e.g. "allowed" to exempt it from EncapsulationViolations).
+func (c *Component) UsesWithTags(n INode, description string, tags ...string) *Component {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, Tags: tags})
+	return c
+}
+
+// UsesWithTechnology creates a "USES" relationship over the given technology (e.g. "gRPC").
+func (c *Component) UsesWithTechnology(n INode, description, technology string) *Component {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, Technology: technology})
+	return c
+}
+
+// UsesAsync creates an asynchronous "USES" relationship, e.g. publishing an event.
+func (c *Component) UsesAsync(n INode, description string) *Component {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, InteractionStyle: InteractionAsynchronous})
+	return c
+}
+
+// UsesOptional creates an optional "USES" relationship. See Container.UsesOptional.
+func (c *Component) UsesOptional(n INode, description string) *Component {
+	c.design.recordRelationship(Relationship{StartID: c.FullId(), EndID: n.FullId(), Type: RelUses, Description: description, Optional: true})
+	return c
+}
+
+// Snippet attaches a code snippet or signature to the component, e.g. the gRPC
+// method it implements, for developer-facing documentation. It replaces any
+// previous snippet. (Component.Code adds a code-level element instead.)
+func (c *Component) Snippet(language, snippet string) *Component {
+	c.Node.Snippet = CodeSnippet{Language: language, Code: snippet}
+	return c
+}
+
 func (c *Component) BelongsTo(n INode, description string) *Component {
 	c.design.addRelationship(c, n, RelBelongsTo, description)
 	return c
 }
 
-// UsedBy creates a "USES" relationship from the given person to this component.
-func (c *Component) UsedBy(p INode, description string) *Component {
-	c.design.addRelationship(p, c, RelUses, description)
+// UsedBy creates a "USES" relationship from the given node (person, system,
+// container, ...) to this component.
+func (c *Component) UsedBy(n INode, description string) *Component {
+	c.design.addRelationship(n, c, RelUses, description)
+	return c
+}
+
+// Code creates a code-level element (e.g. a class or a package) of this component
+// and relates code->component with BELONGS_TO. Code elements are rendered by
+// ToDOT, ToMermaid and ToMarkdown, but left out of the Structurizr DSL.
+func (c *Component) Code(name, description, technology string) *CodeElement {
+	code := &CodeElement{
+		Node:      NewNodeWithParent(c, c.design, name, description, NodeTypeCode),
+		component: c,
+	}
+	code.Node.Technology = technology
+	c.design.setNode(code.Node)
+
+	// We record that the code element belongs to this component
+	c.design.addRelationship(code, c, RelBelongsTo, "Is part of")
+
+	return code
+}
+
+// -----------------------------------------------------------------------------
+
+// CodeElement represents a code-level element (class, package, ...) below a Component.
+type CodeElement struct {
+	*Node
+	component *Component
+}
+
+func (c *CodeElement) Uses(n INode, description string) *CodeElement {
+	c.design.addRelationship(c, n, RelUses, description)
+	return c
+}
+
+// UsedBy creates a "USES" relationship from the given node to this code element.
+func (c *CodeElement) UsedBy(n INode, description string) *CodeElement {
+	c.design.addRelationship(n, c, RelUses, description)
+	return c
+}
+
+// Tag appends a tag to the CodeElement.
+func (c *CodeElement) Tag(tag string) *CodeElement {
+	c.Node.Tag(tag)
 	return c
 }
 
@@ -421,13 +867,45 @@ func (c *Component) UsedBy(p INode, description string) *Component {
 // Design: the container for all nodes & relationships
 // -----------------------------------------------------------------------------
 
-// Design represents a C4 model
+// Design represents a C4 model.
+//
+// Elements and relationships may be added from several goroutines, e.g. by
+// importers working on one system each: the constructors, the Uses family,
+// NodeReference, RemoveNode, RemoveRelationship, Design.Tag and
+// Design.External lock the design. The settings (DefaultDescription,
+// RegisterCustomLabel, SetMeta...), tagging or styling a node shared between
+// goroutines, relationship handles (UsesRel) and every read of the design
+// (exports, queries, Validate, SaveToNeo4j) are not synchronized: configure
+// the design first and read it once building is done.
 type Design struct {
-	ID            string
-	Name          string
-	Description   string
-	nodes         map[string]*Node
-	relationships []Relationship
+	ID                  string
+	Name                string
+	Description         string
+	Version             string // Snapshot the design is saved as, see LoadFromNeo4jVersion; empty for an unversioned design
+	nodes               map[string]*Node
+	relationships       []Relationship
+	defaultDescription  string // used for relationships added with an empty description
+	duplicatePolicy     DuplicateRelationshipPolicy
+	errs                []error      // problems recorded while building the design, reported by Validate
+	log                 *slog.Logger // nil means slog.Default()
+	hierarchy           *hierarchy   // cached by hierarchyIndex, reset when nodes or relationships are added
+	labelPolicy         LabelPolicy
+	customKinds         map[string]CustomKind // custom label -> how exporters render it
+	relationshipIndex   *relationshipIndex    // built by relIndex, maintained by recordRelationship
+	scenarios           map[string]*Scenario
+	impliedUseDisabled  bool                             // set by EnableImpliedUse(false)
+	inScope             string                           // FullId of the system set by System.InScope
+	metrics             *atomic.Pointer[MetricsSnapshot] // published by RegisterExpvar, updated by Refresh
+	dynamicViews        []*DynamicView
+	customViews         []*CustomView
+	strictReferences    bool
+	references          []UnresolvedReference                             // NodeReference lookups in strict mode, checked by unresolvedReferences
+	describe            func(start, end INode, t RelationshipType) string // set by DefaultRelationshipDescription
+	requireDescriptions bool                                              // set by RequireRelationshipDescriptions
+	tagDefaults         map[string]TagDefaults                            // tag -> defaults, set by TagDefaults
+	meta                map[string]string                                 // set by SetMeta, saved on the Design node
+	mu                  sync.Mutex                                        // guards nodes, relationships, errs and references while building, see Design
+	hierarchyMu         sync.Mutex                                        // guards building hierarchy, e.g. by concurrent saves of SaveToMultiple
 }
 
 // NewDesign creates a new C4 design
@@ -453,7 +931,7 @@ func NewDesign(name, description string) *Design {
 
 func (d *Design) Custom(label string, name string, description string, belongsToDescription ...string) *CustomComponent {
 	component := &CustomComponent{
-		Node: NewNodeWithId(name, d, name, description, NodeType(label)),
+		Node: NewNodeWithId(name, d, name, description, d.customLabel(label)),
 	}
 	d.setNode(component.Node)
 
@@ -468,22 +946,57 @@ func (d *Design) Custom(label string, name string, description string, belongsTo
 }
 
 // NodeReference fetches an element from the design by its ID.
+//
+// An id no node has yet gets a placeholder Unknown node, tagged "unresolved",
+// which a node declared later with that id replaces. With StrictReferences,
+// no placeholder is added: the lookup is recorded, and Validate and
+// SaveToNeo4j fail if the id is still unknown by then.
 func (d *Design) NodeReference(id string) INode {
-	node, ok := d.nodes[id]
-	if !ok {
-		d.setNode(&Node{
-			ID:          id,
-			NodeType:    NodeTypeUnknown,
-			Name:        id,
-			Description: "Unknown node",
-			design:      d,
-			// ParentNode:  node,
-		})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if node, ok := d.nodes[id]; ok {
+		return node
+	}
+	if d.strictReferences {
+		callSite := "unknown"
+		if _, file, line, ok := runtime.Caller(1); ok {
+			callSite = fmt.Sprintf("%s:%d", file, line)
+		}
+		d.references = append(d.references, UnresolvedReference{ID: id, CallSite: callSite})
+		return &NodeReference{ID: id}
+	}
+	d.setNodeLocked(&Node{
+		ID:          id,
+		NodeType:    NodeTypeUnknown,
+		Name:        id,
+		Description: "Unknown node",
+		Tags:        []string{TagUnresolved},
+		design:      d,
+	})
+	return d.nodes[id]
+}
+
+// Children returns the nodes that belong to the node with the given ID (or
+// FullId), following BELONGS_TO relationships, in the order they were added.
+func (d *Design) Children(id string) []*Node {
+	parent := d.lookupNode(id)
+	if parent == nil {
+		return nil
 	}
-	return node
+	return slices.Clone(d.hierarchyIndex().children[parent.FullId()])
 }
 
 func (d *Design) setNode(node *Node) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setNodeLocked(node)
+}
+
+// setNodeLocked is setNode for callers holding d.mu.
+func (d *Design) setNodeLocked(node *Node) {
+	if err := checkNodeName(node); err != nil {
+		d.recordErrorLocked(err)
+	}
 	if _, ok := d.nodes[node.ID]; ok {
 		d.nodes[node.ID].Description = node.Description
 		d.nodes[node.ID].Labels = node.Labels
@@ -497,8 +1010,63 @@ func (d *Design) setNode(node *Node) {
 			d.nodes[node.ID].Labels = node.Labels
 		}
 	} else {
+		if node.callSite == "" {
+			node.callSite = callerLocation()
+		}
 		d.nodes[node.ID] = node
 	}
+	d.hierarchy = nil
+}
+
+// recordError records a problem found while building the design, reported by
+// Validate and refused by SaveToNeo4j.
+func (d *Design) recordError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recordErrorLocked(err)
+}
+
+// recordErrorLocked is recordError for callers holding d.mu. The error is
+// recorded as a BuildError located at the closest call outside the package.
+func (d *Design) recordErrorLocked(err error) {
+	d.errs = append(d.errs, &BuildError{Err: err, Location: callerLocation()})
+}
+
+// BuildError is an error recorded while building a design, reported by
+// Validate and returned by SaveToNeo4j, with the file:line of the call that
+// caused it.
+type BuildError struct {
+	Err      error
+	Location string // file:line of the call, "" when unknown
+}
+
+func (e *BuildError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// checkNodeName reports elements declared with a blank name or id, whose ids
+// would be empty or end with a dot and break the ids of their children, Cypher
+// queries and exported identifiers. The element is still added, so chained
+// calls keep working, and the error is reported by Validate and SaveToNeo4j.
+func checkNodeName(node *Node) error {
+	if node.NodeType == NodeTypeDesign {
+		return nil
+	}
+	where := ""
+	if node.ParentNode != nil {
+		where = " in " + node.ParentNode.FullId()
+	}
+	if strings.TrimSpace(node.Name) == "" {
+		return fmt.Errorf("%s%s has an empty name", node.NodeType, where)
+	}
+	if local := node.ID[strings.LastIndex(node.ID, ".")+1:]; strings.TrimSpace(local) == "" {
+		return fmt.Errorf("%s %q%s has an empty id", node.NodeType, node.Name, where)
+	}
+	return nil
 }
 
 type NodeReference struct {
@@ -511,6 +1079,9 @@ func (n *NodeReference) FullId() string {
 }
 
 func (n *NodeReference) FullName() string {
+	if n.resolvedNode == nil {
+		return n.ID
+	}
 	return n.resolvedNode.FullName()
 }
 
@@ -518,6 +1089,16 @@ func (n *NodeReference) GetID() string {
 	return n.ID
 }
 
+// PersonGroup constructs a PersonGroup node in this Design.
+func (d *Design) PersonGroup(name, description string) *PersonGroup {
+	g := &PersonGroup{
+		Node:   NewNodeWithId("persongroup_"+name, d, name, description, NodeTypePersonGroup),
+		design: d,
+	}
+	d.setNode(g.Node)
+	return g
+}
+
 // Person constructs a Person node in this Design.
 func (d *Design) Person(name, description string) *Person {
 	//  NewNodeWithIdAndParent(name, nil, nil, name, description, nodeType)
@@ -564,10 +1145,94 @@ func (d *Design) SystemWithId(id string, name, description string) *System {
 	return s
 }
 
+// Tag appends a tag to the design root node, e.g. "draft" or "v2", to tell
+// designs apart when several share a database.
+func (d *Design) Tag(tag string) *Design {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if root, ok := d.nodes[d.ID]; ok {
+		root.Tag(tag)
+	}
+	return d
+}
+
+// External marks the design root node as external.
+func (d *Design) External() *Design {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if root, ok := d.nodes[d.ID]; ok {
+		root.External()
+	}
+	return d
+}
+
+// SetLogger sets the logger used to report warnings, e.g. elements an exporter
+// had to skip. By default slog.Default() is used.
+func (d *Design) SetLogger(logger *slog.Logger) *Design {
+	d.log = logger
+	return d
+}
+
+func (d *Design) logger() *slog.Logger {
+	if d.log == nil {
+		return slog.Default()
+	}
+	return d.log
+}
+
+// ApplyTags adds the given tags to every node matching pred and returns how
+// many nodes matched. Tags a node already carries are not added twice, so the
+// same rule can be applied repeatedly.
+//
+//	design.ApplyTags(func(n *neoarch.Node) bool {
+//		return n.NodeType == neoarch.NodeTypeContainer && strings.Contains(n.Name, "DB")
+//	}, "db")
+func (d *Design) ApplyTags(pred func(*Node) bool, tags ...string) int {
+	count := 0
+	for _, node := range d.nodes {
+		if !pred(node) {
+			continue
+		}
+		count++
+		for _, tag := range tags {
+			if !slices.Contains(node.Tags, tag) {
+				node.Tag(tag)
+			}
+		}
+	}
+	return count
+}
+
+// DefaultDescription sets the description used for USES and INTERACTS_WITH
+// relationships added with an empty one (e.g. "Uses"). An empty value, the
+// default, keeps descriptions as given.
+func (d *Design) DefaultDescription(desc string) *Design {
+	d.defaultDescription = desc
+	return d
+}
+
+// DefaultRelationshipDescription sets a function that describes relationships
+// added with an empty description, e.g. returning "Uses " + end.FullName().
+// It is given the stored nodes at both ends and takes precedence over
+// DefaultDescription; when it returns "" the static default applies. BELONGS_TO
+// relationships are left alone. A nil function removes it.
+func (d *Design) DefaultRelationshipDescription(fn func(start, end INode, t RelationshipType) string) *Design {
+	d.describe = fn
+	return d
+}
+
+// RequireRelationshipDescriptions makes every relationship but BELONGS_TO that
+// is still without a description once the defaults are applied an error,
+// recorded when it is added and reported by Validate. It is off by default.
+func (d *Design) RequireRelationshipDescriptions(required bool) *Design {
+	d.requireDescriptions = required
+	return d
+}
+
 // addRelationship is a helper to record relationships in the design.
 // It takes start and end nodes, relationship type, and a description.
 func (d *Design) addRelationship(startNode, endNode INode, relType RelationshipType, desc string) {
-	d.relationships = append(d.relationships, Relationship{
+	d.recordRelationship(Relationship{
 		StartID:     startNode.FullId(),
 		EndID:       endNode.FullId(),
 		Type:        relType,
@@ -575,6 +1240,56 @@ func (d *Design) addRelationship(startNode, endNode INode, relType RelationshipT
 	})
 }
 
+// recordRelationship stores rel, applying the description default and the
+// duplicate policy. It returns the index of the stored relationship, which is
+// an existing one when rel was merged into it, or -1 when rel was rejected.
+func (d *Design) recordRelationship(rel Relationship) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if rel.callSite == "" {
+		rel.callSite = callerLocation()
+	}
+	if rel.Description == "" && rel.Type != RelBelongsTo {
+		rel.Description = d.describeRelationship(rel)
+		if rel.Description == "" && d.requireDescriptions {
+			d.recordErrorLocked(fmt.Errorf("%s relationship %s -> %s has no description", rel.Type, rel.StartID, rel.EndID))
+		}
+	}
+	if d.duplicatePolicy == DuplicateKeep {
+		if i, ok := d.relIndex().byKey[rel.Key()]; ok {
+			d.mergeRelationshipAttributes(i, rel)
+			return i
+		}
+	} else if i := d.findRelationship(rel.StartID, rel.EndID, rel.Type); i >= 0 {
+		d.applyDuplicatePolicy(i, rel)
+		if d.duplicatePolicy == DuplicateError {
+			return -1
+		}
+		return i
+	}
+	d.relationships = append(d.relationships, rel)
+	d.relIndex().add(rel, len(d.relationships)-1)
+	d.hierarchy = nil
+	return len(d.relationships) - 1
+}
+
+// describeRelationship returns the default description of rel, from the
+// DefaultRelationshipDescription function when its ends are known, or else
+// from DefaultDescription.
+func (d *Design) describeRelationship(rel Relationship) string {
+	if d.describe != nil {
+		byFullId := d.nodesByFullId()
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if okStart && okEnd {
+			if desc := d.describe(start, end, rel.Type); desc != "" {
+				return desc
+			}
+		}
+	}
+	return d.defaultDescription
+}
+
 // DeleteFromNeo4j removes the design and all its related nodes and relationships from the Neo4j database.
 func DeleteFromNeo4j(ctx context.Context, designId string, driver neo4j.DriverWithContext) error {
 	session := driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
@@ -597,87 +1312,115 @@ func (d *Design) DeleteFromNeo4j(ctx context.Context, driver neo4j.DriverWithCon
 	return DeleteFromNeo4j(ctx, d.ID, driver)
 }
 
-// SaveToNeo4j pushes the entire model to the Neo4j database
-func (d *Design) SaveToNeo4j(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig) error {
+// SaveOptions controls how SaveToNeo4j writes a design.
+type SaveOptions struct {
+	// Verify re-reads the design after saving it (see VerifyInNeo4j) and fails
+	// the save with a *VerificationError when the database doesn't match.
+	Verify bool
+
+	// IncludeImplied also saves the relationships of ImpliedRelationships as
+	// IMPLIED_USE edges, e.g. person -> system for a person using a component,
+	// so context-level queries see them.
+	IncludeImplied bool
+
+	// UseCreate creates nodes and relationships with CREATE instead of MERGE,
+	// which is faster for bulk loads into an empty database, e.g. right after
+	// ClearNeo4j_UNSAFE. Nothing is matched: saving over existing data creates
+	// duplicates, or fails when the database has a uniqueness constraint on id,
+	// so only use it for targets known to be empty. Unknown placeholders (see
+	// NodeReference) and scenarios are still merged.
+	UseCreate bool
+}
+
+// SaveOption configures SaveOptions.
+type SaveOption func(*SaveOptions)
+
+// WithVerify runs VerifyInNeo4j after saving.
+func WithVerify() SaveOption {
+	return func(o *SaveOptions) {
+		o.Verify = true
+	}
+}
+
+// WithImpliedRelationships also saves the implied relationships. See SaveOptions.IncludeImplied.
+func WithImpliedRelationships() SaveOption {
+	return func(o *SaveOptions) {
+		o.IncludeImplied = true
+	}
+}
+
+// WithCreate creates nodes and relationships instead of merging them. See SaveOptions.UseCreate.
+func WithCreate() SaveOption {
+	return func(o *SaveOptions) {
+		o.UseCreate = true
+	}
+}
+
+func newSaveOptions(opts []SaveOption) SaveOptions {
+	o := SaveOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SaveToNeo4j pushes the entire model to the Neo4j database.
+// It refuses to save a design with errors recorded while building it
+// (e.g. duplicates under the DuplicateError policy).
+func (d *Design) SaveToNeo4j(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, opts ...SaveOption) error {
+	if err := errors.Join(d.errs...); err != nil {
+		return err
+	}
+	o := newSaveOptions(opts)
+	if err := d.saveToNeo4j(ctx, driver, sessConfig, o); err != nil {
+		return err
+	}
+	if !o.Verify {
+		return nil
+	}
+	report, err := d.VerifyInNeo4j(ctx, driver, sessConfig)
+	if err != nil {
+		return err
+	}
+	if !report.Clean() {
+		return &VerificationError{Report: report}
+	}
+	return nil
+}
+
+func (d *Design) saveToNeo4j(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, o SaveOptions) error {
+
 	session := driver.NewSession(ctx, sessConfig)
 	defer session.Close(ctx)
 
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		// MERGE all nodes
-		for _, node := range d.nodes {
-			setStr := "n.name=$name, n.description=$desc, n.nodeType=$nodeType, n.tags=$tags"
-			params := map[string]any{
-				"id":       node.FullId(),
-				"name":     node.Name,
-				"desc":     node.Description,
-				"nodeType": string(node.NodeType),
-				"tags":     node.Tags,
-			}
-			for _, tag := range node.Tags {
-				tag = strings.ReplaceAll(tag, `-`, `_`)
-				tag = strings.ReplaceAll(tag, `:`, `_`)
-				tag = strings.ReplaceAll(tag, ` `, `_`)
-				tag = strings.ReplaceAll(tag, `"`, `_`)
-				tag = strings.ReplaceAll(tag, `'`, `_`)
-				setStr += ", n.tag_" + tag + "=$tag_" + tag
-				params["tag_"+tag] = tag
-			}
-			if node.IsExternal {
-				setStr += ", n.external=$ext"
-				params["ext"] = node.IsExternal
-			}
-
-			query := strings.Builder{}
+	if unresolved := d.unresolvedReferences(); len(unresolved) > 0 {
+		return &UnresolvedReferencesError{References: unresolved}
+	}
+	if missing := d.missingEndpoints(); len(missing) > 0 {
+		return fmt.Errorf("%w: relationships reference ids no node has: %s", ErrUnknownNode, strings.Join(missing, ", "))
+	}
 
-			if len(node.Labels) > 0 {
-				query.WriteString(`MERGE (n:` + string(node.NodeType))
-				for _, label := range node.Labels {
-					query.WriteString(`:` + label)
-				}
-				query.WriteString(` { id: $id })`)
-			} else {
-				query.WriteString(`MERGE (n:` + string(node.NodeType) + ` { id: $id })`)
-			}
-			query.WriteString(`
-ON CREATE SET ` + setStr + `
-ON MATCH SET  ` + setStr + `
-`)
+	nodeStatements := buildNodeStatements(d, o.UseCreate)
+	relStatements := append(buildRelationshipStatements(d, o.UseCreate), BuildScenarioStatements(d)...)
+	if o.IncludeImplied {
+		relStatements = append(relStatements, BuildImpliedRelationshipStatements(d)...)
+	}
 
-			if _, e := tx.Run(ctx, query.String(), params); e != nil {
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// MERGE all nodes
+		for _, stmt := range nodeStatements {
+			if _, e := tx.Run(ctx, stmt.Query, stmt.Params); e != nil {
 				return nil, e
 			}
 		}
 
 		// MERGE all relationships
-		for _, rel := range d.relationships {
-			startNodeLabel := "Unknown"
-			endNodeLabel := "Unknown"
-			for _, node := range d.nodes {
-				if node.FullId() == rel.StartID {
-					startNodeLabel = string(node.NodeType)
-				}
-				if node.FullId() == rel.EndID {
-					endNodeLabel = string(node.NodeType)
-				}
-			}
-			query := fmt.Sprintf(`
-MERGE (start:%s { id: $startID })
-MERGE (end:%s { id: $endID })
-MERGE (start)-[r:%s { description: $desc }]->(end)
-`, startNodeLabel, endNodeLabel, rel.Type)
-
-			params := map[string]any{
-				"startID": rel.StartID,
-				"endID":   rel.EndID,
-				"desc":    rel.Description,
-			}
-			if tmp, e := tx.Run(ctx, query, params); e != nil {
+		for _, stmt := range relStatements {
+			if tmp, e := tx.Run(ctx, stmt.Query, stmt.Params); e != nil {
 				return nil, e
 			} else {
 				if _, e := tmp.Consume(ctx); e != nil {
 					return nil, e
-				} else {
-					// fmt.Println("Relationship created:", res)
 				}
 			}
 		}
@@ -686,6 +1429,26 @@ MERGE (start)-[r:%s { description: $desc }]->(end)
 	return err
 }
 
+// missingEndpoints returns the sorted, distinct endpoint ids of relationships
+// that match no node of the design, e.g. the old FullId of a renamed node.
+func (d *Design) missingEndpoints() []string {
+	byFullId := d.nodesByFullId()
+	missing := map[string]struct{}{}
+	for _, rel := range d.relationships {
+		for _, id := range []string{rel.StartID, rel.EndID} {
+			if _, ok := byFullId[id]; !ok {
+				missing[id] = struct{}{}
+			}
+		}
+	}
+	ids := make([]string, 0, len(missing))
+	for id := range missing {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
 // ClearNeo4j_UNSAFE deletes all nodes and relationships in the Neo4j database.
 func ClearNeo4j_UNSAFE(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig) error {
 	session := driver.NewSession(ctx, sessConfig)
@@ -702,6 +1465,35 @@ DETACH DELETE n
 	return err
 }
 
+// ClearC4Nodes deletes the nodes created by neoarch and their relationships,
+// leaving any other data in the database untouched. These are the nodes of the
+// designs saved in the database, recognized by the designId property every
+// saved node carries: C4 nodes (Design, Person, PersonGroup, System,
+// Container, Component, Code), Unknown placeholders and custom-label nodes,
+// whose label is their nodeType. A Person or System node of another
+// application is kept, as it belongs to no saved design. It is a middle ground
+// between the per-design DeleteFromNeo4j and ClearNeo4j_UNSAFE.
+func ClearC4Nodes(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig) error {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+MATCH (design:Design)
+WHERE design.designId = design.id
+WITH collect(DISTINCT design.id) AS designIds
+MATCH (n)
+WHERE n.designId IN designIds
+  AND (n:Design OR n:Person OR n:PersonGroup OR n:System OR n:Container OR n:Component OR n:Code OR n:Unknown
+       OR n.nodeType IN labels(n))
+DETACH DELETE n
+`
+		_, e := tx.Run(ctx, query, nil)
+		return nil, e
+	})
+	return err
+}
+
 // MD5 returns the MD5 hash of a string.
 func MD5(s string) string {
 	h := md5.New()
@@ -0,0 +1,340 @@
+package neoarch
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// newShopDesign returns the fixture most tests build on: a customer using a
+// web shop made of a frontend, an API with two components and a database,
+// calling an external payment provider.
+func newShopDesign() *Design {
+	d := NewDesign("Shop", "Online shop")
+	customer := d.Person("Customer", "Buys things")
+	shop := d.System("Shop", "Sells things")
+	payments := d.System("Payments", "Takes payments").External()
+
+	web := shop.Container("Web", "Storefront").WithTechnology("React")
+	api := shop.Container("API", "Backend").WithTechnology("Go")
+	db := shop.Container("DB", "Orders").WithTechnology("Postgres")
+	orders := api.Component("Orders", "Handles orders")
+	billing := api.Component("Billing", "Bills orders")
+
+	customer.Uses(web, "Browses")
+	web.Uses(api, "Calls")
+	orders.Uses(db, "Reads and writes")
+	orders.Uses(billing, "Bills")
+	billing.Uses(payments, "Charges")
+	return d
+}
+
+func TestClearC4NodesIsScopedToSavedDesigns(t *testing.T) {
+	driver := &recordingDriver{}
+	if err := ClearC4Nodes(context.Background(), driver, neo4j.SessionConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.statements) != 1 {
+		t.Fatalf("ran %d statements, want 1", len(driver.statements))
+	}
+	query := driver.statements[0].Query
+	for _, want := range []string{"WHERE n.designId IN designIds", "n:Code", "n.nodeType IN labels(n)"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query does not contain %q:\n%s", want, query)
+		}
+	}
+	if strings.Contains(query, "n.nodeType IS NOT NULL") {
+		t.Errorf("query deletes any node with a nodeType property:\n%s", query)
+	}
+}
+
+func TestDesignTagAndExternalLock(t *testing.T) {
+	d := NewDesign("Tagged", "Tagged design")
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.Tag("draft")
+			d.External()
+		}()
+		go func() {
+			defer wg.Done()
+			d.System(fmt.Sprintf("System %d", i), "Built concurrently")
+		}()
+	}
+	wg.Wait()
+
+	root := d.nodes[d.ID]
+	if got := len(slices.DeleteFunc(slices.Clone(root.Tags), func(tag string) bool { return tag != "draft" })); got != 10 {
+		t.Errorf("root has %d draft tags, want 10: %v", got, root.Tags)
+	}
+	if !root.IsExternal {
+		t.Error("root is not external")
+	}
+	for _, stmt := range BuildNodeStatements(d) {
+		if stmt.Params["id"] == d.ID && !slices.Contains(stmt.Params["tags"].([]string), "draft") {
+			t.Errorf("the design tags are not saved: %v", stmt.Params)
+		}
+	}
+}
+
+// recordingDriver is a neo4j.DriverWithContext that runs nothing: it records
+// the statements run through its sessions, whose results are empty. Methods
+// other than the ones below panic.
+type recordingDriver struct {
+	neo4j.DriverWithContext
+	statements []Statement
+}
+
+func (d *recordingDriver) NewSession(context.Context, neo4j.SessionConfig) neo4j.SessionWithContext {
+	return &recordingSession{driver: d}
+}
+
+type recordingSession struct {
+	neo4j.SessionWithContext
+	driver *recordingDriver
+}
+
+func (s *recordingSession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork, _ ...func(*neo4j.TransactionConfig)) (any, error) {
+	return work(&recordingTransaction{driver: s.driver})
+}
+
+func (s *recordingSession) ExecuteRead(ctx context.Context, work neo4j.ManagedTransactionWork, _ ...func(*neo4j.TransactionConfig)) (any, error) {
+	return work(&recordingTransaction{driver: s.driver})
+}
+
+func (s *recordingSession) Close(context.Context) error {
+	return nil
+}
+
+type recordingTransaction struct {
+	neo4j.ManagedTransaction
+	driver *recordingDriver
+}
+
+func (tx *recordingTransaction) Run(_ context.Context, cypher string, params map[string]any) (neo4j.ResultWithContext, error) {
+	tx.driver.statements = append(tx.driver.statements, Statement{Query: cypher, Params: params})
+	return emptyResult{}, nil
+}
+
+type emptyResult struct {
+	neo4j.ResultWithContext
+}
+
+func (emptyResult) Next(context.Context) bool { return false }
+
+func (emptyResult) Err() error { return nil }
+
+func (emptyResult) Consume(context.Context) (neo4j.ResultSummary, error) { return nil, nil }
+
+func (emptyResult) Collect(context.Context) ([]*neo4j.Record, error) { return nil, nil }
+
+// stubDriver is a neo4j.DriverWithContext whose read transactions return the
+// records given for each query. Methods other than the ones below panic.
+type stubDriver struct {
+	neo4j.DriverWithContext
+	records func(cypher string) []*neo4j.Record
+}
+
+func (d *stubDriver) NewSession(context.Context, neo4j.SessionConfig) neo4j.SessionWithContext {
+	return &stubSession{driver: d}
+}
+
+type stubSession struct {
+	neo4j.SessionWithContext
+	driver *stubDriver
+}
+
+func (s *stubSession) ExecuteRead(ctx context.Context, work neo4j.ManagedTransactionWork, _ ...func(*neo4j.TransactionConfig)) (any, error) {
+	return work(&stubTransaction{driver: s.driver})
+}
+
+func (s *stubSession) Close(context.Context) error {
+	return nil
+}
+
+type stubTransaction struct {
+	neo4j.ManagedTransaction
+	driver *stubDriver
+}
+
+func (tx *stubTransaction) Run(_ context.Context, cypher string, _ map[string]any) (neo4j.ResultWithContext, error) {
+	return &recordsResult{records: tx.driver.records(cypher)}, nil
+}
+
+// recordsResult returns the records it holds, one at a time.
+type recordsResult struct {
+	neo4j.ResultWithContext
+	records []*neo4j.Record
+	current *neo4j.Record
+}
+
+func (r *recordsResult) Next(context.Context) bool {
+	if len(r.records) == 0 {
+		return false
+	}
+	r.current, r.records = r.records[0], r.records[1:]
+	return true
+}
+
+func (r *recordsResult) Record() *neo4j.Record { return r.current }
+
+func (r *recordsResult) Err() error { return nil }
+
+// TestUsedByImpliedMatrix documents the implied relationships of a single
+// relationship for every pair of element types, declared both with Uses on the
+// source and with UsedBy on the target. The source is in system A (Web, then
+// its Ui component), the target in system B (DB, then its Repo component), and
+// Alice and Bob are top-level persons.
+func TestUsedByImpliedMatrix(t *testing.T) {
+	build := func() (*Design, map[string]INode) {
+		d := NewDesign("Matrix", "Implied relationships")
+		a := d.System("A", "Source system")
+		web := a.Container("Web", "Source container")
+		b := d.System("B", "Target system")
+		db := b.Container("DB", "Target container")
+		return d, map[string]INode{
+			"Alice": d.Person("Alice", "Source person"),
+			"A":     a,
+			"Web":   web,
+			"Ui":    web.Component("Ui", "Source component"),
+			"Bob":   d.Person("Bob", "Target person"),
+			"B":     b,
+			"DB":    db,
+			"Repo":  db.Component("Repo", "Target component"),
+		}
+	}
+	uses := func(from, to INode) {
+		switch n := from.(type) {
+		case *Person:
+			n.Uses(to, "Uses")
+		case *System:
+			n.Uses(to, "Uses")
+		case *Container:
+			n.Uses(to, "Uses")
+		case *Component:
+			n.Uses(to, "Uses")
+		}
+	}
+	usedBy := func(from, to INode) {
+		switch n := to.(type) {
+		case *Person:
+			n.UsedBy(from, "Uses")
+		case *System:
+			n.UsedBy(from, "Uses")
+		case *Container:
+			n.UsedBy(from, "Uses")
+		case *Component:
+			n.UsedBy(from, "Uses")
+		}
+	}
+
+	tests := []struct {
+		from, to string
+		implied  []string
+	}{
+		{"Alice", "Bob", nil},
+		{"Alice", "B", nil},
+		{"Alice", "DB", []string{"Alice->B"}},
+		{"Alice", "Repo", []string{"Alice->B", "Alice->DB"}},
+		{"A", "Bob", nil},
+		{"A", "B", nil},
+		{"A", "DB", []string{"A->B"}},
+		{"A", "Repo", []string{"A->B", "A->DB"}},
+		{"Web", "Bob", []string{"A->Bob"}},
+		{"Web", "B", []string{"A->B"}},
+		{"Web", "DB", []string{"A->B", "A->DB", "Web->B"}},
+		{"Web", "Repo", []string{"A->B", "A->DB", "A->Repo", "Web->B", "Web->DB"}},
+		{"Ui", "Bob", []string{"A->Bob", "Web->Bob"}},
+		{"Ui", "B", []string{"A->B", "Web->B"}},
+		{"Ui", "DB", []string{"A->B", "A->DB", "Ui->B", "Web->B", "Web->DB"}},
+		{"Ui", "Repo", []string{"A->B", "A->DB", "A->Repo", "Ui->B", "Ui->DB", "Web->B", "Web->DB", "Web->Repo"}},
+	}
+	for _, tt := range tests {
+		for _, direction := range []struct {
+			name    string
+			declare func(from, to INode)
+		}{{"Uses", uses}, {"UsedBy", usedBy}} {
+			t.Run(tt.from+"->"+tt.to+"/"+direction.name, func(t *testing.T) {
+				d, elements := build()
+				direction.declare(elements[tt.from], elements[tt.to])
+
+				byFullId := d.nodesByFullId()
+				var implied []string
+				for _, rel := range d.ImpliedRelationships() {
+					implied = append(implied, byFullId[rel.StartID].Name+"->"+byFullId[rel.EndID].Name)
+				}
+				slices.Sort(implied)
+				if !slices.Equal(implied, tt.implied) {
+					t.Errorf("implied %v, want %v", implied, tt.implied)
+				}
+			})
+		}
+	}
+}
+
+func TestBlankNamesAreReported(t *testing.T) {
+	d := NewDesign("Blank", "Blank names")
+	shop := d.System("Shop", "Sells things")
+	shop.Container("  ", "Whitespace name")
+	api := shop.Container("API", "Backend")
+	api.ComponentWithId("\t", "Orders", "Whitespace id")
+	d.Person("", "Empty name")
+
+	var build []string
+	for _, issue := range d.Validate() {
+		if issue.Code == "build" {
+			build = append(build, issue.Message)
+		}
+	}
+	want := []string{
+		"Container in Shop has an empty name",
+		`Component "Orders" in Shop.Shop.API has an empty id`,
+		"Person has an empty name",
+	}
+	if !slices.Equal(build, want) {
+		t.Errorf("got build issues %q, want %q", build, want)
+	}
+}
+
+func TestUsedByVia(t *testing.T) {
+	d := NewDesign("Via", "Entry points")
+	customer := d.Person("Customer", "Buys things")
+	shop := d.System("Shop", "Sells things")
+	web := shop.Container("Web", "Storefront")
+	shop.UsedByVia(customer, web, "Browses")
+
+	if got := usesDescriptions(d); len(got) != 1 {
+		t.Fatalf("got USES relationships %v, want only the one to the container", got)
+	}
+	if rel := d.relationships[len(d.relationships)-1]; rel.StartID != customer.FullId() || rel.EndID != web.FullId() {
+		t.Errorf("the USES relationship goes from %s to %s, want %s to %s", rel.StartID, rel.EndID, customer.FullId(), web.FullId())
+	}
+	var implied []Relationship
+	for _, rel := range d.ImpliedRelationships() {
+		if rel.StartID == customer.FullId() && rel.EndID == shop.FullId() {
+			implied = append(implied, rel)
+		}
+	}
+	if len(implied) != 1 || implied[0].DerivedFrom == nil || implied[0].DerivedFrom.EndID != web.FullId() {
+		t.Errorf("got implied relationships %+v, want one from Customer to Shop derived from the one to Web", implied)
+	}
+	if top := d.TopDependedOn(1); len(top) != 1 || top[0].Incoming != 1 {
+		t.Errorf("TopDependedOn = %+v, want Shop used once", top)
+	}
+
+	other := d.System("Other", "Another system").Container("API", "Backend")
+	shop.UsedByVia(customer, other, "Calls")
+	if got := usesDescriptions(d); len(got) != 1 {
+		t.Errorf("a container of another system was connected: %v", got)
+	}
+	if issues := d.Validate(); len(issues) != 1 || !strings.Contains(issues[0].Message, "does not belong to system Shop") {
+		t.Errorf("got issues %v, want the foreign container reported", issues)
+	}
+}
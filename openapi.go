@@ -0,0 +1,111 @@
+package neoarch
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// -----------------------------------------------------------------------------
+// OpenAPI importer
+// -----------------------------------------------------------------------------
+
+// DefaultOpenAPIGroup is the component ImportOpenAPI adds operations without
+// tags to.
+const DefaultOpenAPIGroup = "default"
+
+// openAPIDocument is the part of an OpenAPI 3 document ImportOpenAPI uses.
+type openAPIDocument struct {
+	OpenAPI string                     `yaml:"openapi"`
+	Paths   map[string]openAPIPathItem `yaml:"paths"`
+}
+
+type openAPIPathItem struct {
+	Get     *openAPIOperation `yaml:"get"`
+	Put     *openAPIOperation `yaml:"put"`
+	Post    *openAPIOperation `yaml:"post"`
+	Delete  *openAPIOperation `yaml:"delete"`
+	Options *openAPIOperation `yaml:"options"`
+	Head    *openAPIOperation `yaml:"head"`
+	Patch   *openAPIOperation `yaml:"patch"`
+	Trace   *openAPIOperation `yaml:"trace"`
+}
+
+// methodOperation is an operation of a path item with its HTTP method.
+type methodOperation struct {
+	method    string
+	operation *openAPIOperation
+}
+
+// operations returns the operations of the path item, in the order the
+// specification lists the methods.
+func (p openAPIPathItem) operations() []methodOperation {
+	var ops []methodOperation
+	for _, op := range []methodOperation{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"OPTIONS", p.Options}, {"HEAD", p.Head}, {"PATCH", p.Patch}, {"TRACE", p.Trace},
+	} {
+		if op.operation != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+type openAPIOperation struct {
+	Summary     string   `yaml:"summary"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+}
+
+// ImportOpenAPI seeds container with the operations of an OpenAPI 3 document,
+// in YAML or JSON: one Component per tag and, inside it, one Endpoint per
+// operation named after its method and path (e.g. "GET /users/{id}") and
+// described by its summary. Operations are grouped under their first tag, or
+// under the DefaultOpenAPIGroup component when they have none. Everything is
+// tagged "http".
+//
+// Ids are derived from the tag and the method and path, so importing an
+// updated document again updates the descriptions of the existing elements
+// instead of adding new ones.
+func ImportOpenAPI(container *Container, spec io.Reader) error {
+	doc := openAPIDocument{}
+	if err := yaml.NewDecoder(spec).Decode(&doc); err != nil {
+		return fmt.Errorf("reading OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return fmt.Errorf("unsupported OpenAPI version %q, expected 3.x", doc.OpenAPI)
+	}
+
+	d := container.design
+	groups := map[string]*Component{}
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		for _, op := range doc.Paths[path].operations() {
+			method, operation := op.method, op.operation
+			group := DefaultOpenAPIGroup
+			if len(operation.Tags) > 0 {
+				group = operation.Tags[0]
+			}
+			component, ok := groups[group]
+			if !ok {
+				component = container.ComponentWithId(sanitizeID(group), group, "")
+				// Re-declared elements keep the stored node, so tag that one
+				tagOnce(d.nodes[component.ID], "http")
+				groups[group] = component
+			}
+
+			name := method + " " + path
+			description := operation.Summary
+			if description == "" {
+				description = operation.Description
+			}
+			endpoint := component.CustomWithId(sanitizeID(name), LabelEndpoint, name, description)
+			tagOnce(d.nodes[endpoint.ID], "http")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package neoarch
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// -----------------------------------------------------------------------------
+// Protobuf descriptor importer
+// -----------------------------------------------------------------------------
+
+// LabelEndpoint is the label of the endpoints added by ImportProtoDescriptor.
+const LabelEndpoint = "Endpoint"
+
+// ImportProtoDescriptor seeds container with the gRPC services of a compiled
+// FileDescriptorSet (as written by `protoc --descriptor_set_out` or
+// `buf build -o`): one Component per service and, inside it, one Endpoint per
+// RPC method described by its request and response types. Everything is
+// tagged "grpc". A service whose methods take or return messages declared in
+// the file of another service of the set uses that service.
+//
+// Service ids are derived from the fully qualified service name and method ids
+// from the method name, so importing the same descriptor set again updates the
+// existing elements instead of adding new ones.
+func ImportProtoDescriptor(container *Container, descriptorSetPath string) error {
+	data, err := os.ReadFile(descriptorSetPath)
+	if err != nil {
+		return err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return fmt.Errorf("reading descriptor set %s: %w", descriptorSetPath, err)
+	}
+
+	d := container.design
+	components := map[*descriptorpb.FileDescriptorProto][]*Component{} // services declared by each file
+	for _, file := range set.GetFile() {
+		for _, service := range file.GetService() {
+			fullName := protoFullName(file.GetPackage(), service.GetName())
+			component := container.ComponentWithId(protoID(fullName), service.GetName(), "gRPC service "+fullName)
+			// Re-declared elements keep the stored node, so tag that one
+			tagOnce(d.nodes[component.ID], "grpc")
+			d.nodes[component.ID].Technology = "gRPC"
+			components[file] = append(components[file], component)
+
+			for _, method := range service.GetMethod() {
+				endpoint := component.Custom(LabelEndpoint, method.GetName(), protoMethodDescription(method))
+				tagOnce(d.nodes[endpoint.ID], "grpc")
+			}
+		}
+	}
+
+	declaredIn := map[string]*descriptorpb.FileDescriptorProto{} // message full name -> file
+	for _, file := range set.GetFile() {
+		var declare func(prefix string, messages []*descriptorpb.DescriptorProto)
+		declare = func(prefix string, messages []*descriptorpb.DescriptorProto) {
+			for _, message := range messages {
+				fullName := protoFullName(prefix, message.GetName())
+				declaredIn[fullName] = file
+				declare(fullName, message.GetNestedType())
+			}
+		}
+		declare(file.GetPackage(), file.GetMessageType())
+	}
+	for _, file := range set.GetFile() {
+		for i, service := range file.GetService() {
+			for _, method := range service.GetMethod() {
+				for _, typeName := range []string{method.GetInputType(), method.GetOutputType()} {
+					other := declaredIn[strings.TrimPrefix(typeName, ".")]
+					if other == nil || other == file {
+						continue
+					}
+					for _, used := range components[other] {
+						// Exact copies are stored once, so repeated types and imports add no edges
+						components[file][i].Uses(used, "Uses the messages of "+used.Name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// protoFullName returns the fully qualified name of a protobuf element declared
+// in the given package or message.
+func protoFullName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// protoMethodDescription describes method by its signature,
+// e.g. "(GetUserRequest) returns (stream User)".
+func protoMethodDescription(method *descriptorpb.MethodDescriptorProto) string {
+	typeName := func(name string, streaming bool) string {
+		name = strings.TrimPrefix(name, ".")
+		if streaming {
+			return "stream " + name
+		}
+		return name
+	}
+	return fmt.Sprintf("(%s) returns (%s)",
+		typeName(method.GetInputType(), method.GetClientStreaming()),
+		typeName(method.GetOutputType(), method.GetServerStreaming()))
+}
+
+// protoID turns a fully qualified protobuf name into an element id, as "."
+// separates the levels of an id.
+func protoID(fullName string) string {
+	return strings.ReplaceAll(fullName, ".", "_")
+}
+
+// tagOnce adds tag to n unless it already carries it.
+func tagOnce(n *Node, tag string) {
+	if !slices.Contains(n.Tags, tag) {
+		n.Tag(tag)
+	}
+}
@@ -0,0 +1,103 @@
+package neoarch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// writeDescriptorSet writes a descriptor set of two files: the users package
+// with a UserService, and the orders package whose OrderService returns the
+// users.User message.
+func writeDescriptorSet(t *testing.T) string {
+	t.Helper()
+	method := func(name, input, output string) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{Name: proto.String(name), InputType: proto.String(input), OutputType: proto.String(output)}
+	}
+	message := func(name string, nested ...*descriptorpb.DescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{Name: proto.String(name), NestedType: nested}
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		{
+			Name:        proto.String("users.proto"),
+			Package:     proto.String("users"),
+			MessageType: []*descriptorpb.DescriptorProto{message("GetUserRequest"), message("User", message("Address"))},
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name:   proto.String("UserService"),
+				Method: []*descriptorpb.MethodDescriptorProto{method("GetUser", ".users.GetUserRequest", ".users.User")},
+			}},
+		},
+		{
+			Name:        proto.String("orders.proto"),
+			Package:     proto.String("orders"),
+			Dependency:  []string{"users.proto"},
+			MessageType: []*descriptorpb.DescriptorProto{message("Order")},
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("OrderService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					method("GetOrder", ".orders.Order", ".orders.Order"),
+					method("GetOwner", ".orders.Order", ".users.User"),
+					method("GetAddress", ".orders.Order", ".users.User.Address"),
+				},
+			}},
+		},
+	}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "services.binpb")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportProtoDescriptor(t *testing.T) {
+	path := writeDescriptorSet(t)
+	d := NewDesign("Grpc", "gRPC services")
+	api := d.System("Shop", "Online shop").Container("API", "Backend")
+	if err := ImportProtoDescriptor(api, path); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := d.lookupNode("Shop.API.orders_OrderService")
+	users := d.lookupNode("Shop.API.users_UserService")
+	if orders == nil || users == nil {
+		t.Fatalf("services were not imported: %v", d.nodesByFullId())
+	}
+	if orders.Technology != "gRPC" || !slices.Contains(orders.Tags, "grpc") {
+		t.Errorf("OrderService = %+v, want gRPC tagged grpc", orders)
+	}
+	getOwner := d.lookupNode(orders.ID + ".GetOwner")
+	if getOwner == nil || getOwner.NodeType != LabelEndpoint || getOwner.Description != "(orders.Order) returns (users.User)" {
+		t.Errorf("GetOwner endpoint = %+v", getOwner)
+	}
+
+	var uses []Relationship
+	for _, rel := range d.relationships {
+		if rel.Type == RelUses {
+			uses = append(uses, rel)
+		}
+	}
+	// GetOwner and GetAddress both use users types, and add a single edge
+	if len(uses) != 1 || uses[0].StartID != orders.FullId() || uses[0].EndID != users.FullId() {
+		t.Errorf("got USES relationships %v, want OrderService -> UserService", uses)
+	}
+
+	nodes, rels := len(d.nodes), d.RelationshipCount()
+	if err := ImportProtoDescriptor(api, path); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.nodes) != nodes || d.RelationshipCount() != rels {
+		t.Errorf("importing again changed the design: %d nodes and %d relationships, want %d and %d",
+			len(d.nodes), d.RelationshipCount(), nodes, rels)
+	}
+	if tags := d.lookupNode(orders.FullId()).Tags; len(tags) != 1 {
+		t.Errorf("importing again tagged OrderService %v", tags)
+	}
+}
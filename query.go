@@ -0,0 +1,128 @@
+package neoarch
+
+import (
+	"context"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// -----------------------------------------------------------------------------
+// Raw graph queries
+// -----------------------------------------------------------------------------
+
+// NodeRecord is a node as stored in Neo4j.
+type NodeRecord struct {
+	ID         string // The neoarch id (FullId) of the node
+	Labels     []string
+	Properties map[string]any
+}
+
+// EdgeRecord is a relationship as stored in Neo4j, between two neoarch ids.
+type EdgeRecord struct {
+	StartID    string
+	EndID      string
+	Type       string
+	Properties map[string]any
+}
+
+// QueryDesignGraph returns the nodes saved for the given design and the
+// relationships between them, as plain records, with a single query. It is
+// meant for rendering (e.g. a web viewer) and does not rebuild a Design.
+// Nodes are scoped by the designId property written by SaveToNeo4j; only those
+// of the unversioned design are returned (see Design.Version).
+func QueryDesignGraph(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, designID string) ([]NodeRecord, []EdgeRecord, error) {
+	return queryDesignGraph(ctx, driver, sessConfig, designID, "")
+}
+
+// queryDesignGraph is QueryDesignGraph for the given version of the design.
+func queryDesignGraph(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, designID, version string) ([]NodeRecord, []EdgeRecord, error) {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	type graph struct {
+		nodes []NodeRecord
+		edges []EdgeRecord
+	}
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+MATCH (n { designId: $designID })
+WHERE coalesce(n.version, '') = $version
+OPTIONAL MATCH (n)-[r]->(m { designId: $designID })
+WHERE coalesce(m.version, '') = $version
+RETURN n, r, m.id AS endID
+ORDER BY n.id, endID
+`
+		result, e := tx.Run(ctx, query, map[string]any{"designID": designID, "version": version})
+		if e != nil {
+			return nil, e
+		}
+
+		g := graph{}
+		seen := map[string]struct{}{}
+		for result.Next(ctx) {
+			record := result.Record()
+			node, _, e := neo4j.GetRecordValue[neo4j.Node](record, "n")
+			if e != nil {
+				return nil, e
+			}
+			id, _ := node.Props["id"].(string)
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				g.nodes = append(g.nodes, NodeRecord{ID: id, Labels: node.Labels, Properties: node.Props})
+			}
+
+			rel, isNil, e := neo4j.GetRecordValue[neo4j.Relationship](record, "r")
+			if e != nil {
+				return nil, e
+			}
+			if isNil {
+				continue
+			}
+			endID, _, e := neo4j.GetRecordValue[string](record, "endID")
+			if e != nil {
+				return nil, e
+			}
+			g.edges = append(g.edges, EdgeRecord{StartID: id, EndID: endID, Type: rel.Type, Properties: rel.Props})
+		}
+		return g, result.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	g := res.(graph)
+	return g.nodes, g.edges, nil
+}
+
+// ImpactOf returns the ids of every node in the stored graph that depends on
+// the node with the given id (its FullId), directly or transitively, through
+// USES relationships. The ids are distinct and sorted.
+func ImpactOf(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, nodeID string) ([]string, error) {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+MATCH (n { id: $id })<-[:USES*]-(dependent)
+WHERE dependent <> n
+RETURN DISTINCT dependent.id AS id
+ORDER BY id
+`
+		result, e := tx.Run(ctx, query, map[string]any{"id": nodeID})
+		if e != nil {
+			return nil, e
+		}
+		var ids []string
+		for result.Next(ctx) {
+			id, _, e := neo4j.GetRecordValue[string](result.Record(), "id")
+			if e != nil {
+				return nil, e
+			}
+			ids = append(ids, id)
+		}
+		return ids, result.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]string), nil
+}
@@ -0,0 +1,54 @@
+package neoarch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Unresolved references
+// -----------------------------------------------------------------------------
+
+// TagUnresolved tags the placeholder nodes NodeReference adds for unknown ids.
+const TagUnresolved = "unresolved"
+
+// UnresolvedReference is a NodeReference lookup, made in strict mode, of an id
+// no node has.
+type UnresolvedReference struct {
+	ID       string
+	CallSite string // file:line of the NodeReference call
+}
+
+// UnresolvedReferencesError is returned by SaveToNeo4j when references made in
+// strict mode are still unresolved.
+type UnresolvedReferencesError struct {
+	References []UnresolvedReference
+}
+
+func (e *UnresolvedReferencesError) Error() string {
+	parts := make([]string, 0, len(e.References))
+	for _, ref := range e.References {
+		parts = append(parts, fmt.Sprintf("%q (%s)", ref.ID, ref.CallSite))
+	}
+	return "unresolved node references: " + strings.Join(parts, ", ")
+}
+
+// StrictReferences makes NodeReference fail on unknown ids instead of adding a
+// placeholder Unknown node, which typos otherwise leave in saved designs and
+// exports. See NodeReference.
+func (d *Design) StrictReferences(strict bool) *Design {
+	d.strictReferences = strict
+	return d
+}
+
+// unresolvedReferences returns the references made in strict mode whose id no
+// node has yet, in the order they were made.
+func (d *Design) unresolvedReferences() []UnresolvedReference {
+	var unresolved []UnresolvedReference
+	for _, ref := range d.references {
+		if _, ok := d.nodes[ref.ID]; !ok {
+			unresolved = append(unresolved, ref)
+		}
+	}
+	return unresolved
+}
@@ -0,0 +1,547 @@
+package neoarch
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Duplicate relationships
+// -----------------------------------------------------------------------------
+
+// DuplicateRelationshipPolicy controls what happens when a relationship is added
+// between the same start and end nodes, with the same type, as an existing one.
+//
+// Whatever the policy, a relationship is looked up by (start, end, type) in an
+// index rather than by scanning the design, so building large designs in
+// several passes stays linear.
+type DuplicateRelationshipPolicy int
+
+const (
+	// DuplicateKeep stores every relationship as added, as parallel edges, except
+	// exact copies (same Key) which are stored once, with the technology,
+	// interaction style, optionality and tags of both. Copies setting a
+	// different technology or interaction style record an error reported by
+	// Validate. This is the default.
+	DuplicateKeep DuplicateRelationshipPolicy = iota
+	// DuplicateMergeDescriptions folds the duplicate into the existing relationship,
+	// joining the distinct descriptions with "; " in sorted order.
+	DuplicateMergeDescriptions
+	// DuplicateError drops the duplicate and records an error reported by Validate.
+	DuplicateError
+	// DuplicateKeepFirst drops the duplicate, keeping the existing description.
+	DuplicateKeepFirst
+	// DuplicateOverwrite replaces the description of the existing relationship
+	// with the duplicate's.
+	DuplicateOverwrite
+)
+
+// OnDuplicateRelationship sets the policy applied to relationships added from now on.
+func (d *Design) OnDuplicateRelationship(policy DuplicateRelationshipPolicy) *Design {
+	d.duplicatePolicy = policy
+	return d
+}
+
+// relationshipIndex locates relationships without scanning the design.
+type relationshipIndex struct {
+	byTriple map[string]int // start|end|type -> first relationship
+	byKey    map[string]int // Key -> relationship
+}
+
+func tripleKey(startID, endID string, relType RelationshipType) string {
+	return startID + "|" + endID + "|" + string(relType)
+}
+
+// relIndex returns the relationship index, building it on first use.
+func (d *Design) relIndex() *relationshipIndex {
+	if d.relationshipIndex != nil {
+		return d.relationshipIndex
+	}
+	idx := &relationshipIndex{
+		byTriple: make(map[string]int, len(d.relationships)),
+		byKey:    make(map[string]int, len(d.relationships)),
+	}
+	for i, rel := range d.relationships {
+		idx.add(rel, i)
+	}
+	d.relationshipIndex = idx
+	return idx
+}
+
+func (idx *relationshipIndex) add(rel Relationship, i int) {
+	if _, ok := idx.byTriple[tripleKey(rel.StartID, rel.EndID, rel.Type)]; !ok {
+		idx.byTriple[tripleKey(rel.StartID, rel.EndID, rel.Type)] = i
+	}
+	if _, ok := idx.byKey[rel.Key()]; !ok {
+		idx.byKey[rel.Key()] = i
+	}
+}
+
+// findRelationship returns the index of the first relationship matching the
+// given endpoints and type, or -1.
+func (d *Design) findRelationship(startID, endID string, relType RelationshipType) int {
+	if i, ok := d.relIndex().byTriple[tripleKey(startID, endID, relType)]; ok {
+		return i
+	}
+	return -1
+}
+
+// applyDuplicatePolicy handles rel, a duplicate of d.relationships[i].
+func (d *Design) applyDuplicatePolicy(i int, rel Relationship) {
+	existing := &d.relationships[i]
+	before := existing.Key()
+	switch d.duplicatePolicy {
+	case DuplicateMergeDescriptions:
+		existing.Description = mergeDescriptions(existing.Description, rel.Description)
+	case DuplicateOverwrite:
+		existing.Description = rel.Description
+	case DuplicateError:
+		d.recordErrorLocked(fmt.Errorf("duplicate %s relationship %s -> %s (%q, already added as %q)",
+			rel.Type, rel.StartID, rel.EndID, rel.Description, existing.Description))
+	}
+	if existing.Key() != before {
+		// The description is part of the key
+		idx := d.relIndex()
+		if idx.byKey[before] == i {
+			delete(idx.byKey, before)
+		}
+		idx.add(*existing, i)
+	}
+}
+
+// mergeRelationshipAttributes folds the attributes of rel, an exact copy of
+// d.relationships[i], into it: unset ones are taken from rel, tags are joined,
+// and conflicting ones are kept and recorded as an error.
+func (d *Design) mergeRelationshipAttributes(i int, rel Relationship) {
+	existing := &d.relationships[i]
+	conflict := func(attribute, had, got string) {
+		d.recordErrorLocked(fmt.Errorf("%s relationship %s -> %s (%q) added again with %s %q, already added with %q",
+			rel.Type, rel.StartID, rel.EndID, rel.Description, attribute, got, had))
+	}
+	switch {
+	case existing.Technology == "":
+		existing.Technology = rel.Technology
+	case rel.Technology != "" && rel.Technology != existing.Technology:
+		conflict("technology", existing.Technology, rel.Technology)
+	}
+	switch {
+	case existing.InteractionStyle == "":
+		existing.InteractionStyle = rel.InteractionStyle
+	case rel.InteractionStyle != "" && rel.InteractionStyle != existing.InteractionStyle:
+		conflict("interaction style", string(existing.InteractionStyle), string(rel.InteractionStyle))
+	}
+	existing.Optional = existing.Optional || rel.Optional
+	for _, tag := range rel.Tags {
+		if !slices.Contains(existing.Tags, tag) {
+			existing.Tags = append(slices.Clip(existing.Tags), tag)
+		}
+	}
+}
+
+// sortedRelationships returns a copy of rels ordered by start, end, type and
+// description. Relationships equal on all four keep their relative order.
+// Exporters emit relationships in this order so their output does not depend
+// on the order the model was built in.
+func sortedRelationships(rels []Relationship) []Relationship {
+	sorted := slices.Clone(rels)
+	slices.SortStableFunc(sorted, func(a, b Relationship) int {
+		return cmp.Or(
+			cmp.Compare(a.StartID, b.StartID),
+			cmp.Compare(a.EndID, b.EndID),
+			cmp.Compare(a.Type, b.Type),
+			cmp.Compare(a.Description, b.Description),
+		)
+	})
+	return sorted
+}
+
+// RelationshipCount returns the number of relationships stored in the design,
+// BELONGS_TO and MEMBER_OF edges included, after duplicates were handled.
+func (d *Design) RelationshipCount() int {
+	return len(d.relationships)
+}
+
+// mergeDescriptions joins the distinct, non-empty parts of both descriptions with "; ".
+func mergeDescriptions(existing, added string) string {
+	seen := map[string]struct{}{}
+	var parts []string
+	for _, part := range append(strings.Split(existing, "; "), added) {
+		if part == "" {
+			continue
+		}
+		if _, ok := seen[part]; ok {
+			continue
+		}
+		seen[part] = struct{}{}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+// RelationshipDirection tells whether a relationship leaves or reaches a node.
+type RelationshipDirection string
+
+const (
+	DirectionOutgoing RelationshipDirection = "outgoing" // The node is the start
+	DirectionIncoming RelationshipDirection = "incoming" // The node is the end
+	DirectionSelf     RelationshipDirection = "self"     // The node is both
+	DirectionNone     RelationshipDirection = ""         // The node is neither
+)
+
+// Direction returns the direction of r relative to the node with the given FullId.
+func (r Relationship) Direction(fullId string) RelationshipDirection {
+	switch {
+	case r.StartID == fullId && r.EndID == fullId:
+		return DirectionSelf
+	case r.StartID == fullId:
+		return DirectionOutgoing
+	case r.EndID == fullId:
+		return DirectionIncoming
+	}
+	return DirectionNone
+}
+
+// RelationshipsFor returns every explicit relationship starting or ending at
+// the node with the given ID or FullId, BELONGS_TO and MEMBER_OF included,
+// ordered by start, end, type and description. Use Relationship.Direction with
+// the node's FullId to tell incoming from outgoing ones. It returns nil for
+// unknown nodes.
+func (d *Design) RelationshipsFor(id string) []Relationship {
+	node := d.lookupNode(id)
+	if node == nil {
+		return nil
+	}
+	fullId := node.FullId()
+	var rels []Relationship
+	for _, rel := range sortedRelationships(d.relationships) {
+		if rel.Direction(fullId) != DirectionNone {
+			rels = append(rels, rel)
+		}
+	}
+	return rels
+}
+
+// RelationshipGroup is a set of relationships sharing the same start, end and type.
+type RelationshipGroup struct {
+	StartID       string
+	EndID         string
+	Type          RelationshipType
+	Relationships []Relationship
+}
+
+// Identical reports whether all relationships of the group also share the description.
+func (g RelationshipGroup) Identical() bool {
+	for _, rel := range g.Relationships {
+		if rel.Description != g.Relationships[0].Description {
+			return false
+		}
+	}
+	return true
+}
+
+// DuplicateRelationships reports every group of two or more relationships with
+// the same start, end and type, whether their descriptions match or not.
+// Groups are returned in the order their first relationship was added.
+//
+// What the report finds depends on the policy in force while the design was
+// built. Under the default DuplicateKeep, exact copies are stored once, so the
+// groups only hold relationships differing in description, the parallel edges
+// the policy keeps. The other policies never store a second relationship with
+// the same start, end and type, so the report is empty. Designs read from
+// Neo4j or CSV are not built through a policy and may hold exact copies too.
+func (d *Design) DuplicateRelationships() []RelationshipGroup {
+	type key struct {
+		start, end string
+		relType    RelationshipType
+	}
+	index := map[key]int{}
+	var groups []RelationshipGroup
+	for _, rel := range d.relationships {
+		k := key{rel.StartID, rel.EndID, rel.Type}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, RelationshipGroup{StartID: rel.StartID, EndID: rel.EndID, Type: rel.Type})
+		}
+		groups[i].Relationships = append(groups[i].Relationships, rel)
+	}
+
+	var duplicates []RelationshipGroup
+	for _, group := range groups {
+		if len(group.Relationships) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	return duplicates
+}
+
+// -----------------------------------------------------------------------------
+// Implied relationships
+// -----------------------------------------------------------------------------
+
+// ancestorsOrSelf returns n followed by its ancestors, nearest first, without the design root.
+func (d *Design) ancestorsOrSelf(n *Node) []*Node {
+	var chain []*Node
+	for cur := n; cur != nil && cur.NodeType != NodeTypeDesign; cur = d.parentOf(cur) {
+		chain = append(chain, cur)
+	}
+	return chain
+}
+
+// forEachAncestorPair calls fn for every pair of ancestors-or-self of start and
+// end, nearest first, except (start, end) itself and pairs where one element
+// contains the other. Persons stand for themselves only: a person scoped to a
+// system (see System.Person) doesn't make the system use what it uses.
+func (d *Design) forEachAncestorPair(start, end *Node, fn func(from, to *Node)) {
+	forEachAncestorPair(d.ancestorsOrSelf(start), d.ancestorsOrSelf(end), fn)
+}
+
+// forEachAncestorPair is Design.forEachAncestorPair for the given chains of
+// ancestors-or-self, starting with the endpoints, so exporters can walk the
+// hierarchy of their view.
+func forEachAncestorPair(startChain, endChain []*Node, fn func(from, to *Node)) {
+	start, end := startChain[0], endChain[0]
+	starts, ends := startChain, endChain
+	if start.NodeType == NodeTypePerson {
+		starts = startChain[:1]
+	}
+	if end.NodeType == NodeTypePerson {
+		ends = endChain[:1]
+	}
+	for _, from := range starts {
+		if slices.Contains(endChain, from) {
+			return // from contains end, and so do its ancestors
+		}
+		for _, to := range ends {
+			if slices.Contains(startChain, to) {
+				break // to contains start, and so do its ancestors
+			}
+			if from == start && to == end {
+				continue
+			}
+			fn(from, to)
+		}
+	}
+}
+
+// TagImplied is the tag carried by the relationships of ImpliedRelationships.
+const TagImplied = "implied"
+
+// EnableImpliedUse turns the derivation of implied relationships on or off for
+// the whole design. It is on by default. When off, ImpliedRelationships returns
+// nothing and exporters render only the explicit relationships. Relationships
+// added with UsesNoImplied or RelNoImplied never imply anything, whatever this
+// setting.
+func (d *Design) EnableImpliedUse(enabled bool) *Design {
+	d.impliedUseDisabled = !enabled
+	return d
+}
+
+// ImpliedRelationships derives the IMPLIED_USE relationships of the design.
+// An explicit USES relationship, or one of a custom type, between two elements
+// implies one between every pair of their ancestors (or themselves), e.g. a
+// component using a container of another system implies that its container and
+// its system use that container and that system. Relationships flagged
+// NoImplied imply nothing. Pairs where one element contains the other, and
+// pairs that already have such an explicit relationship, are left out.
+//
+// Each implied relationship carries the description of the first explicit
+// relationship it was derived from, and the TagImplied tag, which is saved
+// with it so consumers can tell it apart without deriving it again. They are
+// returned in the order the explicit relationships were added, nearest
+// ancestors first. The design is not modified.
+//
+// Only the endpoints of a relationship matter, not the element it was declared
+// on: a.Uses(b, ...) and b.UsedBy(a, ...) record the same relationship and imply
+// the same ones, whatever the types of a and b.
+//
+// It returns nil when implied use is disabled with EnableImpliedUse.
+func (d *Design) ImpliedRelationships() []Relationship {
+	if d.impliedUseDisabled {
+		return nil
+	}
+	byFullId := d.nodesByFullId()
+
+	explicit := map[[2]string]struct{}{}
+	for _, rel := range d.relationships {
+		if usesLike(rel) {
+			explicit[[2]string{rel.StartID, rel.EndID}] = struct{}{}
+		}
+	}
+
+	seen := map[[2]string]struct{}{}
+	var implied []Relationship
+	for _, rel := range d.relationships {
+		if !usesLike(rel) || rel.NoImplied {
+			continue
+		}
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		d.forEachAncestorPair(start, end, func(from, to *Node) {
+			pair := [2]string{from.FullId(), to.FullId()}
+			if _, ok := explicit[pair]; ok {
+				return
+			}
+			if _, ok := seen[pair]; ok {
+				return
+			}
+			seen[pair] = struct{}{}
+			source := rel.Ref()
+			implied = append(implied, Relationship{
+				StartID:     pair[0],
+				EndID:       pair[1],
+				Type:        RelImpliedUse,
+				Description: rel.Description,
+				Technology:  rel.Technology,
+				Tags:        []string{TagImplied},
+				DerivedFrom: &source,
+			})
+		})
+	}
+	return implied
+}
+
+// -----------------------------------------------------------------------------
+// Custom relationship types
+// -----------------------------------------------------------------------------
+
+// builtinRelationshipTypes are the relationship types with a meaning of their own.
+var builtinRelationshipTypes = []RelationshipType{RelUses, RelBelongsTo, RelInteractsWith, RelMemberOf, RelImpliedUse}
+
+// IsCustom reports whether t is not one of the built-in relationship types.
+func (t RelationshipType) IsCustom() bool {
+	return !slices.Contains(builtinRelationshipTypes, t)
+}
+
+// usesLike reports whether rel expresses a dependency: a USES relationship or
+// one of a custom type, which is treated like USES.
+func usesLike(rel Relationship) bool {
+	return rel.Type == RelUses || rel.Type.IsCustom()
+}
+
+// SanitizeRelationshipType turns s into a valid Neo4j relationship type:
+// upper-cased, with anything but letters, digits and underscores replaced by "_".
+// It returns an empty string when nothing usable is left.
+func SanitizeRelationshipType(s string) RelationshipType {
+	b := strings.Builder{}
+	for _, r := range strings.ToUpper(strings.TrimSpace(s)) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	t := strings.Trim(b.String(), "_")
+	if t == "" || (t[0] >= '0' && t[0] <= '9') {
+		return ""
+	}
+	return RelationshipType(t)
+}
+
+// addCustomRelationship records a relationship of a custom type. It is saved to
+// Neo4j with that type, rendered as a USES relationship tagged with the type by
+// the Structurizr exporter, and implies relationships like USES unless noImplied
+// is set. Invalid types are recorded as errors reported by Validate.
+func (d *Design) addCustomRelationship(startNode, endNode INode, relType, desc string, noImplied bool) {
+	t := SanitizeRelationshipType(relType)
+	if t == "" {
+		d.recordError(fmt.Errorf("invalid relationship type %q for %s -> %s", relType, startNode.FullId(), endNode.FullId()))
+		return
+	}
+	d.recordRelationship(Relationship{
+		StartID:     startNode.FullId(),
+		EndID:       endNode.FullId(),
+		Type:        t,
+		Description: desc,
+		NoImplied:   noImplied,
+	})
+}
+
+// -----------------------------------------------------------------------------
+// Bulk wiring
+// -----------------------------------------------------------------------------
+
+// addRelationshipsWith adds a USES relationship between owner and each of
+// others, from owner when outgoing is set and to it otherwise, as the
+// individual calls would. Every "%s" in descriptionFmt is replaced by the name
+// of the other element. Nil elements are recorded as errors reported by
+// Validate; method names the calling method in those errors.
+func (d *Design) addRelationshipsWith(owner INode, others []INode, outgoing bool, descriptionFmt, method string) {
+	for i, other := range others {
+		if isNilNode(other) {
+			d.recordError(fmt.Errorf("%s of %s: element %d is nil", method, owner.FullId(), i))
+			continue
+		}
+		name := other.FullName()
+		if node := d.lookupNode(other.FullId()); node != nil {
+			name = node.Name
+		}
+		description := strings.ReplaceAll(descriptionFmt, "%s", name)
+		if outgoing {
+			d.addRelationship(owner, other, RelUses, description)
+		} else {
+			d.addRelationship(other, owner, RelUses, description)
+		}
+	}
+}
+
+// isNilNode reports whether n is nil or a nil pointer, e.g. a *Container that
+// was never assigned.
+func isNilNode(n INode) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// UsesAll adds a USES relationship from the system to each target, e.g. a
+// gateway to every service. Every "%s" in descriptionFmt is replaced by the
+// name of the target. Nil targets are recorded as errors reported by Validate.
+func (s *System) UsesAll(targets []INode, descriptionFmt string) *System {
+	s.design.addRelationshipsWith(s, targets, true, descriptionFmt, "UsesAll")
+	return s
+}
+
+// UsedByAll adds a USES relationship from each source to the system. See UsesAll.
+func (s *System) UsedByAll(sources []INode, descriptionFmt string) *System {
+	s.design.addRelationshipsWith(s, sources, false, descriptionFmt, "UsedByAll")
+	return s
+}
+
+// UsesAll adds a USES relationship from the container to each target. See
+// System.UsesAll.
+func (c *Container) UsesAll(targets []INode, descriptionFmt string) *Container {
+	c.design.addRelationshipsWith(c, targets, true, descriptionFmt, "UsesAll")
+	return c
+}
+
+// UsedByAll adds a USES relationship from each source to the container. See
+// System.UsesAll.
+func (c *Container) UsedByAll(sources []INode, descriptionFmt string) *Container {
+	c.design.addRelationshipsWith(c, sources, false, descriptionFmt, "UsedByAll")
+	return c
+}
+
+// UsesAll adds a USES relationship from the component to each target. See
+// System.UsesAll.
+func (c *Component) UsesAll(targets []INode, descriptionFmt string) *Component {
+	c.design.addRelationshipsWith(c, targets, true, descriptionFmt, "UsesAll")
+	return c
+}
+
+// UsedByAll adds a USES relationship from each source to the component. See
+// System.UsesAll.
+func (c *Component) UsedByAll(sources []INode, descriptionFmt string) *Component {
+	c.design.addRelationshipsWith(c, sources, false, descriptionFmt, "UsedByAll")
+	return c
+}
@@ -0,0 +1,303 @@
+package neoarch
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newDuplicatesDesign returns a design built under the given policy where the
+// API uses the database three times: twice as "Writes", once as "Reads".
+func newDuplicatesDesign(policy DuplicateRelationshipPolicy) *Design {
+	d := NewDesign("Dup", "Duplicates").OnDuplicateRelationship(policy)
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	db := s.Container("DB", "Orders")
+	api.Uses(db, "Writes")
+	api.Uses(db, "Reads")
+	api.Uses(db, "Writes")
+	return d
+}
+
+// usesDescriptions returns the descriptions of the USES relationships of d, in
+// the order they are stored.
+func usesDescriptions(d *Design) []string {
+	var descriptions []string
+	for _, rel := range d.relationships {
+		if rel.Type == RelUses {
+			descriptions = append(descriptions, rel.Description)
+		}
+	}
+	return descriptions
+}
+
+func TestDuplicateRelationshipPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicateRelationshipPolicy
+		want   []string
+		errors int
+	}{
+		{"keep", DuplicateKeep, []string{"Writes", "Reads"}, 0},
+		{"merge descriptions", DuplicateMergeDescriptions, []string{"Reads; Writes"}, 0},
+		{"error", DuplicateError, []string{"Writes"}, 2},
+		{"keep first", DuplicateKeepFirst, []string{"Writes"}, 0},
+		{"overwrite", DuplicateOverwrite, []string{"Writes"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDuplicatesDesign(tt.policy)
+			if got := usesDescriptions(d); !slices.Equal(got, tt.want) {
+				t.Errorf("stored descriptions = %q, want %q", got, tt.want)
+			}
+			if got := len(d.errs); got != tt.errors {
+				t.Errorf("recorded %d errors, want %d: %v", got, tt.errors, d.errs)
+			}
+		})
+	}
+}
+
+func TestDuplicateMergeDescriptionsIsDeterministic(t *testing.T) {
+	build := func(descriptions ...string) string {
+		d := NewDesign("Dup", "Duplicates").OnDuplicateRelationship(DuplicateMergeDescriptions)
+		s := d.System("Shop", "Sells things")
+		api := s.Container("API", "Backend")
+		db := s.Container("DB", "Orders")
+		for _, desc := range descriptions {
+			api.Uses(db, desc)
+		}
+		return usesDescriptions(d)[0]
+	}
+
+	a := build("Writes", "Reads", "Deletes")
+	b := build("Deletes", "Writes", "Reads", "Writes")
+	if want := "Deletes; Reads; Writes"; a != want || b != want {
+		t.Errorf("merged descriptions = %q and %q, want %q", a, b, want)
+	}
+}
+
+func TestDuplicateRelationships(t *testing.T) {
+	groups := newDuplicatesDesign(DuplicateKeep).DuplicateRelationships()
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1: %+v", len(groups), groups)
+	}
+	group := groups[0]
+	if group.StartID != "Shop.Shop.API" || group.EndID != "Shop.Shop.DB" || group.Type != RelUses {
+		t.Errorf("group = %s -[%s]-> %s", group.StartID, group.Type, group.EndID)
+	}
+	if len(group.Relationships) != 2 || group.Identical() {
+		t.Errorf("group holds %d relationships, identical %v; want the 2 distinct ones", len(group.Relationships), group.Identical())
+	}
+
+	for _, policy := range []DuplicateRelationshipPolicy{DuplicateMergeDescriptions, DuplicateError, DuplicateKeepFirst, DuplicateOverwrite} {
+		if groups := newDuplicatesDesign(policy).DuplicateRelationships(); len(groups) != 0 {
+			t.Errorf("policy %d: got %d groups, want none", policy, len(groups))
+		}
+	}
+}
+
+func TestDuplicateRelationshipsReportsExactCopies(t *testing.T) {
+	d := newDuplicatesDesign(DuplicateKeep)
+	// Designs read back from Neo4j or CSV are not built through a policy
+	d.relationships = append(d.relationships, d.relationships[len(d.relationships)-1])
+
+	if got := d.DuplicateRelationships(); len(got) != 1 || len(got[0].Relationships) != 3 {
+		t.Errorf("got %+v, want one group of 3 relationships", got)
+	}
+}
+
+func TestImpliedRelationshipsProvenance(t *testing.T) {
+	d := newShopDesign()
+	explicit := map[string]bool{}
+	for _, rel := range d.relationships {
+		if strings.Contains(rel.Description, "(implied") {
+			t.Errorf("explicit relationship %s has provenance in its description", rel.Key())
+		}
+		explicit[rel.Key()] = true
+	}
+
+	implied := d.ImpliedRelationships()
+	if len(implied) == 0 {
+		t.Fatal("the shop design has no implied relationships")
+	}
+	for _, rel := range implied {
+		if strings.Contains(rel.Description, "(implied") {
+			t.Errorf("implied relationship %s has provenance in its description", rel.Key())
+		}
+		if rel.DerivedFrom == nil {
+			t.Errorf("implied relationship %s has no DerivedFrom", rel.Key())
+		} else if !explicit[rel.DerivedFrom.Key()] {
+			t.Errorf("implied relationship %s is derived from the unknown %s", rel.Key(), rel.DerivedFrom.Key())
+		}
+	}
+
+	statements := BuildImpliedRelationshipStatements(d)
+	for i, stmt := range statements {
+		if !strings.Contains(stmt.Query, "SET r.derived_from = $derivedFrom") || stmt.Params["derivedFrom"] == "" {
+			t.Errorf("implied statement %d does not save derived_from: %v\n%s", i, stmt.Params, stmt.Query)
+		}
+	}
+	for _, stmt := range append(BuildRelationshipStatements(d), statements...) {
+		if desc, _ := stmt.Params["desc"].(string); strings.Contains(desc, "(implied") {
+			t.Errorf("statement saves provenance in the description %q", desc)
+		}
+	}
+
+	for _, name := range Exporters() {
+		b := strings.Builder{}
+		if err := d.Export(name, &b); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if strings.Contains(b.String(), "(implied") {
+			t.Errorf("%s output has provenance in a description:\n%s", name, b.String())
+		}
+	}
+}
+
+func TestDuplicateKeepMergesAttributes(t *testing.T) {
+	d := NewDesign("Dup", "Duplicates")
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	queue := s.Container("Queue", "Events")
+	api.Uses(queue, "Publishes")
+	api.UsesAsync(queue, "Publishes")
+	api.UsesOptional(queue, "Publishes")
+	api.UsesWithTechnology(queue, "Publishes", "AMQP")
+
+	if got := usesDescriptions(d); !slices.Equal(got, []string{"Publishes"}) {
+		t.Fatalf("got USES relationships %v, want a single one", got)
+	}
+	rel := d.relationships[d.relIndex().byKey[Relationship{StartID: api.FullId(), EndID: queue.FullId(), Type: RelUses, Description: "Publishes"}.Key()]]
+	if rel.InteractionStyle != InteractionAsynchronous || !rel.Optional || rel.Technology != "AMQP" {
+		t.Errorf("merged relationship = %+v, want asynchronous, optional, over AMQP", rel)
+	}
+	if issues := d.Validate(); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+
+	api.UsesWithTechnology(queue, "Publishes", "Kafka")
+	var conflicts []string
+	for _, issue := range d.Validate() {
+		if issue.Code == "build" {
+			conflicts = append(conflicts, issue.Message)
+		}
+	}
+	if len(conflicts) != 1 || !strings.Contains(conflicts[0], `technology "Kafka", already added with "AMQP"`) {
+		t.Errorf("got build issues %v, want the technology conflict", conflicts)
+	}
+	if rel := d.relationships[d.relIndex().byKey[rel.Key()]]; rel.Technology != "AMQP" {
+		t.Errorf("conflicting copy changed the technology to %q", rel.Technology)
+	}
+}
+
+// BenchmarkRecordRelationship adds an exact copy of a relationship to designs
+// of growing size: the index keeps the cost per copy flat.
+func BenchmarkRecordRelationship(b *testing.B) {
+	for _, size := range []int{1_000, 10_000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			d := NewDesign("Large", "Generated design")
+			s := d.System("Shop", "Sells things")
+			containers := make([]*Container, size)
+			for i := range containers {
+				containers[i] = s.Container(fmt.Sprintf("C%d", i), "Generated")
+			}
+			for i := 1; i < size; i++ {
+				containers[i-1].Uses(containers[i], "Calls")
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				containers[size/2].Uses(containers[size/2+1], "Calls")
+			}
+		})
+	}
+}
+
+func TestBulkWiringMatchesIndividualCalls(t *testing.T) {
+	build := func(bulk bool) *Design {
+		d := NewDesign("Wiring", "Bulk wiring")
+		ops := d.Person("Ops", "Runs the platform")
+		stripe := d.System("Stripe", "Payments").External()
+		edge := d.System("Edge", "Entry points")
+		gateway := edge.Container("Gateway", "API gateway")
+		auth := gateway.Component("Auth", "Checks tokens")
+		core := d.System("Core", "Business services")
+		users := core.Container("Users", "Accounts")
+		orders := core.Container("Orders", "Orders")
+		billing := core.Container("Billing", "Invoices")
+		if bulk {
+			gateway.UsesAll([]INode{users, orders, billing}, "Routes to %s")
+			gateway.UsedByAll([]INode{ops}, "Configured by %s")
+			auth.UsesAll([]INode{users, stripe}, "Checks %s")
+			auth.UsedByAll([]INode{ops}, "Rotates keys")
+			edge.UsesAll([]INode{stripe}, "Pays %s")
+			core.UsedByAll([]INode{ops, edge}, "Called by %s")
+		} else {
+			gateway.Uses(users, "Routes to Users")
+			gateway.Uses(orders, "Routes to Orders")
+			gateway.Uses(billing, "Routes to Billing")
+			gateway.UsedBy(ops, "Configured by Ops")
+			auth.Uses(users, "Checks Users")
+			auth.Uses(stripe, "Checks Stripe")
+			auth.UsedBy(ops, "Rotates keys")
+			edge.Uses(stripe, "Pays Stripe")
+			core.UsedBy(ops, "Called by Ops")
+			core.UsedBy(edge, "Called by Edge")
+		}
+		return d
+	}
+	csvRelationships := func(d *Design) string {
+		var nodes, rels strings.Builder
+		if err := d.ToCSV(&nodes, &rels); err != nil {
+			t.Fatal(err)
+		}
+		return rels.String()
+	}
+	impliedKeys := func(d *Design) []string {
+		var keys []string
+		for _, rel := range d.ImpliedRelationships() {
+			keys = append(keys, rel.Key())
+		}
+		slices.Sort(keys)
+		return keys
+	}
+
+	bulk, individual := build(true), build(false)
+	if got, want := csvRelationships(bulk), csvRelationships(individual); got != want {
+		t.Errorf("bulk wiring gave the relationships\n%s\nwant\n%s", got, want)
+	}
+	if got, want := impliedKeys(bulk), impliedKeys(individual); !slices.Equal(got, want) {
+		t.Errorf("bulk wiring gave the implied relationships\n%v\nwant\n%v", got, want)
+	}
+	if issues := bulk.Validate(); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestBulkWiringReportsNilElements(t *testing.T) {
+	d := NewDesign("Wiring", "Bulk wiring")
+	s := d.System("Shop", "Sells things")
+	gateway := s.Container("Gateway", "API gateway")
+	users := s.Container("Users", "Accounts")
+	var missing *Container
+	gateway.UsesAll([]INode{users, missing, nil}, "Routes to %s")
+	s.UsedByAll([]INode{nil}, "Uses")
+
+	if got := usesDescriptions(d); !slices.Equal(got, []string{"Routes to Users"}) {
+		t.Errorf("got USES relationships %v, want the one to Users", got)
+	}
+	var build []string
+	for _, issue := range d.Validate() {
+		if issue.Code == "build" {
+			build = append(build, issue.Message)
+		}
+	}
+	want := []string{
+		"UsesAll of Shop.Shop.Gateway: element 1 is nil",
+		"UsesAll of Shop.Shop.Gateway: element 2 is nil",
+		"UsedByAll of Shop: element 0 is nil",
+	}
+	if !slices.Equal(build, want) {
+		t.Errorf("got build issues %q, want %q", build, want)
+	}
+}
@@ -0,0 +1,126 @@
+package neoarch
+
+// -----------------------------------------------------------------------------
+// Relationship handles
+// -----------------------------------------------------------------------------
+
+// Rel is a handle on a relationship that was just added, to refine it before
+// resuming the element chain with Done:
+//
+//	api.UsesRel(db, "Reads/writes").Technology("SQL").Weight(10).Done().Tag("core")
+//
+// Changes made through the handle are what gets saved and exported. A handle
+// on a relationship rejected by the DuplicateError policy, or removed since
+// (see Design.RemoveRelationship), ignores changes.
+type Rel[T any] struct {
+	design *Design
+	key    string // Key of the relationship, "" when rejected
+	owner  T
+}
+
+// update applies fn to the relationship, unless it was rejected or removed.
+// The relationship is looked up by key, since removals shift the others.
+func (r *Rel[T]) update(fn func(rel *Relationship)) *Rel[T] {
+	r.design.mu.Lock()
+	defer r.design.mu.Unlock()
+	if i, ok := r.design.relIndex().byKey[r.key]; ok && r.key != "" {
+		fn(&r.design.relationships[i])
+	}
+	return r
+}
+
+// Technology sets the technology of the relationship, e.g. "gRPC".
+func (r *Rel[T]) Technology(technology string) *Rel[T] {
+	return r.update(func(rel *Relationship) { rel.Technology = technology })
+}
+
+// Tag appends a tag to the relationship.
+func (r *Rel[T]) Tag(tag string) *Rel[T] {
+	return r.update(func(rel *Relationship) { rel.Tags = append(rel.Tags, tag) })
+}
+
+// Async marks the relationship as asynchronous.
+func (r *Rel[T]) Async() *Rel[T] {
+	return r.update(func(rel *Relationship) { rel.InteractionStyle = InteractionAsynchronous })
+}
+
+// Sync marks the relationship as synchronous.
+func (r *Rel[T]) Sync() *Rel[T] {
+	return r.update(func(rel *Relationship) { rel.InteractionStyle = InteractionSynchronous })
+}
+
+// Optional marks the relationship as optional: the start works without the end.
+func (r *Rel[T]) Optional() *Rel[T] {
+	return r.update(func(rel *Relationship) { rel.Optional = true })
+}
+
+// Weight sets the relative importance of the relationship.
+func (r *Rel[T]) Weight(weight int) *Rel[T] {
+	return r.update(func(rel *Relationship) { rel.Weight = weight })
+}
+
+// Relationship returns a copy of the relationship as currently recorded, or the
+// zero Relationship when it was rejected or removed.
+func (r *Rel[T]) Relationship() Relationship {
+	var rel Relationship
+	r.update(func(recorded *Relationship) { rel = *recorded })
+	return rel
+}
+
+// Done returns the element the relationship was added from, to continue the chain.
+func (r *Rel[T]) Done() T {
+	return r.owner
+}
+
+// addRel records a relationship and returns a handle on it.
+func addRel[T any](d *Design, owner T, start, end INode, relType RelationshipType, desc string) *Rel[T] {
+	r := &Rel[T]{design: d, owner: owner}
+	if i := d.recordRelationship(Relationship{StartID: start.FullId(), EndID: end.FullId(), Type: relType, Description: desc}); i >= 0 {
+		// The stored relationship may have a default description, or be an
+		// existing one rel was merged into
+		d.mu.Lock()
+		r.key = d.relationships[i].Key()
+		d.mu.Unlock()
+	}
+	return r
+}
+
+// UsesRel is like Uses but returns a handle on the relationship.
+func (p *Person) UsesRel(n INode, description string) *Rel[*Person] {
+	return addRel(p.design, p, p, n, RelUses, description)
+}
+
+// InteractsWithRel is like InteractsWith but returns a handle on the relationship.
+func (p *Person) InteractsWithRel(other *Person, description string) *Rel[*Person] {
+	return addRel(p.design, p, p, other, RelInteractsWith, description)
+}
+
+// UsesRel is like Uses but returns a handle on the relationship.
+func (s *System) UsesRel(n INode, description string) *Rel[*System] {
+	return addRel(s.design, s, s, n, RelUses, description)
+}
+
+// UsedByRel is like UsedBy but returns a handle on the relationship.
+func (s *System) UsedByRel(n INode, description string) *Rel[*System] {
+	return addRel(s.design, s, n, s, RelUses, description)
+}
+
+// UsesRel is like Uses but returns a handle on the relationship.
+func (c *Container) UsesRel(n INode, description string) *Rel[*Container] {
+	return addRel(c.design, c, c, n, RelUses, description)
+}
+
+// UsedByRel is like UsedBy but returns a handle on the relationship.
+func (c *Container) UsedByRel(n INode, description string) *Rel[*Container] {
+	return addRel(c.design, c, n, c, RelUses, description)
+}
+
+// UsesRel is like Uses but returns a handle on the relationship.
+func (c *Component) UsesRel(n INode, description string) *Rel[*Component] {
+	return addRel(c.design, c, c, n, RelUses, description)
+}
+
+// UsedByRel is like UsedBy but returns a handle on the relationship.
+func (c *Component) UsedByRel(n INode, description string) *Rel[*Component] {
+	return addRel(c.design, c, n, c, RelUses, description)
+}
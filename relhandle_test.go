@@ -0,0 +1,64 @@
+package neoarch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRelHandleSurvivesRemovals(t *testing.T) {
+	d := NewDesign("Handles", "Relationship handles")
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	db := s.Container("DB", "Orders")
+	cache := s.Container("Cache", "Hot orders")
+
+	api.Uses(db, "first")
+	h := api.UsesRel(cache, "second")
+	if !d.RemoveRelationship(RelationshipRef{StartID: api.FullId(), EndID: db.FullId(), Type: RelUses, Description: "first"}) {
+		t.Fatal("the first relationship was not removed")
+	}
+	h.Technology("gRPC").Tag("hot").Weight(5).Async().Property("sla_ms", 10)
+
+	var second Relationship
+	for _, rel := range d.relationships {
+		if rel.Type == RelUses && rel.Description == "second" {
+			second = rel
+		} else if rel.Technology != "" || len(rel.Tags) > 0 {
+			t.Errorf("the handle edited another relationship: %+v", rel)
+		}
+	}
+	if second.Technology != "gRPC" || !slices.Equal(second.Tags, []string{"hot"}) || second.Weight != 5 ||
+		second.InteractionStyle != InteractionAsynchronous || second.Properties["sla_ms"] != 10 {
+		t.Errorf("the handled relationship is %+v", second)
+	}
+	if got := h.Relationship(); got.Description != "second" || got.Technology != "gRPC" {
+		t.Errorf("Relationship() = %+v", got)
+	}
+
+	// Once removed, the handle ignores changes
+	if err := d.RemoveNode(cache.FullId()); err != nil {
+		t.Fatal(err)
+	}
+	h.Technology("HTTP")
+	if got := h.Relationship(); got.Type != "" {
+		t.Errorf("the handle of a removed relationship returned %+v", got)
+	}
+	for _, rel := range d.relationships {
+		if rel.Technology == "HTTP" {
+			t.Errorf("the handle of a removed relationship edited %+v", rel)
+		}
+	}
+	if h.Done() != api {
+		t.Error("Done does not return the owner")
+	}
+}
+
+func TestRelHandleOfDefaultDescription(t *testing.T) {
+	d := NewDesign("Handles", "Relationship handles").DefaultDescription("Uses")
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	h := api.UsesRel(s.Container("DB", "Orders"), "").Technology("SQL")
+	if got := h.Relationship(); got.Description != "Uses" || got.Technology != "SQL" {
+		t.Errorf("Relationship() = %+v, want the defaulted description with SQL", got)
+	}
+}
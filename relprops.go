@@ -0,0 +1,92 @@
+package neoarch
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Relationship properties
+// -----------------------------------------------------------------------------
+
+// reservedRelationshipProperties are the properties SaveToNeo4j sets itself on
+// relationships, which Relationship.Properties cannot override.
+var reservedRelationshipProperties = []string{
+	"description", "technology", "tags", "derived_from", "weight", "interactionStyle", "optional", "scenario", "version",
+	"savedProperties",
+}
+
+// validRelationshipProperty reports whether key can be used in
+// Relationship.Properties: a valid property name (see ValidLabel) that is not
+// one of the properties saved for the relationship fields.
+func validRelationshipProperty(key string) bool {
+	return ValidLabel(key) && !slices.Contains(reservedRelationshipProperties, key)
+}
+
+// AddRelationshipWithProps adds a relationship carrying extra properties, e.g.
+// sla_ms or auth_method, saved by SaveToNeo4j on the Neo4j relationship and
+// read back by LoadFromNeo4j. Values must be ones Neo4j can store: strings,
+// numbers, booleans or lists of them. Keys must be valid property names
+// (letters, digits and underscores, see ValidLabel) other than those saved for
+// the relationship fields, such as description or weight; other keys are
+// recorded as errors reported by Validate and left out. When the relationship
+// is merged into an existing one, the properties are added to it.
+func (d *Design) AddRelationshipWithProps(startNode, endNode INode, relType RelationshipType, desc string, props map[string]any) {
+	i := d.recordRelationship(Relationship{
+		StartID:     startNode.FullId(),
+		EndID:       endNode.FullId(),
+		Type:        relType,
+		Description: desc,
+	})
+	for _, key := range slices.Sorted(maps.Keys(props)) {
+		d.setRelationshipProperty(i, key, props[key])
+	}
+}
+
+// Property sets an extra property of the relationship. See
+// Design.AddRelationshipWithProps.
+func (r *Rel[T]) Property(key string, value any) *Rel[T] {
+	return r.update(func(rel *Relationship) { r.design.setPropertyLocked(rel, key, value) })
+}
+
+// setRelationshipProperty sets a property of the relationship at index i,
+// recording an error for invalid keys. Rejected relationships (i < 0) are left
+// alone.
+func (d *Design) setRelationshipProperty(i int, key string, value any) {
+	if i < 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setPropertyLocked(&d.relationships[i], key, value)
+}
+
+// setPropertyLocked is setRelationshipProperty for callers holding d.mu.
+func (d *Design) setPropertyLocked(rel *Relationship, key string, value any) {
+	if !validRelationshipProperty(key) {
+		d.recordErrorLocked(fmt.Errorf("invalid property %q on %s relationship %s -> %s: use letters, digits and underscores, and not a reserved name",
+			key, rel.Type, rel.StartID, rel.EndID))
+		return
+	}
+	if rel.Properties == nil {
+		rel.Properties = map[string]any{}
+	}
+	rel.Properties[key] = value
+}
+
+// relationshipPropertiesFrom reads back the extra properties saved on a Neo4j
+// relationship, or nil when there are none.
+func relationshipPropertiesFrom(props map[string]any) map[string]any {
+	var out map[string]any
+	for key, value := range props {
+		if !validRelationshipProperty(key) {
+			continue
+		}
+		if out == nil {
+			out = map[string]any{}
+		}
+		out[key] = value
+	}
+	return out
+}
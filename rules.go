@@ -0,0 +1,64 @@
+package neoarch
+
+import (
+	"fmt"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Architecture rules
+// -----------------------------------------------------------------------------
+
+// RuleViolation is a relationship breaking one of the built-in architecture rules.
+type RuleViolation struct {
+	Rule            string // Name of the rule, e.g. "encapsulation"
+	Message         string
+	Relationship    Relationship
+	Source          *Node
+	Target          *Node
+	SourceContainer *Node // Container of the source, when relevant to the rule
+	TargetContainer *Node // Container of the target, when relevant to the rule
+}
+
+func (v RuleViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// EncapsulationViolations flags explicit USES relationships from a Component
+// directly to a Component of another Container, bypassing that container's
+// public surface. Targets tagged "public" and relationships tagged "allowed"
+// are exempt. Violations are returned in the order the relationships were added.
+func (d *Design) EncapsulationViolations() []RuleViolation {
+	byFullId := d.nodesByFullId()
+
+	var violations []RuleViolation
+	for _, rel := range d.relationships {
+		if rel.Type != RelUses || slices.Contains(rel.Tags, "allowed") {
+			continue
+		}
+		source, okSource := byFullId[rel.StartID]
+		target, okTarget := byFullId[rel.EndID]
+		if !okSource || !okTarget || source.NodeType != NodeTypeComponent || target.NodeType != NodeTypeComponent {
+			continue
+		}
+		if slices.Contains(target.Tags, "public") {
+			continue
+		}
+		sourceContainer := d.ancestorAt(d.parentOf(source), NodeTypeContainer)
+		targetContainer := d.ancestorAt(d.parentOf(target), NodeTypeContainer)
+		if sourceContainer == nil || targetContainer == nil || sourceContainer == targetContainer {
+			continue
+		}
+		violations = append(violations, RuleViolation{
+			Rule: "encapsulation",
+			Message: fmt.Sprintf("component %s (container %s) uses component %s of container %s directly; use the container, tag the target \"public\" or the relationship \"allowed\"",
+				source.FullName(), sourceContainer.FullName(), target.FullName(), targetContainer.FullName()),
+			Relationship:    rel,
+			Source:          source,
+			Target:          target,
+			SourceContainer: sourceContainer,
+			TargetContainer: targetContainer,
+		})
+	}
+	return violations
+}
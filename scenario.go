@@ -0,0 +1,210 @@
+package neoarch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Scenarios
+// -----------------------------------------------------------------------------
+
+// ErrUnknownScenario is returned when exporting a scenario the design doesn't have.
+var ErrUnknownScenario = errors.New("unknown scenario")
+
+// Scenario is an overlay on a design, e.g. the "target" architecture next to
+// the current one. Its changes are recorded as a delta against the design
+// rather than applied to it; Design.Materialize produces the merged design.
+type Scenario struct {
+	Name   string
+	design *Design
+	delta  DesignDiff // From the design to the scenario
+}
+
+// Scenario returns the scenario with the given name, creating an empty one.
+func (d *Design) Scenario(name string) *Scenario {
+	if s, ok := d.scenarios[name]; ok {
+		return s
+	}
+	if d.scenarios == nil {
+		d.scenarios = map[string]*Scenario{}
+	}
+	s := &Scenario{Name: name, design: d}
+	d.scenarios[name] = s
+	return s
+}
+
+// Scenarios returns the names of the scenarios of the design, sorted.
+func (d *Design) Scenarios() []string {
+	names := make([]string, 0, len(d.scenarios))
+	for name := range d.scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build runs fn on the scenario state, a copy of the design with the scenario
+// applied, and records what fn added or changed. Use the DSL as usual inside
+// fn: re-declaring an element, e.g. d.System("UserSystem", "New description"),
+// gives access to it and records the property changes.
+func (s *Scenario) Build(fn func(d *Design)) *Scenario {
+	state := s.design.Materialize(s.Name)
+	fn(state)
+	s.delta = Diff(s.design, state)
+	return s
+}
+
+// RemoveNode removes the node with the given ID or FullId from the scenario,
+// together with its descendants and their relationships.
+func (s *Scenario) RemoveNode(id string) *Scenario {
+	return s.Build(func(d *Design) {
+		node, ok := d.nodes[id]
+		if !ok {
+			node, ok = d.nodesByFullId()[id]
+		}
+		if ok {
+			d.removeNode(node)
+		}
+	})
+}
+
+// RemoveRelationship removes a relationship from the scenario.
+func (s *Scenario) RemoveRelationship(ref RelationshipRef) *Scenario {
+	return s.Build(func(d *Design) {
+		d.removeRelationship(ref.Key())
+	})
+}
+
+// Delta returns the changes of the scenario against its design.
+func (s *Scenario) Delta() DesignDiff {
+	return s.delta
+}
+
+// Materialize returns a copy of the design with the named scenario applied, or
+// nil when there is no such scenario. Changes recorded against parts of the
+// design that were removed since are ignored. The receiver is not modified.
+func (d *Design) Materialize(name string) *Design {
+	s, ok := d.scenarios[name]
+	if !ok {
+		return nil
+	}
+	m := d.clone()
+
+	for _, rel := range s.delta.RemovedRelationships {
+		m.removeRelationship(rel.Key())
+	}
+	for _, node := range s.delta.RemovedNodes {
+		if existing, ok := m.nodesByFullId()[node.FullId()]; ok {
+			m.removeNode(existing)
+		}
+	}
+	// Sorted by FullId, so parents come before their children
+	for _, node := range s.delta.AddedNodes {
+		copied := *node
+		copied.design = m
+		if node.ParentNode != nil {
+			parent, ok := m.nodesByFullId()[node.ParentNode.FullId()]
+			if !ok {
+				continue
+			}
+			copied.ParentNode = parent
+		}
+		m.setNode(&copied)
+	}
+	byFullId := m.nodesByFullId()
+	for _, change := range s.delta.ChangedNodes {
+		if node, ok := byFullId[change.NodeID]; ok {
+			applyNodeChange(node, change)
+		}
+	}
+	for _, rel := range s.delta.AddedRelationships {
+		m.recordRelationship(rel)
+	}
+	return m
+}
+
+// BuildScenarioStatements returns the statements SaveToNeo4j runs for the
+// scenarios of the design, after saving the design itself. Each scenario is a
+// Scenario node linked from the Design node by HAS_SCENARIO, with ADDS edges to
+// the nodes it adds (saved with a scenario property), REMOVES edges to the
+// nodes it removes and CHANGES edges, holding the property and its new value,
+// to the nodes it changes. Relationships it adds are saved with a scenario
+// property, and the keys of those it removes are kept on the Scenario node.
+func BuildScenarioStatements(d *Design) []Statement {
+	var statements []Statement
+	for _, name := range d.Scenarios() {
+		s := d.scenarios[name]
+		m := d.Materialize(name)
+		scenarioID := d.ID + "_scenario_" + name
+		if d.Version != "" {
+			scenarioID = d.ID + "_" + d.Version + "_scenario_" + name
+		}
+
+		removedRels := make([]string, 0, len(s.delta.RemovedRelationships))
+		for _, rel := range s.delta.RemovedRelationships {
+			removedRels = append(removedRels, rel.Key())
+		}
+		statements = append(statements, Statement{
+			Query: `
+MERGE (s:Scenario { id: $id })
+SET s.name = $name, s.designId = $designId, s.removedRelationships = $removedRelationships
+WITH s
+MATCH (d:Design ` + mergeKey("designId", d.Version) + `)
+MERGE (d)-[:HAS_SCENARIO]->(s)
+`,
+			Params: map[string]any{"id": scenarioID, "name": name, "designId": d.ID, "removedRelationships": removedRels},
+		})
+		if d.Version != "" {
+			statements[len(statements)-1].Params["version"] = d.Version
+		}
+
+		link := func(relType, nodeID string, props string, params map[string]any) {
+			if params == nil {
+				params = map[string]any{}
+			}
+			params["scenarioID"] = scenarioID
+			params["nodeID"] = nodeID
+			if d.Version != "" {
+				params["version"] = d.Version
+			}
+			statements = append(statements, Statement{
+				Query: fmt.Sprintf(`
+MATCH (s:Scenario { id: $scenarioID }), (n %s)
+MERGE (s)-[r:%s%s]->(n)
+`, mergeKey("nodeID", d.Version), relType, props),
+				Params: params,
+			})
+		}
+
+		for _, node := range s.delta.AddedNodes {
+			added, ok := m.nodesByFullId()[node.FullId()]
+			if !ok {
+				continue
+			}
+			stmt := nodeStatement(d, added, false)
+			stmt.Query = strings.TrimRight(stmt.Query, "\n") + "\nSET n.scenario = $scenario\n"
+			stmt.Params["scenario"] = name
+			statements = append(statements, stmt)
+			link("ADDS", node.FullId(), "", nil)
+		}
+		for _, node := range s.delta.RemovedNodes {
+			link("REMOVES", node.FullId(), "", nil)
+		}
+		for _, change := range s.delta.ChangedNodes {
+			link("CHANGES", change.NodeID, " { property: $property }", map[string]any{"property": change.Property})
+			last := &statements[len(statements)-1]
+			last.Query += "SET r.value = $value\n"
+			last.Params["value"] = change.New
+		}
+		for _, rel := range s.delta.AddedRelationships {
+			stmt := relationshipStatement(d.Version, rel, false)
+			stmt.Query += "SET r.scenario = $scenario\n"
+			stmt.Params["scenario"] = name
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
@@ -0,0 +1,128 @@
+package neoarch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// -----------------------------------------------------------------------------
+// Schema version
+// -----------------------------------------------------------------------------
+
+// SchemaVersion is the version of the graph layout SaveToNeo4j writes: labels,
+// properties and relationship directions. It is bumped on incompatible changes,
+// so saved designs can be migrated.
+const SchemaVersion = 1
+
+// LibraryVersion is the version of neoarch saved as the generator of a design,
+// "dev" unless set at build time:
+//
+//	go build -ldflags "-X github.com/wricardo/neoarch.LibraryVersion=v1.4.0"
+var LibraryVersion = "dev"
+
+// generator identifies what saved a design, e.g. "neoarch/v1.4.0".
+func generator() string {
+	return "neoarch/" + LibraryVersion
+}
+
+// SchemaVersionError is returned when loading a design saved with a newer
+// schema than this version of the library supports.
+type SchemaVersionError struct {
+	DesignID  string
+	Found     int // Schema version of the saved design
+	Supported int // SchemaVersion
+	Generator string
+}
+
+func (e *SchemaVersionError) Error() string {
+	return fmt.Sprintf("design %s was saved by %s with schema version %d, newer than the supported %d",
+		e.DesignID, e.Generator, e.Found, e.Supported)
+}
+
+// DesignMetadata is what SaveToNeo4j saves on the Design node about the design
+// itself.
+type DesignMetadata struct {
+	ID                string
+	Name              string
+	Description       string
+	Version           string // Design.Version
+	Tags              []string
+	Meta              map[string]string // See SetMeta
+	SchemaVersion     int               // 0 for designs saved before schema versions were recorded
+	Generator         string            // e.g. "neoarch/v1.4.0"
+	NodeCount         int               // Nodes of the design, the Design node included
+	RelationshipCount int               // Explicit relationships of the design
+}
+
+// ReadDesignMetadata reads the Design node of the unversioned design saved with
+// the given id, without loading the design, e.g. to check its schema version
+// before a migration. It fails with ErrDesignNodeNotFound when there is none.
+func ReadDesignMetadata(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, designID string) (*DesignMetadata, error) {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+MATCH (n:Design { id: $id })
+WHERE coalesce(n.version, '') = ''
+RETURN properties(n) AS props
+LIMIT 1
+`
+		result, e := tx.Run(ctx, query, map[string]any{"id": designID})
+		if e != nil {
+			return nil, e
+		}
+		if !result.Next(ctx) {
+			return nil, result.Err()
+		}
+		props, _, e := neo4j.GetRecordValue[map[string]any](result.Record(), "props")
+		return props, e
+	})
+	if err != nil {
+		return nil, err
+	}
+	props, _ := res.(map[string]any)
+	if props == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDesignNodeNotFound, designID)
+	}
+	return designMetadataFrom(designID, props), nil
+}
+
+// designMetadataFrom reads the metadata saved on a Design node.
+func designMetadataFrom(designID string, props map[string]any) *DesignMetadata {
+	text := func(key string) string {
+		s, _ := props[key].(string)
+		return s
+	}
+	number := func(key string) int {
+		n, _ := props[key].(int64)
+		return int(n)
+	}
+	m := &DesignMetadata{
+		ID:                designID,
+		Name:              text("name"),
+		Description:       text("description"),
+		Version:           text("version"),
+		Meta:              metaFromProperties(props),
+		SchemaVersion:     number("schema_version"),
+		Generator:         text("generator"),
+		NodeCount:         number("nodeCount"),
+		RelationshipCount: number("relationshipCount"),
+	}
+	if tags := stringList(props["tags"]); len(tags) > 0 {
+		m.Tags = tags
+	}
+	return m
+}
+
+// checkSchemaVersion fails with a *SchemaVersionError when the design saved
+// with these Design node properties has a newer schema than supported.
+func checkSchemaVersion(designID string, props map[string]any) error {
+	m := designMetadataFrom(designID, props)
+	if m.SchemaVersion > SchemaVersion {
+		return &SchemaVersionError{DesignID: designID, Found: m.SchemaVersion, Supported: SchemaVersion, Generator: m.Generator}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package neoarch
+
+import (
+	"context"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// SearchIndexName is the name of the full-text index created by EnsureSearchIndex.
+const SearchIndexName = "neoarch_search"
+
+// searchLabels are the labels covered by the search index.
+var searchLabels = []string{
+	string(NodeTypeDesign),
+	string(NodeTypePerson),
+	string(NodeTypePersonGroup),
+	string(NodeTypeSystem),
+	string(NodeTypeContainer),
+	string(NodeTypeComponent),
+	string(NodeTypeCode),
+}
+
+// NodeSummary is a search match.
+type NodeSummary struct {
+	ID       string // FullId of the node
+	Name     string
+	NodeType NodeType
+	Score    float64
+}
+
+// EnsureSearchIndex creates the full-text index over the name and description
+// of the nodes with one of the C4 labels, unless it already exists. It needs
+// Neo4j 4.4 or later, like the driver. Custom-label nodes are not indexed.
+func EnsureSearchIndex(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig) error {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `CREATE FULLTEXT INDEX ` + SearchIndexName + ` IF NOT EXISTS
+FOR (n:` + strings.Join(searchLabels, "|") + `)
+ON EACH [n.name, n.description]`
+		_, e := tx.Run(ctx, query, nil)
+		return nil, e
+	})
+	return err
+}
+
+// SearchNodes runs a full-text query (Lucene syntax, e.g. "user*") against the
+// search index, scoped to one design, and returns the matches best first.
+// EnsureSearchIndex must have been called once on the database.
+func SearchNodes(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, designID, query string) ([]NodeSummary, error) {
+	session := driver.NewSession(ctx, sessConfig)
+	defer session.Close(ctx)
+
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		cypher := `
+CALL db.index.fulltext.queryNodes($index, $query) YIELD node, score
+WHERE node.designId = $designID
+RETURN node.id AS id, node.name AS name, node.nodeType AS nodeType, score
+ORDER BY score DESC, id
+`
+		result, e := tx.Run(ctx, cypher, map[string]any{"index": SearchIndexName, "query": query, "designID": designID})
+		if e != nil {
+			return nil, e
+		}
+		var matches []NodeSummary
+		for result.Next(ctx) {
+			record := result.Record()
+			id, _, _ := neo4j.GetRecordValue[string](record, "id")
+			name, _, _ := neo4j.GetRecordValue[string](record, "name")
+			nodeType, _, _ := neo4j.GetRecordValue[string](record, "nodeType")
+			score, _, e := neo4j.GetRecordValue[float64](record, "score")
+			if e != nil {
+				return nil, e
+			}
+			matches = append(matches, NodeSummary{ID: id, Name: name, NodeType: NodeType(nodeType), Score: score})
+		}
+		return matches, result.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	matches, _ := res.([]NodeSummary)
+	return matches, nil
+}
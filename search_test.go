@@ -0,0 +1,25 @@
+package neoarch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func TestEnsureSearchIndex(t *testing.T) {
+	driver := &recordingDriver{}
+	if err := EnsureSearchIndex(context.Background(), driver, neo4j.SessionConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.statements) != 1 {
+		t.Fatalf("got %d statements, want a single one: %v", len(driver.statements), driver.statements)
+	}
+	query := driver.statements[0].Query
+	for _, want := range []string{"CREATE FULLTEXT INDEX " + SearchIndexName + " IF NOT EXISTS", "FOR (n:Design|Person|PersonGroup|System|Container|Component|Code)", "ON EACH [n.name, n.description]"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query does not contain %q:\n%s", want, query)
+		}
+	}
+}
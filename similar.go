@@ -0,0 +1,196 @@
+package neoarch
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// -----------------------------------------------------------------------------
+// Near-duplicate nodes
+// -----------------------------------------------------------------------------
+
+// ErrUnknownNode is returned when an id does not match any node of the design.
+var ErrUnknownNode = errors.New("unknown node")
+
+// FindSimilarNodes returns the groups of nodes whose names are so close they
+// are likely accidental duplicates, e.g. "User DB" and "UserDB" after an
+// import. Only nodes of the same type under the same parent are compared.
+// Names are compared ignoring case, spaces and punctuation, with a Levenshtein
+// similarity from 0 (nothing in common) to 1 (same name); two nodes are similar
+// when it is at least threshold, and a group holds the nodes linked by such
+// pairs. Nodes in a group, and groups by their first node, are sorted by FullId.
+func (d *Design) FindSimilarNodes(threshold float64) [][]*Node {
+	type key struct {
+		parent   string
+		nodeType NodeType
+	}
+	siblings := map[key][]*Node{}
+	var keys []key
+	for _, node := range d.sortedNodes() {
+		if node.NodeType == NodeTypeDesign {
+			continue
+		}
+		k := key{nodeType: node.NodeType}
+		if parent := d.parentOf(node); parent != nil {
+			k.parent = parent.FullId()
+		}
+		if _, ok := siblings[k]; !ok {
+			keys = append(keys, k)
+		}
+		siblings[k] = append(siblings[k], node)
+	}
+
+	var groups [][]*Node
+	for _, k := range keys {
+		nodes := siblings[k]
+		names := make([]string, len(nodes))
+		for i, node := range nodes {
+			names[i] = normalizeName(node.Name)
+		}
+		// Union-find over the similar pairs
+		root := make([]int, len(nodes))
+		for i := range root {
+			root[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if root[i] != i {
+				root[i] = find(root[i])
+			}
+			return root[i]
+		}
+		for i := range nodes {
+			for j := i + 1; j < len(nodes); j++ {
+				if nameSimilarity(names[i], names[j]) >= threshold {
+					root[find(j)] = find(i)
+				}
+			}
+		}
+		byRoot := map[int][]*Node{}
+		for i, node := range nodes {
+			byRoot[find(i)] = append(byRoot[find(i)], node)
+		}
+		for i := range nodes {
+			if group := byRoot[i]; len(group) > 1 {
+				groups = append(groups, group)
+			}
+		}
+	}
+	slices.SortFunc(groups, func(a, b []*Node) int { return strings.Compare(a[0].FullId(), b[0].FullId()) })
+	return groups
+}
+
+// normalizeName lower-cases name and keeps only its letters and digits.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// nameSimilarity returns 1 minus the Levenshtein distance between a and b
+// divided by the length of the longer one.
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// MergeNodes folds the node dropID into keepID, both ids or FullIds: the
+// relationships of the dropped node are redirected to the kept one, which also
+// gets its tags and, when it has none, its description. Relationships that
+// become loops or exact duplicates are removed, and so is the dropped node.
+//
+// The dropped node must not have children, since their ids derive from its
+// own; merge or remove them first.
+func (d *Design) MergeNodes(keepID, dropID string) error {
+	keep, drop := d.lookupNode(keepID), d.lookupNode(dropID)
+	if keep == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, keepID)
+	}
+	if drop == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, dropID)
+	}
+	if keep == drop {
+		return fmt.Errorf("cannot merge node %s into itself", keep.FullId())
+	}
+	if children := d.Children(drop.FullId()); len(children) > 0 {
+		return fmt.Errorf("cannot merge node %s: it has %d children", drop.FullId(), len(children))
+	}
+	if slices.Contains(d.ancestorsOrSelf(keep), drop) {
+		return fmt.Errorf("cannot merge node %s into its descendant %s", drop.FullId(), keep.FullId())
+	}
+
+	keepFullId, dropFullId := keep.FullId(), drop.FullId()
+	parent := d.parentOf(drop)
+	seen := map[string]struct{}{}
+	relationships := make([]Relationship, 0, len(d.relationships))
+	for _, rel := range d.relationships {
+		if rel.Type == RelBelongsTo && rel.StartID == dropFullId && parent != nil && rel.EndID == parent.FullId() {
+			continue // Containment of the dropped node
+		}
+		if rel.StartID == dropFullId {
+			rel.StartID = keepFullId
+		}
+		if rel.EndID == dropFullId {
+			rel.EndID = keepFullId
+		}
+		if rel.StartID == rel.EndID {
+			continue
+		}
+		if _, ok := seen[rel.Key()]; ok {
+			continue
+		}
+		seen[rel.Key()] = struct{}{}
+		relationships = append(relationships, rel)
+	}
+	d.relationships = relationships
+
+	for _, tag := range drop.Tags {
+		tagOnce(keep, tag)
+	}
+	if keep.Description == "" {
+		keep.Description = drop.Description
+	}
+	delete(d.nodes, drop.ID)
+	d.hierarchy = nil
+	d.relationshipIndex = nil
+	return nil
+}
+
+// lookupNode returns the node with the given ID or FullId, or nil.
+func (d *Design) lookupNode(id string) *Node {
+	if node, ok := d.nodes[id]; ok {
+		return node
+	}
+	return d.nodesByFullId()[id]
+}
@@ -0,0 +1,243 @@
+package neoarch
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Statement is a single Cypher query together with its parameters.
+type Statement struct {
+	Query  string
+	Params map[string]any
+}
+
+// BuildNodeStatements returns the MERGE statements SaveToNeo4j runs for the
+// design nodes, ordered by node FullId. Building them has no side effects, so
+// callers can wrap, log or route them through their own tooling instead of
+// calling SaveToNeo4j.
+func BuildNodeStatements(d *Design) []Statement {
+	return buildNodeStatements(d, false)
+}
+
+// buildNodeStatements returns the statements of the design nodes, creating
+// them with CREATE rather than MERGE when create is set (see SaveOptions.UseCreate).
+func buildNodeStatements(d *Design, create bool) []Statement {
+	statements := make([]Statement, 0, len(d.nodes))
+	for _, node := range d.sortedNodes() {
+		statements = append(statements, nodeStatement(d, node, create))
+	}
+	return statements
+}
+
+// nodeStatement returns the MERGE statement of a single node, or its CREATE
+// statement when create is set.
+func nodeStatement(d *Design, node *Node, create bool) Statement {
+	setStr := "n.name=$name, n.description=$desc, n.nodeType=$nodeType, n.tags=$tags, n.designId=$designId"
+	// The saved values are kept as the base PullChangesFromNeo4j compares edits against
+	setStr += ", n.savedDescription=$desc, n.savedTags=$tags, n.savedTechnology=$technology"
+	params := map[string]any{
+		"id":         node.FullId(),
+		"designId":   d.ID,
+		"name":       node.Name,
+		"desc":       node.Description,
+		"nodeType":   string(node.NodeType),
+		"tags":       node.Tags,
+		"technology": node.Technology,
+	}
+	if d.Version != "" {
+		params["version"] = d.Version
+	}
+	for _, tag := range node.Tags {
+		tag = strings.ReplaceAll(tag, `-`, `_`)
+		tag = strings.ReplaceAll(tag, `:`, `_`)
+		tag = strings.ReplaceAll(tag, ` `, `_`)
+		tag = strings.ReplaceAll(tag, `"`, `_`)
+		tag = strings.ReplaceAll(tag, `'`, `_`)
+		setStr += ", n.tag_" + tag + "=$tag_" + tag
+		params["tag_"+tag] = tag
+	}
+	if node.IsExternal {
+		setStr += ", n.external=$ext"
+		params["ext"] = node.IsExternal
+	}
+	if !node.Snippet.IsZero() {
+		setStr += ", n.codeLanguage=$codeLanguage, n.codeSnippet=$codeSnippet"
+		params["codeLanguage"] = node.Snippet.Language
+		params["codeSnippet"] = node.Snippet.Code
+	}
+	if h := node.Layout; !h.IsZero() {
+		setStr += ", n.layoutRank=$layoutRank, n.layoutGroup=$layoutGroup, n.layoutPosition=$layoutPosition"
+		params["layoutRank"] = string(h.Rank)
+		params["layoutGroup"] = h.Group
+		params["layoutPosition"] = h.PreferredPosition
+	}
+	if node.Technology != "" {
+		setStr += ", n.technology=$technology"
+	}
+	// Always set, so that re-saving a node that is no longer deprecated clears it
+	setStr += ", n.deprecated=$deprecated"
+	params["deprecated"] = node.Deprecated
+	if node.Deprecated && node.DeprecationReason != "" {
+		setStr += ", n.deprecationReason=$deprecationReason"
+		params["deprecationReason"] = node.DeprecationReason
+	}
+	if node.NodeType == NodeTypeDesign && node.ID == d.ID {
+		setStr += ", n.schema_version=$schemaVersion, n.generator=$generator, n.nodeCount=$nodeCount, n.relationshipCount=$relationshipCount"
+		params["schemaVersion"] = SchemaVersion
+		params["generator"] = generator()
+		params["nodeCount"] = len(d.nodes)
+		params["relationshipCount"] = len(d.relationships)
+		for _, key := range d.MetaKeys() {
+			setStr += ", n." + metaPrefix + key + "=$" + metaPrefix + key
+			params[metaPrefix+key] = d.meta[key]
+		}
+		setStr += ", n.savedMeta=$savedMeta"
+		params["savedMeta"] = savedJSON(d.meta)
+	}
+
+	query := strings.Builder{}
+
+	if node.NodeType == NodeTypeUnknown {
+		// A placeholder left by NodeReference stands for a node that may have
+		// been saved under its real label, e.g. by another design: reuse it
+		// untouched rather than adding an Unknown twin
+		query.WriteString(`MERGE (n ` + mergeKey("id", d.Version) + `)
+ON CREATE SET n:Unknown, ` + setStr + `
+`)
+		return Statement{Query: query.String(), Params: params}
+	}
+	verb := "MERGE"
+	if create {
+		verb = "CREATE"
+	}
+	if len(node.Labels) > 0 {
+		query.WriteString(verb + ` (n:` + string(node.NodeType))
+		for _, label := range node.Labels {
+			query.WriteString(`:` + label)
+		}
+		query.WriteString(` ` + mergeKey("id", d.Version) + `)`)
+	} else {
+		query.WriteString(verb + ` (n:` + string(node.NodeType) + ` ` + mergeKey("id", d.Version) + `)`)
+	}
+	if create {
+		query.WriteString(`
+SET ` + setStr + `
+`)
+	} else {
+		query.WriteString(`
+ON CREATE SET ` + setStr + `
+ON MATCH SET  ` + setStr + `
+`)
+		if _, ok := params["deprecationReason"]; !ok {
+			query.WriteString("REMOVE n.deprecationReason\n")
+		}
+	}
+
+	return Statement{Query: query.String(), Params: params}
+}
+
+// BuildRelationshipStatements returns the MERGE statements SaveToNeo4j runs for
+// the design relationships, ordered by start, end, type and description.
+// Building them has no side effects.
+func BuildRelationshipStatements(d *Design) []Statement {
+	return buildRelationshipStatements(d, false)
+}
+
+// buildRelationshipStatements returns the statements of the design
+// relationships, using CREATE rather than MERGE when create is set.
+func buildRelationshipStatements(d *Design, create bool) []Statement {
+	statements := make([]Statement, 0, len(d.relationships))
+	for _, rel := range sortedRelationships(d.relationships) {
+		statements = append(statements, relationshipStatement(d.Version, rel, create))
+	}
+	return statements
+}
+
+// mergeKey returns the properties identifying a saved node whose id is in the
+// given parameter: the id alone, or with the version of a versioned design, so
+// each version is a subgraph of its own.
+func mergeKey(param, version string) string {
+	if version == "" {
+		return "{ id: $" + param + " }"
+	}
+	return "{ id: $" + param + ", version: $version }"
+}
+
+// relationshipStatement returns the MERGE statement of a single relationship
+// of the given design version, or its CREATE statement when create is set. Its
+// endpoints are matched by id whatever their label, so they must have been
+// saved already: the statement creates no nodes.
+func relationshipStatement(version string, rel Relationship, create bool) Statement {
+	verb := "MERGE"
+	if create {
+		verb = "CREATE"
+	}
+	query := fmt.Sprintf(`
+MATCH (start %s)
+MATCH (end %s)
+%s (start)-[r:%s { description: $desc }]->(end)
+`, mergeKey("startID", version), mergeKey("endID", version), verb, rel.Type)
+
+	params := map[string]any{
+		"startID": rel.StartID,
+		"endID":   rel.EndID,
+		"desc":    rel.Description,
+	}
+	if version != "" {
+		params["version"] = version
+	}
+	// Always set, null removing the property, so re-saving a relationship
+	// reflects the model when a field was cleared
+	query += "SET r.technology = $technology, r.tags = $tags, r.weight = $weight, r.interactionStyle = $interactionStyle\n"
+	params["technology"] = nullIfZero(rel.Technology)
+	params["tags"] = nil
+	if len(rel.Tags) > 0 {
+		params["tags"] = rel.Tags
+	}
+	params["weight"] = nullIfZero(rel.Weight)
+	params["interactionStyle"] = nullIfZero(string(rel.InteractionStyle))
+	if rel.DerivedFrom != nil {
+		// Key of the explicit relationship this one was derived from
+		query += "SET r.derived_from = $derivedFrom\n"
+		params["derivedFrom"] = rel.DerivedFrom.Key()
+	}
+	// Always set, so re-saving a relationship that is no longer optional clears it
+	query += "SET r.optional = $optional\n"
+	params["optional"] = rel.Optional
+	for _, key := range slices.Sorted(maps.Keys(rel.Properties)) {
+		if !validRelationshipProperty(key) {
+			continue
+		}
+		query += fmt.Sprintf("SET r.`%s` = $prop_%s\n", key, key)
+		params["prop_"+key] = rel.Properties[key]
+	}
+	// Kept as the base PullChangesFromNeo4j compares edits against
+	query += "SET r.savedProperties = $savedProperties\n"
+	params["savedProperties"] = savedJSON(relationshipPropertiesFrom(rel.Properties))
+	return Statement{Query: query, Params: params}
+}
+
+// nullIfZero returns v, or nil for its zero value, for which SET removes the
+// property.
+func nullIfZero[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
+
+// BuildImpliedRelationshipStatements returns the MERGE statements of the
+// relationships of ImpliedRelationships, saved as IMPLIED_USE edges tagged
+// "implied" in r.tags, with the key of their explicit relationship in
+// r.derived_from.
+func BuildImpliedRelationshipStatements(d *Design) []Statement {
+	implied := sortedRelationships(d.ImpliedRelationships())
+	statements := make([]Statement, 0, len(implied))
+	for _, rel := range implied {
+		statements = append(statements, relationshipStatement(d.Version, rel, false))
+	}
+	return statements
+}
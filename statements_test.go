@@ -0,0 +1,268 @@
+package neoarch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func TestBuildNodeStatements(t *testing.T) {
+	d := newShopDesign()
+	statements := BuildNodeStatements(d)
+
+	// Design root, Customer, Shop, Payments, Web, API, DB, Orders, Billing
+	if got, want := len(statements), 9; got != want {
+		t.Fatalf("got %d node statements, want %d", got, want)
+	}
+	byID := map[string]Statement{}
+	for _, stmt := range statements {
+		byID[stmt.Params["id"].(string)] = stmt
+	}
+
+	api, ok := byID["Shop.Shop.API"]
+	if !ok {
+		t.Fatalf("no statement for Shop.Shop.API in %v", statements)
+	}
+	for key, want := range map[string]any{
+		"designId":   d.ID,
+		"name":       "API",
+		"desc":       "Backend",
+		"nodeType":   "Container",
+		"technology": "Go",
+	} {
+		if got := api.Params[key]; got != want {
+			t.Errorf("API param %s = %v, want %v", key, got, want)
+		}
+	}
+	if !strings.HasPrefix(api.Query, "MERGE (n:Container { id: $id })") {
+		t.Errorf("API query does not merge a Container:\n%s", api.Query)
+	}
+
+	payments := byID["Payments"]
+	if payments.Params["ext"] != true || !strings.Contains(payments.Query, "n.external=$ext") {
+		t.Errorf("Payments is not saved as external: %v\n%s", payments.Params, payments.Query)
+	}
+
+	root := byID[d.ID]
+	if root.Params["nodeCount"] != 9 || root.Params["schemaVersion"] != SchemaVersion {
+		t.Errorf("design root params = %v", root.Params)
+	}
+}
+
+func TestBuildRelationshipStatements(t *testing.T) {
+	d := newShopDesign()
+	statements := BuildRelationshipStatements(d)
+
+	if got, want := len(statements), len(d.relationships); got != want {
+		t.Fatalf("got %d relationship statements, want %d", got, want)
+	}
+	var found bool
+	for _, stmt := range statements {
+		if stmt.Params["startID"] == "Shop.Shop.API.Shop.API.Orders" && stmt.Params["endID"] == "Shop.Shop.DB" {
+			found = true
+			if stmt.Params["desc"] != "Reads and writes" {
+				t.Errorf("desc = %v, want %q", stmt.Params["desc"], "Reads and writes")
+			}
+			if !strings.Contains(stmt.Query, "MERGE (start)-[r:USES { description: $desc }]->(end)") {
+				t.Errorf("query does not merge a USES edge:\n%s", stmt.Query)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no statement for Orders -> DB in %v", statements)
+	}
+}
+
+func TestBuildStatementsHaveNoSideEffects(t *testing.T) {
+	d := newShopDesign()
+	before := d.Fingerprint()
+	first := append(BuildNodeStatements(d), BuildRelationshipStatements(d)...)
+	second := append(BuildNodeStatements(d), BuildRelationshipStatements(d)...)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Error("building the statements twice gave different statements")
+	}
+	if after := d.Fingerprint(); after != before {
+		t.Error("building the statements changed the design")
+	}
+}
+
+func TestSaveToNeo4jRunsBuiltStatements(t *testing.T) {
+	d := newShopDesign()
+	driver := &recordingDriver{}
+	if err := d.SaveToNeo4j(context.Background(), driver, neo4j.SessionConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(BuildNodeStatements(d), BuildRelationshipStatements(d)...)
+	if got := driver.statements; !reflect.DeepEqual(got, want) {
+		t.Fatalf("SaveToNeo4j ran %d statements that differ from the %d built ones", len(got), len(want))
+	}
+}
+
+func TestBuildRelationshipStatementsParallelEdges(t *testing.T) {
+	var descriptions []string
+	for _, stmt := range BuildRelationshipStatements(newParallelEdgesDesign()) {
+		if stmt.Params["startID"] == "Shop.Shop.API" && stmt.Params["endID"] == "Shop.Shop.DB" {
+			descriptions = append(descriptions, stmt.Params["desc"].(string))
+		}
+	}
+	if want := []string{"Reads orders", "Writes orders"}; !reflect.DeepEqual(descriptions, want) {
+		t.Errorf("API -> DB statements describe %q, want %q", descriptions, want)
+	}
+}
+
+func TestBuildRelationshipStatementsSetOptional(t *testing.T) {
+	d := NewDesign("Cache", "Optional cache")
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	api.UsesOptional(s.Container("Cache", "Hot orders"), "Reads")
+	api.Uses(s.Container("DB", "Orders"), "Writes")
+
+	optional := map[string]any{}
+	for _, stmt := range BuildRelationshipStatements(d) {
+		if !strings.Contains(stmt.Query, "SET r.optional = $optional\n") {
+			t.Errorf("statement does not set r.optional:\n%s", stmt.Query)
+		}
+		optional[stmt.Params["desc"].(string)] = stmt.Params["optional"]
+	}
+	// Required relationships clear a stale flag rather than leaving it unset
+	if optional["Reads"] != true || optional["Writes"] != false {
+		t.Errorf("got optional params %v, want Reads true and Writes false", optional)
+	}
+}
+
+func TestBuildRelationshipStatementsClearFields(t *testing.T) {
+	d := NewDesign("Shop", "Online shop")
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	api.UsesRel(s.Container("Queue", "Jobs"), "Publishes").Technology("AMQP").Tag("events").Weight(3).Async()
+	api.Uses(s.Container("DB", "Orders"), "Writes")
+
+	params := map[string]map[string]any{}
+	for _, stmt := range BuildRelationshipStatements(d) {
+		if !strings.Contains(stmt.Query, "SET r.technology = $technology, r.tags = $tags, r.weight = $weight, r.interactionStyle = $interactionStyle\n") {
+			t.Errorf("statement does not set every field:\n%s", stmt.Query)
+		}
+		params[stmt.Params["desc"].(string)] = stmt.Params
+	}
+	set, cleared := params["Publishes"], params["Writes"]
+	if set["technology"] != "AMQP" || !reflect.DeepEqual(set["tags"], []string{"events"}) || set["weight"] != 3 || set["interactionStyle"] != "Asynchronous" {
+		t.Errorf("unexpected params for a relationship with every field: %v", set)
+	}
+	// Null removes the property, so a re-save clears a stale value
+	for _, key := range []string{"technology", "tags", "weight", "interactionStyle"} {
+		if value, ok := cleared[key]; !ok || value != nil {
+			t.Errorf("%s = %v, want null", key, value)
+		}
+	}
+}
+
+func TestBuildNodeStatementsSetDeprecated(t *testing.T) {
+	d := NewDesign("Legacy", "Decommissioning")
+	s := d.System("Shop", "Sells things")
+	s.Container("Billing", "Old billing").Deprecate("Replaced by Billing v2")
+	s.Container("Batch", "Nightly jobs").Deprecate("")
+	s.Container("API", "Backend")
+	revived := s.Container("Reports", "Revived").Node.Deprecate("Unused")
+	revived.Deprecated = false
+
+	statements := map[string]Statement{}
+	for _, stmt := range BuildNodeStatements(d) {
+		statements[stmt.Params["name"].(string)] = stmt
+	}
+	tests := []struct {
+		name       string
+		deprecated bool
+		reason     any
+	}{
+		{"Billing", true, "Replaced by Billing v2"},
+		{"Batch", true, nil},
+		{"API", false, nil},
+		{"Reports", false, nil},
+	}
+	for _, tt := range tests {
+		stmt := statements[tt.name]
+		if !strings.Contains(stmt.Query, "n.deprecated=$deprecated") || stmt.Params["deprecated"] != tt.deprecated {
+			t.Errorf("%s: deprecated = %v, want %v:\n%s", tt.name, stmt.Params["deprecated"], tt.deprecated, stmt.Query)
+		}
+		if got := stmt.Params["deprecationReason"]; got != tt.reason {
+			t.Errorf("%s: deprecationReason = %v, want %v", tt.name, got, tt.reason)
+		}
+		// A reason saved before is removed rather than left stale
+		if removes := strings.Contains(stmt.Query, "REMOVE n.deprecationReason\n"); removes != (tt.reason == nil) {
+			t.Errorf("%s: removes the reason: %v, want %v:\n%s", tt.name, removes, tt.reason == nil, stmt.Query)
+		}
+	}
+}
+
+func TestSaveToNeo4jMatchesEndpointsByID(t *testing.T) {
+	d := newShopDesign()
+	api := &Container{Node: d.lookupNode("Shop.Shop.API"), system: &System{Node: d.lookupNode("Shop"), design: d}}
+	api.Uses(d.NodeReference("LegacyBilling"), "Syncs invoices")
+
+	driver := &recordingDriver{}
+	if err := d.SaveToNeo4j(context.Background(), driver, neo4j.SessionConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, stmt := range driver.statements {
+		if stmt.Params["startID"] == nil {
+			continue
+		}
+		// Endpoints are matched whatever their label, so no node is created
+		if !strings.Contains(stmt.Query, "MATCH (start { id: $startID })") || !strings.Contains(stmt.Query, "MATCH (end { id: $endID })") || strings.Contains(stmt.Query, "Unknown") {
+			t.Errorf("relationship statement asserts endpoint labels:\n%s", stmt.Query)
+		}
+		found = found || stmt.Params["endID"] == "LegacyBilling"
+	}
+	if !found {
+		t.Error("the relationship to the referenced node was not saved")
+	}
+}
+
+func TestSaveToNeo4jRejectsMissingEndpoints(t *testing.T) {
+	d := newShopDesign()
+	// Rename the database node mid-build: relationships still use its old FullId
+	db := d.lookupNode("Shop.Shop.DB")
+	delete(d.nodes, db.ID)
+	db.ID = "Shop.Database"
+	d.nodes[db.ID] = db
+	d.hierarchy = nil
+
+	driver := &recordingDriver{}
+	err := d.SaveToNeo4j(context.Background(), driver, neo4j.SessionConfig{})
+	if !errors.Is(err, ErrUnknownNode) || !strings.Contains(err.Error(), "Shop.Shop.DB") {
+		t.Errorf("got error %v, want ErrUnknownNode listing Shop.Shop.DB", err)
+	}
+	if len(driver.statements) != 0 {
+		t.Errorf("%d statements ran despite the missing endpoint", len(driver.statements))
+	}
+}
+
+func TestBuildStatementsSaveThePullBase(t *testing.T) {
+	d := newShopDesign()
+	d.SetMeta("team", "core")
+	d.AddRelationshipWithProps(d.lookupNode("Shop.Shop.Web"), d.lookupNode("Shop.Shop.API"), RelUses, "Calls", map[string]any{"sla_ms": 200})
+
+	for _, stmt := range BuildNodeStatements(d) {
+		if !strings.Contains(stmt.Query, "n.savedTechnology=$technology") {
+			t.Errorf("%s: the technology base is not saved:\n%s", stmt.Params["id"], stmt.Query)
+		}
+		if stmt.Params["id"] == d.ID && stmt.Params["savedMeta"] != `{"team":"core"}` {
+			t.Errorf("savedMeta = %v", stmt.Params["savedMeta"])
+		}
+	}
+	for _, stmt := range BuildRelationshipStatements(d) {
+		if !strings.Contains(stmt.Query, "SET r.savedProperties = $savedProperties\n") {
+			t.Errorf("the properties base is not saved:\n%s", stmt.Query)
+		}
+		if want := `{"sla_ms":200}`; stmt.Params["desc"] == "Calls" && stmt.Params["savedProperties"] != want {
+			t.Errorf("savedProperties = %v, want %s", stmt.Params["savedProperties"], want)
+		}
+	}
+}
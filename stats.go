@@ -0,0 +1,137 @@
+package neoarch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DesignStats summarizes the size and shape of a design.
+type DesignStats struct {
+	Nodes                     int
+	NodesByType               map[NodeType]int
+	Relationships             int // Explicit relationships, BELONGS_TO and MEMBER_OF included
+	RelationshipsByType       map[RelationshipType]int
+	ImpliedRelationships      int // IMPLIED_USE relationships derived by ImpliedRelationships, not stored
+	ExternalNodes             int
+	MaxDepth                  int     // Persons and systems are at depth 1, their containers at 2, ...
+	AvgComponentsPerContainer float64 // Over all containers, including those without components
+	LargestContainer          *Node   // Container with the most children, nil if there is none
+	LargestContainerChildren  int
+	LargestSystem             *Node // System with the most children, nil if there is none
+	LargestSystemChildren     int
+}
+
+// Stats computes the DesignStats of the design in O(nodes + relationships),
+// except for the implied relationships, derived as ImpliedRelationships does
+// in O(relationships * depth²). It does not modify the design.
+func (d *Design) Stats() DesignStats {
+	stats := DesignStats{
+		NodesByType:         map[NodeType]int{},
+		RelationshipsByType: map[RelationshipType]int{},
+	}
+
+	depths := make(map[*Node]int, len(d.nodes))
+	var depthOf func(n *Node) int
+	depthOf = func(n *Node) int {
+		if depth, ok := depths[n]; ok {
+			return depth
+		}
+		depth := 0
+		if n.NodeType != NodeTypeDesign {
+			depth = 1
+			if parent := d.parentOf(n); parent != nil {
+				depth = depthOf(parent) + 1
+			}
+		}
+		depths[n] = depth
+		return depth
+	}
+
+	byFullId := d.nodesByFullId()
+	for _, node := range d.nodes {
+		stats.Nodes++
+		stats.NodesByType[node.NodeType]++
+		if node.IsExternal {
+			stats.ExternalNodes++
+		}
+		stats.MaxDepth = max(stats.MaxDepth, depthOf(node))
+	}
+
+	stats.ImpliedRelationships = len(d.ImpliedRelationships())
+
+	children := map[*Node]int{}
+	for _, rel := range d.relationships {
+		stats.Relationships++
+		stats.RelationshipsByType[rel.Type]++
+		if rel.Type != RelBelongsTo {
+			continue
+		}
+		if parent, ok := byFullId[rel.EndID]; ok {
+			children[parent]++
+		}
+	}
+
+	// Ties are broken by FullId so the result does not depend on map order.
+	larger := func(n *Node, count int, current *Node, currentCount int) bool {
+		return current == nil || count > currentCount || (count == currentCount && n.FullId() < current.FullId())
+	}
+	for parent, count := range children {
+		switch parent.NodeType {
+		case NodeTypeContainer:
+			if larger(parent, count, stats.LargestContainer, stats.LargestContainerChildren) {
+				stats.LargestContainer, stats.LargestContainerChildren = parent, count
+			}
+		case NodeTypeSystem:
+			if larger(parent, count, stats.LargestSystem, stats.LargestSystemChildren) {
+				stats.LargestSystem, stats.LargestSystemChildren = parent, count
+			}
+		}
+	}
+
+	components := 0
+	for _, node := range d.nodes {
+		if node.NodeType != NodeTypeComponent {
+			continue
+		}
+		if parent := d.parentOf(node); parent != nil && parent.NodeType == NodeTypeContainer {
+			components++
+		}
+	}
+	if containers := stats.NodesByType[NodeTypeContainer]; containers > 0 {
+		stats.AvgComponentsPerContainer = float64(components) / float64(containers)
+	}
+
+	return stats
+}
+
+// String renders the stats on a few lines, suitable for logs.
+func (s DesignStats) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "nodes: %d (%s)\n", s.Nodes, formatCounts(s.NodesByType))
+	fmt.Fprintf(&b, "relationships: %d explicit (%s), %d implied\n", s.Relationships, formatCounts(s.RelationshipsByType), s.ImpliedRelationships)
+	fmt.Fprintf(&b, "external nodes: %d\n", s.ExternalNodes)
+	fmt.Fprintf(&b, "max depth: %d\n", s.MaxDepth)
+	fmt.Fprintf(&b, "avg components per container: %.2f\n", s.AvgComponentsPerContainer)
+	if s.LargestContainer != nil {
+		fmt.Fprintf(&b, "largest container: %s (%d children)\n", s.LargestContainer.FullName(), s.LargestContainerChildren)
+	}
+	if s.LargestSystem != nil {
+		fmt.Fprintf(&b, "largest system: %s (%d children)\n", s.LargestSystem.FullName(), s.LargestSystemChildren)
+	}
+	return b.String()
+}
+
+// formatCounts renders a count map as "key=n" pairs sorted by key.
+func formatCounts[K ~string](counts map[K]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, counts[K(k)]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,48 @@
+package neoarch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	d := newShopDesign()
+	before := d.Fingerprint()
+	stats := d.Stats()
+
+	if stats.Nodes != 9 || stats.NodesByType[NodeTypeContainer] != 3 || stats.NodesByType[NodeTypeComponent] != 2 {
+		t.Errorf("nodes = %d %v", stats.Nodes, stats.NodesByType)
+	}
+	if stats.Relationships != 10 || stats.RelationshipsByType[RelUses] != 5 || stats.RelationshipsByType[RelBelongsTo] != 5 {
+		t.Errorf("explicit relationships = %d %v", stats.Relationships, stats.RelationshipsByType)
+	}
+	// Customer -> Shop, API -> DB, API -> Payments and Shop -> Payments
+	if stats.ImpliedRelationships != 4 {
+		t.Errorf("implied relationships = %d, want 4", stats.ImpliedRelationships)
+	}
+	if _, ok := stats.RelationshipsByType[RelImpliedUse]; ok {
+		t.Errorf("implied relationships are counted as explicit: %v", stats.RelationshipsByType)
+	}
+	if stats.ExternalNodes != 1 || stats.MaxDepth != 3 {
+		t.Errorf("external nodes = %d, max depth = %d", stats.ExternalNodes, stats.MaxDepth)
+	}
+	if stats.LargestContainer == nil || stats.LargestContainer.Name != "API" || stats.LargestContainerChildren != 2 {
+		t.Errorf("largest container = %v (%d)", stats.LargestContainer, stats.LargestContainerChildren)
+	}
+	if stats.LargestSystem == nil || stats.LargestSystem.Name != "Shop" || stats.LargestSystemChildren != 3 {
+		t.Errorf("largest system = %v (%d)", stats.LargestSystem, stats.LargestSystemChildren)
+	}
+	if want := "relationships: 10 explicit (BELONGS_TO=5, USES=5), 4 implied\n"; !strings.Contains(stats.String(), want) {
+		t.Errorf("String() does not contain %q:\n%s", want, stats)
+	}
+	if d.Fingerprint() != before {
+		t.Error("Stats changed the design")
+	}
+}
+
+func TestStatsWithoutImpliedUse(t *testing.T) {
+	stats := newShopDesign().EnableImpliedUse(false).Stats()
+	if stats.ImpliedRelationships != 0 {
+		t.Errorf("implied relationships = %d with implied use disabled, want 0", stats.ImpliedRelationships)
+	}
+}
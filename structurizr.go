@@ -1 +1,1001 @@
 package neoarch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Structurizr DSL export
+// -----------------------------------------------------------------------------
+
+// ToStructurizrDSL renders the design as a Structurizr DSL workspace
+// (https://docs.structurizr.com/dsl). Persons and systems are emitted at the
+// workspace level, containers and components nested inside their parents.
+//
+// Structurizr does not nest software systems, so subsystems are flattened next
+// to their top-level system inside a group named after it, and tagged "Subsystem".
+// Members of a PersonGroup are rendered inside a group boundary, or as a single
+// person element with the CollapsePersonGroups option. Code-level elements are
+// left out, since Structurizr has no code level.
+//
+// Elements marked external are tagged "External", styled grey with a dashed
+// border, and deprecated elements "Deprecated", rendered faded; each style is
+// only written when an element carries its tag. When a system is in scope
+// (see System.InScope), the other systems are tagged "External" too and
+// rendered as context, and only the system in scope and its subsystems get
+// system context and container views.
+//
+// Failures, such as a missing design node, are logged and rendered as a DSL
+// comment; use ToStructurizrDSLErr to detect them.
+func (d *Design) ToStructurizrDSL(opts ...ExportOption) string {
+	dsl, err := d.ToStructurizrDSLErr(opts...)
+	if err != nil {
+		d.logger().Error("structurizr: export failed", "design", d.ID, "error", err)
+		return "// " + err.Error() + "\n"
+	}
+	return dsl
+}
+
+// ErrDesignNodeNotFound is returned when a design lacks its root Design node,
+// which only happens with a corrupt or partially loaded design.
+var ErrDesignNodeNotFound = errors.New("no design node found")
+
+// ToStructurizrDSLErr is like ToStructurizrDSL but returns an error instead of
+// logging it.
+func (d *Design) ToStructurizrDSLErr(opts ...ExportOption) (string, error) {
+	b := strings.Builder{}
+	if err := d.ToStructurizrDSLTo(&b, opts...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ToStructurizrDSLTo streams the Structurizr DSL workspace to out, which keeps
+// memory flat for large designs. See ToStructurizrDSL. Nothing is written when
+// the design can't be exported. The workspace is always named after the
+// design's own root node; other Design nodes are ignored (Validate reports them).
+// It is the same as d.Export("structurizr", out, opts...).
+func (d *Design) ToStructurizrDSLTo(out io.Writer, opts ...ExportOption) error {
+	return d.Export(StructurizrExporter{}.Name(), out, opts...)
+}
+
+// StructurizrExporter is the Exporter behind ToStructurizrDSL, registered as
+// "structurizr".
+type StructurizrExporter struct{}
+
+func init() {
+	RegisterExporter(StructurizrExporter{})
+}
+
+// Name implements Exporter.
+func (StructurizrExporter) Name() string {
+	return "structurizr"
+}
+
+// Export implements Exporter. Warnings are logged.
+func (StructurizrExporter) Export(v *DesignView, out io.Writer) error {
+	warnings, err := exportStructurizr(v, out)
+	for _, warning := range warnings {
+		v.Logger().Warn("structurizr: "+warning.Message, "code", warning.Code, "view", warning.View, "nodes", warning.NodeIDs)
+	}
+	return err
+}
+
+// Warning is something ToStructurizrDSLWithWarnings left out of the workspace
+// because Structurizr would reject it or render it blank.
+type Warning struct {
+	Code    string // "empty-view", "skipped-view", "skipped-element", "skipped-relationship" or "skipped-step"
+	View    string // Key of the view concerned, "" for the model
+	Message string
+	NodeIDs []string // FullIds of the nodes involved
+}
+
+func (w Warning) String() string {
+	return "warning: " + w.Message
+}
+
+// Describe implements Finding.
+func (w Warning) Describe() FindingInfo {
+	return FindingInfo{Code: w.Code, Severity: SeverityWarning, Message: w.Message, NodeIDs: w.NodeIDs}
+}
+
+// ToStructurizrDSLWithWarnings is like ToStructurizrDSL but also returns what
+// was left out of the workspace: views that would be empty, such as the
+// container view of a system without containers, or a custom or dynamic view
+// whose elements or steps were all filtered out, and the relationships, steps
+// and custom view elements that were not emitted. Failures are rendered as a
+// DSL comment, as with ToStructurizrDSL.
+func (d *Design) ToStructurizrDSLWithWarnings(opts ...ExportOption) (string, []Warning) {
+	b := strings.Builder{}
+	v, err := d.View(opts...)
+	var warnings []Warning
+	if err == nil {
+		warnings, err = exportStructurizr(v, &b)
+	}
+	if err != nil {
+		d.logger().Error("structurizr: export failed", "design", d.ID, "error", err)
+		return "// " + err.Error() + "\n", nil
+	}
+	return b.String(), warnings
+}
+
+// exportStructurizr writes the workspace of the view and returns the warnings.
+func exportStructurizr(v *DesignView, out io.Writer) ([]Warning, error) {
+	view := v.Options()
+	root := v.Root()
+	if root == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDesignNodeNotFound, v.ID())
+	}
+
+	e := newStructurizrExport(v)
+	w := newDSLWriter(out)
+
+	if view.ExtendsWorkspace != "" {
+		w.open(`workspace extends "%s"`, sanitizeDSLString(view.ExtendsWorkspace))
+	} else {
+		w.open(`workspace "%s" "%s"`, sanitizeDSLString(root.Name), sanitizeDSLString(root.Description))
+	}
+	w.line("!identifiers hierarchical")
+	w.line("")
+
+	w.open("model")
+	// Structurizr derives implied relationships from every relationship, so we
+	// emit them ourselves when some relationships must not imply any
+	relationships := v.Relationships()
+	ownImplied := e.opts.IncludeImplied || !v.ImpliedUseEnabled() || slices.ContainsFunc(relationships, func(rel Relationship) bool { return rel.NoImplied })
+	if ownImplied {
+		w.line("!impliedRelationships false")
+	}
+	for _, node := range v.Nodes() {
+		if v.Parent(node) != nil && node.NodeType != NodeTypePerson {
+			continue
+		}
+		switch node.NodeType {
+		case NodeTypePerson:
+			if e.groupOf[node.FullId()] == nil {
+				e.emitNodeDSL(w, node, "")
+			}
+		case NodeTypePersonGroup:
+			e.emitPersonGroup(w, node)
+		case NodeTypeSystem:
+			if len(e.subsystems(node)) > 0 {
+				w.open(`group "%s"`, sanitizeDSLString(node.Name))
+				e.emitSystemTree(w, node)
+				w.close()
+			} else {
+				e.emitNodeDSL(w, node, "")
+			}
+		}
+	}
+
+	w.line("")
+	emitted := map[string]struct{}{}
+	explicitPairs := map[[2]string]struct{}{}
+	var covered map[[2]string]struct{}
+	if e.opts.CollapseRelationships {
+		covered = e.coveredPairs()
+	}
+	for _, rel := range relationships {
+		if rel.Type == RelBelongsTo || rel.Type == RelMemberOf {
+			continue
+		}
+		if _, ok := covered[[2]string{rel.StartID, rel.EndID}]; ok && rel.Type == RelUses {
+			// A more specific relationship implies this one
+			continue
+		}
+		start, okStart := e.refs[rel.StartID]
+		end, okEnd := e.refs[rel.EndID]
+		if e.isCodeLevel(rel.StartID) || e.isCodeLevel(rel.EndID) {
+			// Structurizr has no code level; these roll up as implied relationships
+			continue
+		}
+		if !okStart || !okEnd {
+			// One of the endpoints was not declared in the model section, e.g. an
+			// Unknown reference or a custom node, so the DSL can't reference it.
+			e.warn(Warning{
+				Code:    "skipped-relationship",
+				Message: fmt.Sprintf("skipping %s relationship %s -> %s: an endpoint was not emitted", rel.Type, rel.StartID, rel.EndID),
+				NodeIDs: []string{rel.StartID, rel.EndID},
+			})
+			continue
+		}
+		if start == end {
+			if group := e.collapsedInto(rel.StartID); group != nil && group == e.collapsedInto(rel.EndID) {
+				// Relationship between members of the same collapsed group
+				continue
+			}
+			e.warn(Warning{
+				Code:    "skipped-relationship",
+				Message: fmt.Sprintf("skipping %s relationship %s -> %s: Structurizr does not allow an element to relate to itself", rel.Type, rel.StartID, rel.EndID),
+				NodeIDs: []string{rel.StartID, rel.EndID},
+			})
+			continue
+		}
+		// Parallel edges with different descriptions are emitted separately;
+		// Structurizr rejects exact duplicates, so those are emitted once.
+		key := Relationship{StartID: start, EndID: end, Type: rel.Type, Description: rel.Description}.Key()
+		if _, ok := emitted[key]; ok {
+			continue
+		}
+		emitted[key] = struct{}{}
+		explicitPairs[[2]string{start, end}] = struct{}{}
+		var tags []string
+		if rel.Type.IsCustom() {
+			// The DSL has a single relationship kind, keep the type as a tag
+			tags = append(tags, string(rel.Type))
+		}
+		emitRelationshipDSL(w, start, end, rel, tags...)
+	}
+	if ownImplied {
+		e.emitImpliedRelationships(w, explicitPairs)
+	}
+	w.close()
+	w.line("")
+
+	w.open("views")
+	if view.ExtendsWorkspace == "" {
+		// The extended workspace has its own landscape view
+		w.open(`systemLandscape "landscape"`)
+		w.line("include *")
+		e.autolayout(w)
+		w.close()
+	}
+	for _, system := range e.systems {
+		if !e.opts.AllSystemViews && !e.inScope(system) {
+			continue
+		}
+		ref := e.refs[system.FullId()]
+		w.open(`systemContext %s "%s"`, ref, e.viewKey("system_context", system))
+		w.line("include *")
+		e.autolayout(w)
+		w.close()
+		if key := e.viewKey("container", system); e.hasElementsIn(ref) {
+			w.open(`container %s "%s"`, ref, key)
+			w.line("include *")
+			e.autolayout(w)
+			w.close()
+		} else {
+			e.warn(Warning{
+				Code:    "empty-view",
+				View:    key,
+				Message: fmt.Sprintf("skipping container view of %s: the system has no containers", system.FullId()),
+				NodeIDs: []string{system.FullId()},
+			})
+		}
+	}
+	for _, view := range v.CustomViews() {
+		e.emitCustomView(w, view)
+	}
+	for _, view := range v.DynamicViews() {
+		e.emitDynamicView(w, view)
+	}
+	e.emitStyles(w)
+	w.close()
+
+	w.close()
+	return e.warnings, w.flush()
+}
+
+// structurizrExport holds the lookup tables of a single export run.
+type structurizrExport struct {
+	view     *DesignView
+	opts     ViewOptions
+	refs     map[string]string   // FullId -> DSL identifier of every emitted element
+	systems  []*Node             // emitted software systems, in emission order
+	visited  map[string]struct{} // guards against emitting a node twice
+	groupOf  map[string]*Node    // person FullId -> first PersonGroup, by FullId, it is a member of
+	members  map[string][]*Node  // PersonGroup FullId -> member persons
+	aliases  map[string]struct{} // "<parent identifier>/<identifier>" of every emitted element
+	styled   []*Node             // emitted nodes with a style of their own
+	tagged   map[string]bool     // tags of the emitted elements, see emitStyles
+	clashing map[string][]*Node  // person name -> persons sharing it, ordered by FullId
+	warnings []Warning           // what was left out, see ToStructurizrDSLWithWarnings
+}
+
+func newStructurizrExport(v *DesignView) *structurizrExport {
+	e := &structurizrExport{
+		view:     v,
+		opts:     v.Options(),
+		refs:     map[string]string{},
+		aliases:  map[string]struct{}{},
+		visited:  map[string]struct{}{},
+		groupOf:  map[string]*Node{},
+		members:  map[string][]*Node{},
+		tagged:   map[string]bool{},
+		clashing: personNameClashes(v.Nodes()),
+	}
+	for _, rel := range v.Relationships() {
+		if rel.Type != RelMemberOf {
+			continue
+		}
+		person, group := v.Node(rel.StartID), v.Node(rel.EndID)
+		if person != nil && group != nil && e.groupOf[rel.StartID] == nil {
+			e.groupOf[rel.StartID] = group
+			e.members[rel.EndID] = append(e.members[rel.EndID], person)
+		}
+	}
+	return e
+}
+
+// inScope reports whether the system n is the system in scope or one of its
+// subsystems. Every system is in scope when none is marked.
+func (e *structurizrExport) inScope(n *Node) bool {
+	focus := e.view.InScopeSystem()
+	return focus == nil || n == focus || slices.Contains(e.view.Ancestors(n), focus)
+}
+
+// subsystems returns the direct child systems of a system node.
+func (e *structurizrExport) subsystems(n *Node) []*Node {
+	var out []*Node
+	for _, child := range e.view.Children(n) {
+		if child.NodeType == NodeTypeSystem {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// emitPersonGroup emits a PersonGroup either as a single person element that
+// stands in for all its members, or as a group boundary around them.
+func (e *structurizrExport) emitPersonGroup(w *dslWriter, g *Node) {
+	members := e.members[g.FullId()]
+	if e.opts.CollapsePersonGroups {
+		e.emitNodeDSL(w, g, "")
+		for _, member := range members {
+			e.refs[member.FullId()] = e.refs[g.FullId()]
+		}
+		return
+	}
+	if len(members) == 0 {
+		return
+	}
+	w.open(`group "%s"`, sanitizeDSLString(g.Name))
+	for _, member := range members {
+		e.emitNodeDSL(w, member, "")
+	}
+	w.close()
+}
+
+// emitSystemTree emits a system followed by all of its subsystems, flattened.
+func (e *structurizrExport) emitSystemTree(w *dslWriter, n *Node) {
+	e.emitNodeDSL(w, n, "")
+	for _, sub := range e.subsystems(n) {
+		e.emitSystemTree(w, sub)
+	}
+}
+
+// emitContainerTree emits a top-level container followed by its nested
+// containers, flattened as siblings since Structurizr does not nest containers,
+// and optionally wrapped in a group named after the top-level container.
+func (e *structurizrExport) emitContainerTree(w *dslWriter, c *Node, systemRef string) {
+	nested := e.nestedContainers(c)
+	grouped := e.opts.GroupNestedContainers && len(nested) > 0
+	if grouped {
+		w.open(`group "%s"`, sanitizeDSLString(c.Name))
+	}
+	e.emitNodeDSL(w, c, systemRef)
+	for _, child := range nested {
+		e.emitNodeDSL(w, child, systemRef)
+	}
+	if grouped {
+		w.close()
+	}
+}
+
+// nestedContainers returns all containers nested below c, depth first.
+func (e *structurizrExport) nestedContainers(c *Node) []*Node {
+	var out []*Node
+	for _, child := range e.view.Children(c) {
+		if child.NodeType == NodeTypeContainer {
+			out = append(out, child)
+			out = append(out, e.nestedContainers(child)...)
+		}
+	}
+	return out
+}
+
+// containerAlias returns the identifier of a container within its system.
+// Nested containers are prefixed with their parents' identifiers, since they
+// are flattened next to them.
+func (e *structurizrExport) containerAlias(n *Node) string {
+	alias := sanitizeIdentifier(localID(n))
+	if parent := e.view.Parent(n); parent != nil && parent.NodeType == NodeTypeContainer {
+		return e.containerAlias(parent) + "_" + alias
+	}
+	return alias
+}
+
+// collapsedInto returns the PersonGroup standing for the element with the
+// given FullId when person groups are collapsed: the group itself or the group
+// of a member. It returns nil otherwise.
+func (e *structurizrExport) collapsedInto(fullId string) *Node {
+	if !e.opts.CollapsePersonGroups {
+		return nil
+	}
+	if group := e.groupOf[fullId]; group != nil {
+		return group
+	}
+	if n := e.view.Node(fullId); n != nil && n.NodeType == NodeTypePersonGroup {
+		return n
+	}
+	return nil
+}
+
+// uniqueAlias returns alias, or alias with a numeric suffix when another
+// element of the same scope already has it: sanitizing turns different names,
+// e.g. "Shop {v2}" and "Shop (v2)", into the same identifier. scope is the
+// identifier of the parent element, "" at workspace level.
+func (e *structurizrExport) uniqueAlias(scope, alias string) string {
+	candidate := alias
+	for i := 2; ; i++ {
+		key := scope + "/" + candidate
+		if _, ok := e.aliases[key]; !ok {
+			e.aliases[key] = struct{}{}
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d", alias, i)
+	}
+}
+
+// emitNodeDSL writes the element declaration for n (and its nested elements)
+// and records its identifier. parentRef is empty for workspace-level elements.
+func (e *structurizrExport) emitNodeDSL(w *dslWriter, n *Node, parentRef string) {
+	if _, ok := e.visited[n.FullId()]; ok {
+		return
+	}
+	e.visited[n.FullId()] = struct{}{}
+
+	kind := e.view.Kind(n)
+	if kind == NodeTypeContainer && n.NodeType != NodeTypeContainer {
+		if parent := e.view.Parent(n); parent != nil && e.view.Kind(parent) == NodeTypeContainer {
+			// Custom label registered as a container but declared in one, e.g. with
+			// Container.Custom: the DSL doesn't nest containers, so it is a component
+			kind = NodeTypeComponent
+		}
+	}
+	var keyword string
+	switch kind {
+	case NodeTypePerson, NodeTypePersonGroup:
+		keyword = "person"
+	case NodeTypeSystem:
+		keyword = "softwareSystem"
+	case NodeTypeContainer:
+		keyword = "container"
+	case NodeTypeComponent:
+		keyword = "component"
+	default:
+		return
+	}
+
+	alias := sanitizeIdentifier(localID(n))
+	if kind == NodeTypeContainer {
+		alias = e.containerAlias(n)
+	}
+	if parentRef == "" && (n.NodeType == NodeTypeSystem || n.NodeType == NodeTypePerson || n.NodeType == NodeTypePersonGroup) {
+		// Workspace-level identifiers must be unique, so use the whole ID.
+		alias = sanitizeIdentifier(n.ID)
+	}
+	alias = e.uniqueAlias(parentRef, alias)
+	ref := alias
+	if parentRef != "" {
+		ref = parentRef + "." + alias
+	}
+	e.refs[n.FullId()] = ref
+
+	tags := n.Tags
+	if kind != n.NodeType {
+		// Custom label, rendered as its registered kind
+		custom, _ := e.view.CustomKind(n)
+		tags = append(append([]string{string(n.NodeType)}, custom.Tags...), tags...)
+	}
+	parent := e.view.Parent(n)
+	if n.NodeType == NodeTypeSystem && parent != nil {
+		tags = append([]string{"Subsystem"}, tags...)
+	}
+	if (n.IsExternal || n.NodeType == NodeTypeSystem && !e.inScope(n)) && !slices.Contains(tags, "External") {
+		tags = append(tags, "External")
+	}
+	if n.NodeType == NodeTypePersonGroup {
+		tags = append([]string{"Person Group"}, tags...)
+	}
+	if n.Deprecated {
+		tags = append(tags, "Deprecated")
+	}
+	name := n.Name
+	if n.NodeType == NodeTypePerson && parent != nil {
+		// Persons can't nest: hoisted to workspace level, tagged with their system
+		tags = append(tags, parent.Name)
+		if len(e.clashing[n.Name]) > 0 {
+			// Structurizr requires distinct person names
+			name = fmt.Sprintf("%s (%s)", n.Name, parent.Name)
+			e.warn(Warning{
+				Code:    "person-name",
+				Message: fmt.Sprintf("person %s is declared as %q: another person is named %q", n.FullId(), name, n.Name),
+				NodeIDs: []string{n.FullId()},
+			})
+		}
+	}
+	if !e.view.OwnStyle(n).IsZero() {
+		// Structurizr styles elements through tags and lets the last one win,
+		// so give the node its own carrying the style of its first styled tag
+		tags = append(tags, e.styleTag(n))
+		e.styled = append(e.styled, n)
+	}
+	if n.NodeType == NodeTypeContainer && parent != nil && parent.NodeType == NodeTypeContainer {
+		tags = append([]string{"Nested Container", parent.Name}, tags...)
+	}
+
+	declaration := fmt.Sprintf(`%s = %s "%s" "%s"`, alias, keyword, sanitizeDSLString(name), sanitizeDSLString(n.Description))
+	if technology := e.view.Technology(n); technology != "" && (kind == NodeTypeContainer || kind == NodeTypeComponent) {
+		declaration += fmt.Sprintf(` "%s"`, sanitizeDSLString(technology))
+	}
+	w.open("%s", declaration)
+	if len(tags) > 0 {
+		w.line("tags %s", quoteAll(tags))
+	}
+	for _, tag := range tags {
+		e.tagged[tag] = true
+	}
+	var properties [][2]string
+	if e.opts.IncludeSnippets && !n.Snippet.IsZero() {
+		properties = append(properties, [2]string{"codeLanguage", n.Snippet.Language}, [2]string{"codeSnippet", n.Snippet.Code})
+	}
+	if h := n.Layout; !h.IsZero() {
+		properties = append(properties, [2]string{"layoutRank", string(h.Rank)}, [2]string{"layoutGroup", h.Group}, [2]string{"layoutPosition", h.PreferredPosition})
+	}
+	if len(properties) > 0 {
+		w.open("properties")
+		for _, p := range properties {
+			if p[1] != "" {
+				w.line(`"%s" "%s"`, p[0], sanitizeDSLString(p[1]))
+			}
+		}
+		w.close()
+	}
+
+	switch kind {
+	case NodeTypeSystem:
+		e.systems = append(e.systems, n)
+		for _, child := range e.view.Children(n) {
+			switch {
+			case child.NodeType == NodeTypeContainer:
+				e.emitContainerTree(w, child, ref)
+			case e.view.Kind(child) == NodeTypeContainer:
+				e.emitNodeDSL(w, child, ref)
+			}
+		}
+	case NodeTypeContainer:
+		for _, child := range e.view.Children(n) {
+			if kind := e.view.Kind(child); kind == NodeTypeComponent || kind == NodeTypeContainer {
+				e.emitNodeDSL(w, child, ref)
+			}
+		}
+	}
+	w.close()
+}
+
+// autolayout writes the autolayout line of a view, unless ManualLayout is set.
+func (e *structurizrExport) autolayout(w *dslWriter) {
+	if !e.opts.ManualLayout {
+		w.line("autolayout lr")
+	}
+}
+
+// isCodeLevel reports whether the node is a code-level element, which the DSL
+// can't represent.
+func (e *structurizrExport) isCodeLevel(fullId string) bool {
+	node := e.view.Node(fullId)
+	return node != nil && node.NodeType == NodeTypeCode
+}
+
+// coveredPairs returns the (start, end) FullId pairs implied by a more specific
+// explicit USES relationship.
+func (e *structurizrExport) coveredPairs() map[[2]string]struct{} {
+	covered := map[[2]string]struct{}{}
+	for _, rel := range e.view.Relationships() {
+		if rel.Type != RelUses {
+			continue
+		}
+		start, end := e.view.Node(rel.StartID), e.view.Node(rel.EndID)
+		if start == nil || end == nil {
+			continue
+		}
+		startChain := append([]*Node{start}, e.view.Ancestors(start)...)
+		endChain := append([]*Node{end}, e.view.Ancestors(end)...)
+		forEachAncestorPair(startChain, endChain, func(from, to *Node) {
+			covered[[2]string{from.FullId(), to.FullId()}] = struct{}{}
+		})
+	}
+	return covered
+}
+
+// emitImpliedRelationships writes the implied relationships, skipping pairs that
+// already have an explicit relationship. With IncludeImplied only those between
+// workspace-level elements are written; otherwise all of them are, standing in
+// for the ones Structurizr would have derived.
+func (e *structurizrExport) emitImpliedRelationships(w *dslWriter, explicitPairs map[[2]string]struct{}) {
+	for _, rel := range e.view.ImpliedRelationships() {
+		start, end := e.view.Node(rel.StartID), e.view.Node(rel.EndID)
+		if e.opts.IncludeImplied && (!isLandscapeLevel(start) || !isLandscapeLevel(end)) {
+			continue
+		}
+		startRef, okStart := e.refs[rel.StartID]
+		endRef, okEnd := e.refs[rel.EndID]
+		if !okStart || !okEnd || startRef == endRef {
+			continue
+		}
+		pair := [2]string{startRef, endRef}
+		if _, ok := explicitPairs[pair]; ok {
+			continue
+		}
+		explicitPairs[pair] = struct{}{}
+		var tags []string
+		if e.opts.ImpliedTag != "" && !slices.Contains(rel.Tags, e.opts.ImpliedTag) {
+			tags = append(tags, e.opts.ImpliedTag)
+		}
+		emitRelationshipDSL(w, startRef, endRef, rel, tags...)
+	}
+}
+
+// isLandscapeLevel reports whether n is rendered at the workspace level.
+func isLandscapeLevel(n *Node) bool {
+	return n.NodeType == NodeTypePerson || n.NodeType == NodeTypePersonGroup || n.NodeType == NodeTypeSystem
+}
+
+// viewKey returns the key of a view of the given kind scoped to n. Keys are
+// derived from the FullId so they survive renames, unless readable keys were asked for.
+func (e *structurizrExport) viewKey(kind string, n *Node) string {
+	if e.opts.ReadableViewKeys {
+		return kind + "_" + sanitizeDSLString(n.Name)
+	}
+	return hashKey(kind, n.FullId())
+}
+
+// emitCustomView writes a custom view: a system landscape view, or a container
+// or component view of its scope. Elements that were not emitted, or that the
+// kind of view can't show, such as components in a system landscape view, are
+// skipped, and so are views whose scope was not emitted or that are left
+// without elements.
+func (e *structurizrExport) emitCustomView(w *dslWriter, view *CustomView) {
+	kind, scope, depth := "systemLandscape", "", 0
+	if id := view.Scope(); id != "" {
+		ref, ok := e.refs[id]
+		switch {
+		case !ok:
+			e.warn(Warning{
+				Code:    "skipped-view",
+				View:    view.Key,
+				Message: fmt.Sprintf("skipping custom view %s: its scope %s was not emitted", view.Key, id),
+				NodeIDs: []string{id},
+			})
+			return
+		case e.view.Node(id).NodeType == NodeTypeSystem && !strings.Contains(ref, "."):
+			kind, scope, depth = "container", ref, 1
+		case strings.Count(ref, ".") == 1:
+			kind, scope, depth = "component", ref, 2
+		default:
+			e.warn(Warning{
+				Code:    "skipped-view",
+				View:    view.Key,
+				Message: fmt.Sprintf("skipping custom view %s: its scope %s is not a system or a container", view.Key, id),
+				NodeIDs: []string{id},
+			})
+			return
+		}
+	}
+
+	var include []string
+	for _, id := range view.Elements() {
+		ref, ok := e.refs[id]
+		if !ok {
+			e.warn(Warning{
+				Code:    "skipped-element",
+				View:    view.Key,
+				Message: fmt.Sprintf("skipping %s in custom view %s: it was not emitted", id, view.Key),
+				NodeIDs: []string{id},
+			})
+			continue
+		}
+		// Identifiers nest, so their depth is the level of the element:
+		// component views only show the components of their container
+		if d := strings.Count(ref, "."); d > depth || d == 2 && !strings.HasPrefix(ref, scope+".") {
+			e.warn(Warning{
+				Code:    "skipped-element",
+				View:    view.Key,
+				Message: fmt.Sprintf("skipping %s in custom view %s: a %s view can't show it", id, view.Key, kind),
+				NodeIDs: []string{id},
+			})
+			continue
+		}
+		if !slices.Contains(include, ref) {
+			// Members of a collapsed person group share its identifier
+			include = append(include, ref)
+		}
+	}
+	if len(include) == 0 {
+		e.warn(Warning{
+			Code:    "empty-view",
+			View:    view.Key,
+			Message: fmt.Sprintf("skipping custom view %s: it has no elements to render", view.Key),
+		})
+		return
+	}
+	if scope == "" {
+		w.open(`%s "%s" "%s"`, kind, sanitizeDSLString(view.Key), sanitizeDSLString(view.Description))
+	} else {
+		w.open(`%s %s "%s" "%s"`, kind, scope, sanitizeDSLString(view.Key), sanitizeDSLString(view.Description))
+	}
+	w.line("include %s", strings.Join(include, " "))
+	e.autolayout(w)
+	w.close()
+}
+
+// emitDynamicView writes a dynamic view with its steps sorted by order. Steps
+// between elements that were not emitted are skipped, and so are views whose
+// scope was not emitted or that are left without steps.
+func (e *structurizrExport) emitDynamicView(w *dslWriter, view *DynamicView) {
+	scope := "*"
+	if id := view.Scope(); id != "" {
+		ref, ok := e.refs[id]
+		if !ok {
+			e.warn(Warning{
+				Code:    "skipped-view",
+				View:    view.Key,
+				Message: fmt.Sprintf("skipping dynamic view %s: its scope %s was not emitted", view.Key, id),
+				NodeIDs: []string{id},
+			})
+			return
+		}
+		scope = ref
+	}
+	var steps []string
+	for _, step := range view.Steps() {
+		from, okFrom := e.refs[step.From]
+		to, okTo := e.refs[step.To]
+		if !okFrom || !okTo {
+			e.warn(Warning{
+				Code:    "skipped-step",
+				View:    view.Key,
+				Message: fmt.Sprintf("skipping step %s -> %s of dynamic view %s: an endpoint was not emitted", step.From, step.To, view.Key),
+				NodeIDs: []string{step.From, step.To},
+			})
+			continue
+		}
+		steps = append(steps, fmt.Sprintf(`%s -> %s "%s"`, from, to, sanitizeDSLString(step.Description)))
+	}
+	if len(steps) == 0 {
+		e.warn(Warning{
+			Code:    "empty-view",
+			View:    view.Key,
+			Message: fmt.Sprintf("skipping dynamic view %s: it has no steps to render", view.Key),
+		})
+		return
+	}
+	w.open(`dynamic %s "%s" "%s"`, scope, sanitizeDSLString(view.Key), sanitizeDSLString(view.Description))
+	for _, step := range steps {
+		w.line("%s", step)
+	}
+	e.autolayout(w)
+	w.close()
+}
+
+// warn records something left out of the workspace.
+func (e *structurizrExport) warn(warning Warning) {
+	e.warnings = append(e.warnings, warning)
+}
+
+// hasElementsIn reports whether an element was emitted inside the element
+// with the given DSL identifier, e.g. a container of a system.
+func (e *structurizrExport) hasElementsIn(ref string) bool {
+	for _, other := range e.refs {
+		if strings.HasPrefix(other, ref+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// styleTag returns the tag carrying the own style of n.
+func (e *structurizrExport) styleTag(n *Node) string {
+	return hashKey("style", n.FullId())
+}
+
+// emitStyles writes the default element styles, followed by the own styles of
+// the emitted nodes (see DesignView.OwnStyle).
+// The "External" and "Deprecated" styles are only written when an emitted
+// element carries the tag.
+func (e *structurizrExport) emitStyles(w *dslWriter) {
+	w.open("styles")
+	w.open(`element "Person"`)
+	w.line("shape Person")
+	w.line("background #08427b")
+	w.line("color #ffffff")
+	w.close()
+	w.open(`element "Software System"`)
+	w.line("background #1168bd")
+	w.line("color #ffffff")
+	w.close()
+	w.open(`element "Subsystem"`)
+	w.line("background #3b7dc4")
+	w.close()
+	if e.tagged["External"] {
+		w.open(`element "External"`)
+		w.line("background #999999")
+		w.line("color #ffffff")
+		w.line("border dashed")
+		w.close()
+	}
+	if e.tagged["Deprecated"] {
+		w.open(`element "Deprecated"`)
+		w.line("opacity 40")
+		w.line("border dashed")
+		w.close()
+	}
+	w.open(`element "Container"`)
+	w.line("background #438dd5")
+	w.line("color #ffffff")
+	w.close()
+	w.open(`element "Component"`)
+	w.line("background #85bbf0")
+	w.line("color #000000")
+	w.close()
+	w.open(`relationship "Synchronous"`)
+	w.line("dashed false")
+	w.close()
+	w.open(`relationship "Asynchronous"`)
+	w.line("dashed true")
+	w.close()
+	w.open(`relationship "Optional"`)
+	w.line("style dotted")
+	w.close()
+	for _, n := range e.styled {
+		emitElementStyle(w, e.styleTag(n), e.view.OwnStyle(n))
+	}
+	w.close()
+}
+
+// emitElementStyle writes the style of the elements with the given tag.
+func emitElementStyle(w *dslWriter, tag string, style ElementStyle) {
+	w.open(`element "%s"`, sanitizeDSLString(tag))
+	if style.Shape != "" {
+		w.line("shape %s", style.Shape)
+	}
+	if style.Background != "" {
+		w.line("background %s", style.Background)
+	}
+	if style.Stroke != "" {
+		w.line("stroke %s", style.Stroke)
+	}
+	w.close()
+}
+
+// emitRelationshipDSL writes the relationship between two DSL identifiers,
+// with its tags followed by the extra ones.
+func emitRelationshipDSL(w *dslWriter, startRef, endRef string, rel Relationship, extraTags ...string) {
+	line := fmt.Sprintf(`%s -> %s "%s"`, startRef, endRef, sanitizeDSLString(rel.Description))
+	if rel.Technology != "" {
+		line += fmt.Sprintf(` "%s"`, sanitizeDSLString(rel.Technology))
+	}
+	tags := append(slices.Clone(rel.Tags), extraTags...)
+	var properties [][2]string
+	if rel.InteractionStyle != "" {
+		// Styled by the relationship styles of the same name
+		tags = append(tags, string(rel.InteractionStyle))
+		properties = append(properties, [2]string{"interactionStyle", string(rel.InteractionStyle)})
+	}
+	if rel.Weight != 0 {
+		properties = append(properties, [2]string{"weight", strconv.Itoa(rel.Weight)})
+	}
+	if rel.Optional {
+		// Styled by the "Optional" relationship style
+		tags = append(tags, "Optional")
+	}
+	if len(tags) == 0 && len(properties) == 0 {
+		w.line("%s", line)
+		return
+	}
+	w.open("%s", line)
+	if len(tags) > 0 {
+		w.line("tags %s", quoteAll(tags))
+	}
+	if len(properties) > 0 {
+		w.open("properties")
+		for _, p := range properties {
+			w.line(`"%s" "%s"`, p[0], sanitizeDSLString(p[1]))
+		}
+		w.close()
+	}
+	w.close()
+}
+
+// localID returns the ID of n without its parent's ID prefix.
+func localID(n *Node) string {
+	if n.ParentNode != nil {
+		return strings.TrimPrefix(n.ID, n.ParentNode.GetID()+".")
+	}
+	return n.ID
+}
+
+// sanitizeIdentifier turns s into a valid Structurizr identifier.
+func sanitizeIdentifier(s string) string {
+	b := strings.Builder{}
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// dslStringReplacer neutralizes the characters that break a quoted DSL string:
+// backslashes and double quotes are escaped, braces (block delimiters, which
+// the DSL can't escape) become parentheses, and line breaks become spaces
+// since the DSL is line-based. Backslashes come first, so a trailing one can't
+// escape the closing quote.
+var dslStringReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`{`, `(`,
+	`}`, `)`,
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+	"\t", " ",
+)
+
+// sanitizeDSLString makes s safe to use inside a quoted DSL string.
+func sanitizeDSLString(s string) string {
+	return dslStringReplacer.Replace(s)
+}
+
+// quoteAll renders each value as a quoted DSL string, separated by spaces.
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, `"`+sanitizeDSLString(v)+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// dslWriter writes indented DSL lines through a buffer. Write errors are
+// sticky and reported by flush.
+type dslWriter struct {
+	w      *bufio.Writer
+	indent int
+}
+
+func newDSLWriter(w io.Writer) *dslWriter {
+	return &dslWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *dslWriter) line(format string, args ...any) {
+	if format != "" {
+		for range w.indent {
+			w.w.WriteString("    ")
+		}
+		fmt.Fprintf(w.w, format, args...)
+	}
+	w.w.WriteByte('\n')
+}
+
+func (w *dslWriter) open(format string, args ...any) {
+	w.line(format+" {", args...)
+	w.indent++
+}
+
+func (w *dslWriter) close() {
+	w.indent--
+	w.line("}")
+}
+
+func (w *dslWriter) flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,461 @@
+package neoarch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newParallelEdgesDesign returns a design where the API uses the database twice,
+// with different descriptions.
+func newParallelEdgesDesign() *Design {
+	d := NewDesign("Parallel", "Parallel edges")
+	s := d.System("Shop", "Sells things")
+	api := s.Container("API", "Backend")
+	db := s.Container("DB", "Orders")
+	api.Uses(db, "Reads orders")
+	api.Uses(db, "Writes orders")
+	return d
+}
+
+func TestStructurizrParallelEdges(t *testing.T) {
+	dsl := newParallelEdgesDesign().ToStructurizrDSL()
+	for _, line := range []string{
+		`Shop.API -> Shop.DB "Reads orders"`,
+		`Shop.API -> Shop.DB "Writes orders"`,
+	} {
+		if got := strings.Count(dsl, line); got != 1 {
+			t.Errorf("%q appears %d times, want once:\n%s", line, got, dsl)
+		}
+	}
+}
+
+// structurizrModel returns the hierarchical identifiers declared in the model
+// section of a Structurizr DSL workspace, e.g. "Shop.API", and the endpoints of
+// its relationship lines.
+func structurizrModel(t *testing.T, dsl string) (declared map[string]bool, relationships [][2]string) {
+	t.Helper()
+	declared = map[string]bool{}
+	var scope []string
+	inModel := false
+	for _, line := range strings.Split(dsl, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "model {":
+			inModel = true
+			continue
+		case !inModel:
+			continue
+		}
+		fields := strings.Fields(line)
+		switch {
+		case line == "}" && len(scope) == 0:
+			return declared, relationships
+		case line == "}":
+			scope = scope[:len(scope)-1]
+		case len(fields) > 2 && fields[1] == "=":
+			path := slices.DeleteFunc(append(slices.Clone(scope), fields[0]), func(s string) bool { return s == "" })
+			id := strings.Join(path, ".")
+			if declared[id] {
+				t.Errorf("identifier %s is declared twice", id)
+			}
+			declared[id] = true
+			if strings.HasSuffix(line, "{") {
+				scope = append(scope, fields[0])
+			}
+		case len(fields) > 2 && fields[1] == "->":
+			relationships = append(relationships, [2]string{fields[0], fields[2]})
+		case strings.HasSuffix(line, "{"):
+			// Groups open a block without declaring an identifier
+			scope = append(scope, "")
+		}
+	}
+	t.Fatal("the DSL has no closed model section")
+	return nil, nil
+}
+
+func TestStructurizrRelationshipsReferenceDeclaredIdentifiers(t *testing.T) {
+	collisions := func() *Design {
+		d := NewDesign("Collide", "Colliding names")
+		ops := d.Person("Ops team", "Runs things")
+		a := d.System("Shop {v2}", "First")
+		b := d.System("Shop (v2)", "Second")
+		x1 := a.Container("X y", "First")
+		x2 := a.Container("X-y", "Second")
+		c1 := x1.Component("Graph QL", "First")
+		c2 := x1.Component("Graph-QL", "Second")
+		ops.Uses(c1, "Uses")
+		c1.Uses(c2, "Calls")
+		c2.Uses(x2, "Calls")
+		x2.Uses(b, "Calls")
+		b.Uses(a, "Calls")
+		return d
+	}
+
+	tests := []struct {
+		name          string
+		design        *Design
+		relationships int
+	}{
+		{"shop", newShopDesign(), 5},
+		{"parallel edges", newParallelEdgesDesign(), 2},
+		{"colliding names", collisions(), 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsl, _ := tt.design.ToStructurizrDSLWithWarnings()
+			declared, relationships := structurizrModel(t, dsl)
+			if len(relationships) != tt.relationships {
+				t.Errorf("got %d relationships, want %d:\n%s", len(relationships), tt.relationships, dsl)
+			}
+			for _, rel := range relationships {
+				for _, id := range rel {
+					if !declared[id] {
+						t.Errorf("relationship %s -> %s references the undeclared identifier %s:\n%s", rel[0], rel[1], id, dsl)
+					}
+				}
+				if rel[0] == rel[1] {
+					t.Errorf("relationship %s -> %s relates an element to itself", rel[0], rel[1])
+				}
+			}
+		})
+	}
+}
+
+// dslBlocksBalanced reports whether the braces of the DSL outside its quoted
+// strings are balanced and every quoted string is closed on its line.
+func dslBlocksBalanced(dsl string) bool {
+	depth := 0
+	for _, line := range strings.Split(dsl, "\n") {
+		quoted := false
+		for i := 0; i < len(line); i++ {
+			switch c := line[i]; {
+			case quoted && c == '\\':
+				i++
+			case c == '"':
+				quoted = !quoted
+			case !quoted && c == '{':
+				depth++
+			case !quoted && c == '}':
+				depth--
+			}
+			if depth < 0 {
+				return false
+			}
+		}
+		if quoted {
+			return false
+		}
+	}
+	return depth == 0
+}
+
+func TestStructurizrEscapesStrings(t *testing.T) {
+	d := NewDesign("Config {prod}", `Deployed from C:\deploy\`)
+	config := d.System("Config {prod}", `Holds "settings" {per env}`)
+	store := config.Container(`Store\`, "Line one\nline two").WithTechnology("Go {1.22}")
+	api := config.Container("API", "Serves the settings")
+	d.Person(`Ops "on call"`, `Pages \ escalates`)
+	api.UsesWithTechnology(store, `Edits {keys}`, `HTTP\JSON`)
+
+	dsl, warnings := d.ToStructurizrDSLWithWarnings()
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	for _, want := range []string{
+		`"Config (prod)"`,
+		`"Holds \"settings\" (per env)"`,
+		`"Store\\"`,
+		`"Line one line two"`,
+		`"Go (1.22)"`,
+		`"Ops \"on call\""`,
+		`"Pages \\ escalates"`,
+		`"Edits (keys)"`,
+		`"HTTP\\JSON"`,
+	} {
+		if !strings.Contains(dsl, want) {
+			t.Errorf("the DSL lacks %s:\n%s", want, dsl)
+		}
+	}
+	if !dslBlocksBalanced(dsl) {
+		t.Errorf("the DSL has unbalanced blocks or unclosed strings:\n%s", dsl)
+	}
+	declared, relationships := structurizrModel(t, dsl)
+	if len(relationships) != 1 {
+		t.Fatalf("got %d relationships, want 1:\n%s", len(relationships), dsl)
+	}
+	for _, id := range relationships[0] {
+		if !declared[id] {
+			t.Errorf("the relationship references the undeclared identifier %s:\n%s", id, dsl)
+		}
+	}
+}
+
+func TestStructurizrUsesTheDesignRoot(t *testing.T) {
+	d := newShopDesign()
+	want := d.ToStructurizrDSL()
+	// A second Design node, e.g. left over from merging designs
+	d.setNode(&Node{ID: "design_Other", NodeType: NodeTypeDesign, Name: "Other", Description: "Merged", design: d})
+
+	for range 20 {
+		if got := d.ToStructurizrDSL(); got != want {
+			t.Fatalf("a second Design node changed the output:\n%s\nwant:\n%s", got, want)
+		}
+	}
+	if !strings.HasPrefix(want, `workspace "Shop" `) {
+		t.Errorf("the workspace is not the design root:\n%s", want)
+	}
+	var found bool
+	for _, issue := range d.Validate() {
+		found = found || issue.Code == "design-root" && slices.Equal(issue.NodeIDs, []string{"design_Other"})
+	}
+	if !found {
+		t.Errorf("Validate does not report the second Design node: %v", d.Validate())
+	}
+
+	delete(d.nodes, d.ID)
+	d.hierarchy = nil
+	if err := d.Export("structurizr", io.Discard); !errors.Is(err, ErrDesignNodeNotFound) {
+		t.Errorf("exporting without the design root: got %v, want ErrDesignNodeNotFound", err)
+	}
+}
+
+// newLargeDesign returns a generated design of about 10k nodes: 10 systems of
+// 20 containers of 50 components, each component using the next one and each
+// container using a container of the next system.
+func newLargeDesign() *Design {
+	d := NewDesign("Large", "Generated design")
+	var systems []*System
+	for s := range 10 {
+		systems = append(systems, d.System(fmt.Sprintf("System %d", s), "Generated system"))
+	}
+	containers := make([][]*Container, len(systems))
+	for s, system := range systems {
+		for c := range 20 {
+			container := system.Container(fmt.Sprintf("Container %d", c), "Generated container")
+			containers[s] = append(containers[s], container)
+			var previous *Component
+			for i := range 50 {
+				component := container.Component(fmt.Sprintf("Component %d", i), "Generated component")
+				if previous != nil {
+					previous.Uses(component, "Calls")
+				}
+				previous = component
+			}
+		}
+	}
+	for s := range containers {
+		for c, container := range containers[s] {
+			container.Uses(containers[(s+1)%len(containers)][c], "Calls")
+		}
+	}
+	return d
+}
+
+func BenchmarkToStructurizrDSL(b *testing.B) {
+	d := newLargeDesign()
+	b.ReportAllocs()
+	for b.Loop() {
+		d.ToStructurizrDSL()
+	}
+}
+
+func BenchmarkToStructurizrDSLTo(b *testing.B) {
+	d := newLargeDesign()
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := d.ToStructurizrDSLTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStructurizrQualifiesClashingPersonNames(t *testing.T) {
+	d := NewDesign("Ops", "Clashing persons")
+	a := d.System("A", "First")
+	b := d.System("B", "Second")
+	a.Person("Ops", "Runs A").Uses(a, "Operates")
+	b.Person("Ops", "Runs B").Uses(b, "Operates")
+	d.Person("Ops", "Runs everything")
+
+	dsl, warnings := d.ToStructurizrDSLWithWarnings()
+	for _, want := range []string{
+		`person_Ops = person "Ops" "Runs everything"`,
+		`person "Ops (A)" "Runs A"`,
+		`person "Ops (B)" "Runs B"`,
+	} {
+		if strings.Count(dsl, want) != 1 {
+			t.Errorf("%q does not appear once:\n%s", want, dsl)
+		}
+	}
+	var qualified int
+	for _, warning := range warnings {
+		if warning.Code == "person-name" {
+			qualified++
+		}
+	}
+	if qualified != 2 {
+		t.Errorf("got warnings %v, want a person-name warning per qualified person", warnings)
+	}
+	declared, relationships := structurizrModel(t, dsl)
+	for _, rel := range relationships {
+		for _, id := range rel {
+			if !declared[id] {
+				t.Errorf("relationship %s -> %s references the undeclared identifier %s:\n%s", rel[0], rel[1], id, dsl)
+			}
+		}
+	}
+
+	var issues []ValidationIssue
+	for _, issue := range d.Validate() {
+		if issue.Code == "person-name" {
+			issues = append(issues, issue)
+		}
+	}
+	if len(issues) != 1 || len(issues[0].NodeIDs) != 3 {
+		t.Errorf("got person-name issues %v, want one for the three persons", issues)
+	}
+}
+
+func TestStructurizrStylesOnlyUsedTags(t *testing.T) {
+	const external, deprecated = `element "External"`, `element "Deprecated"`
+	tests := []struct {
+		name       string
+		design     func() *Design
+		opts       []ExportOption
+		external   bool
+		deprecated bool
+	}{
+		{"internal", newParallelEdgesDesign, nil, false, false},
+		{"external", newShopDesign, nil, true, false},
+		{"deprecated", func() *Design {
+			d := newShopDesign()
+			d.lookupNode("Shop.Shop.DB").Deprecate("Moved to Spanner")
+			return d
+		}, nil, true, true},
+		{"omitted", func() *Design {
+			d := newShopDesign()
+			d.lookupNode("Shop.Shop.DB").Deprecate("Moved to Spanner")
+			return d
+		}, []ExportOption{OmitDeprecated()}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsl := tt.design().ToStructurizrDSL(tt.opts...)
+			if got := strings.Contains(dsl, external); got != tt.external {
+				t.Errorf("External style emitted: %v, want %v:\n%s", got, tt.external, dsl)
+			}
+			if got := strings.Contains(dsl, deprecated); got != tt.deprecated {
+				t.Errorf("Deprecated style emitted: %v, want %v:\n%s", got, tt.deprecated, dsl)
+			}
+			if !strings.Contains(dsl, `element "Container"`) {
+				t.Errorf("the default styles are missing:\n%s", dsl)
+			}
+		})
+	}
+}
+
+func TestStructurizrCustomViews(t *testing.T) {
+	d := newShopDesign()
+	customer := d.lookupNode("person_Customer")
+	shop := d.lookupNode("Shop")
+	payments := d.lookupNode("Payments")
+	web, api, db := d.lookupNode("Shop.Shop.Web"), d.lookupNode("Shop.Shop.API"), d.lookupNode("Shop.Shop.DB")
+	orders, billing := d.lookupNode("Shop.Shop.API.Shop.API.Orders"), d.lookupNode("Shop.Shop.API.Shop.API.Billing")
+
+	d.CustomView("actors", "Who uses what", nil).Include(customer, shop, payments, api)
+	d.CustomView("checkout", "Checkout", shop).Include(customer, web, api, api, payments)
+	d.CustomView("billing", "Billing", api).Include(orders, billing, db, payments)
+	d.CustomView("storage", "Storage", shop).Include(db)
+	d.CustomView("person", "Scoped to a person", customer).Include(web)
+	if view := d.CustomView("checkout", "Ignored", nil); view.Description != "Checkout" {
+		t.Errorf("CustomView created the view again: %+v", view)
+	}
+
+	dsl, warnings := d.ToStructurizrDSLWithWarnings()
+	for _, want := range []string{
+		"systemLandscape \"actors\" \"Who uses what\" {\n            include person_Customer Shop Payments\n",
+		"container Shop \"checkout\" \"Checkout\" {\n            include person_Customer Shop.Web Shop.API Payments\n",
+		"component Shop.API \"billing\" \"Billing\" {\n            include Shop.API.Orders Shop.API.Billing Shop.DB Payments\n",
+		"container Shop \"storage\" \"Storage\" {\n            include Shop.DB\n",
+	} {
+		if !strings.Contains(dsl, want) {
+			t.Errorf("the DSL lacks %s:\n%s", want, dsl)
+		}
+	}
+	if !dslBlocksBalanced(dsl) {
+		t.Errorf("the DSL has unbalanced blocks or unclosed strings:\n%s", dsl)
+	}
+	var got []string
+	for _, w := range warnings {
+		if w.View != "" && !strings.Contains(w.Message, "container view") {
+			got = append(got, w.Code+" "+w.View)
+		}
+	}
+	if want := []string{"skipped-element actors", "skipped-view person"}; !slices.Equal(got, want) {
+		t.Errorf("got warnings %v, want %v", got, want)
+	}
+
+	// Filtered out elements are skipped, and so are the views left empty
+	db.Deprecate("Moved to Spanner")
+	dsl, warnings = d.ToStructurizrDSLWithWarnings(OmitDeprecated())
+	got = nil
+	for _, w := range warnings {
+		if w.View != "" && !strings.Contains(w.Message, "container view") {
+			got = append(got, w.Code+" "+w.View)
+		}
+	}
+	want := []string{"skipped-element actors", "skipped-element billing", "skipped-element storage", "empty-view storage", "skipped-view person"}
+	if !slices.Equal(got, want) {
+		t.Errorf("with DB omitted, got warnings %v, want %v", got, want)
+	}
+	if strings.Contains(dsl, `"storage"`) {
+		t.Errorf("the empty view was rendered:\n%s", dsl)
+	}
+
+	// Clones have their own views
+	c := d.Clone("design_Copy", "Copy")
+	c.CustomView("storage", "", nil).Include(c.lookupNode("Shop.Shop.Web"))
+	if view := d.CustomView("storage", "", nil); len(view.include) != 1 {
+		t.Errorf("including in the clone changed the original view: %v", view.include)
+	}
+}
+
+func TestStructurizrStylesFollowTheFirstStyledTag(t *testing.T) {
+	d := newShopDesign()
+	d.TagDefaults("cache", TagDefaults{Style: ElementStyle{Background: "#ffeeaa"}})
+	d.TagDefaults("aa-legacy", TagDefaults{Style: ElementStyle{Background: "#cccccc"}})
+	web := d.lookupNode("Shop.Shop.Web")
+	web.Tag("cache")
+	web.Tag("aa-legacy")
+	db := d.lookupNode("Shop.Shop.DB").Style(ShapeCylinder, "", "")
+	db.Tag("aa-legacy")
+	dsl := d.ToStructurizrDSL()
+
+	// Web takes the style of cache, declared first, whatever the tag order in
+	// the styles; DB keeps its shape and takes the background of its tag
+	for n, want := range map[*Node][]string{
+		web: {"background #ffeeaa"},
+		db:  {"shape Cylinder", "background #cccccc"},
+	} {
+		tag := hashKey("style", n.FullId())
+		_, style, ok := strings.Cut(dsl, `element "`+tag+`" {`)
+		if !ok {
+			t.Fatalf("no style for %s:\n%s", n.FullId(), dsl)
+		}
+		style, _, _ = strings.Cut(style, "}")
+		for _, line := range want {
+			if !strings.Contains(style, line) {
+				t.Errorf("the style of %s does not contain %q:\n%s", n.FullId(), line, style)
+			}
+		}
+	}
+	for _, tag := range []string{"cache", "aa-legacy"} {
+		if strings.Contains(dsl, `element "`+tag+`"`) {
+			t.Errorf("the tag %s is styled on its own:\n%s", tag, dsl)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package neoarch
+
+import "fmt"
+
+// Shapes understood by the Structurizr exporter.
+const (
+	ShapeBox                  = "Box"
+	ShapeRoundedBox           = "RoundedBox"
+	ShapeCircle               = "Circle"
+	ShapeEllipse              = "Ellipse"
+	ShapeHexagon              = "Hexagon"
+	ShapeCylinder             = "Cylinder"
+	ShapePipe                 = "Pipe"
+	ShapePerson               = "Person"
+	ShapeRobot                = "Robot"
+	ShapeFolder               = "Folder"
+	ShapeWebBrowser           = "WebBrowser"
+	ShapeMobileDevicePortrait = "MobileDevicePortrait"
+	ShapeComponent            = "Component"
+)
+
+// ElementStyle holds explicit visual attributes of an element.
+// Colors are CSS-style hex strings such as "#1168bd".
+type ElementStyle struct {
+	Shape      string
+	Background string
+	Stroke     string
+}
+
+// IsZero reports whether no attribute is set.
+func (s ElementStyle) IsZero() bool {
+	return s == ElementStyle{}
+}
+
+// typeStyles are the default styles of the node types, the ones the Structurizr
+// workspace declares for its element types.
+var typeStyles = map[NodeType]ElementStyle{
+	NodeTypePerson:      {Shape: ShapePerson, Background: "#08427b"},
+	NodeTypePersonGroup: {Shape: ShapePerson, Background: "#08427b"},
+	NodeTypeSystem:      {Background: "#1168bd"},
+	NodeTypeContainer:   {Background: "#438dd5"},
+	NodeTypeComponent:   {Background: "#85bbf0"},
+	NodeTypeCode:        {Background: "#c5dcf5"},
+}
+
+// externalBackground replaces the default background of external elements.
+const externalBackground = "#999999"
+
+// merge returns s with its empty fields taken from fallback.
+func (s ElementStyle) merge(fallback ElementStyle) ElementStyle {
+	if s.Shape == "" {
+		s.Shape = fallback.Shape
+	}
+	if s.Background == "" {
+		s.Background = fallback.Background
+	}
+	if s.Stroke == "" {
+		s.Stroke = fallback.Stroke
+	}
+	return s
+}
+
+// ownStyleOf returns the style n has of its own: its explicit style (see
+// Node.Style), whose empty fields fall back to the style of its first tag with
+// one (see TagDefaults).
+func (d *Design) ownStyleOf(n *Node) ElementStyle {
+	style := n.Appearance
+	for _, tag := range n.Tags {
+		if defaults, ok := d.tagDefaults[tag]; ok && !defaults.Style.IsZero() {
+			return style.merge(defaults.Style)
+		}
+	}
+	return style
+}
+
+// styleOf returns the style of n: its own style (see ownStyleOf), whose empty
+// fields fall back to the default style of its type, grey when external.
+func (d *Design) styleOf(n *Node) ElementStyle {
+	style := d.ownStyleOf(n)
+	fallback := typeStyles[n.NodeType]
+	if n.IsExternal {
+		fallback.Background = externalBackground
+	}
+	return style.merge(fallback)
+}
+
+// Style returns the style exporters drawing shapes themselves should give n:
+// its explicit style, with empty fields falling back to its tags and type.
+func (v *DesignView) Style(n *Node) ElementStyle {
+	return v.design.styleOf(n)
+}
+
+// OwnStyle is like Style without the defaults of the type, for exporters whose
+// output format styles types itself, such as Structurizr. It is zero for nodes
+// with neither an explicit style nor a styled tag.
+func (v *DesignView) OwnStyle(n *Node) ElementStyle {
+	return v.design.ownStyleOf(n)
+}
+
+// textColor returns a text color readable on the given "#rrggbb" background:
+// black on light colors, white otherwise, and on colors it can't parse.
+func textColor(background string) string {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(background, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return "#ffffff"
+	}
+	if 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) > 150 {
+		return "#000000"
+	}
+	return "#ffffff"
+}
+
+// LayoutRank places an element at an edge of a diagram.
+type LayoutRank string
+
+const (
+	RankSource LayoutRank = "source" // First, e.g. gateways and entry points
+	RankSink   LayoutRank = "sink"   // Last, e.g. databases
+)
+
+// LayoutHint holds optional placement hints for an element. They are saved as
+// node properties, become rank constraints in ToDOT, and are rendered as
+// element properties by the Structurizr exporter, whose DSL can't position
+// elements; combine them with the ManualLayout export option to place elements
+// by hand.
+type LayoutHint struct {
+	Rank              LayoutRank
+	Group             string // Elements of the same group are kept together
+	PreferredPosition string // Free-form, e.g. "top-left"
+}
+
+// IsZero reports whether no hint is set.
+func (h LayoutHint) IsZero() bool {
+	return h == LayoutHint{}
+}
+
+// CodeSnippet is a piece of code attached to an element.
+type CodeSnippet struct {
+	Language string // e.g. "go", "protobuf"
+	Code     string
+}
+
+// IsZero reports whether no snippet is set.
+func (s CodeSnippet) IsZero() bool {
+	return s == CodeSnippet{}
+}
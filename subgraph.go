@@ -0,0 +1,273 @@
+package neoarch
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Subgraphs
+// -----------------------------------------------------------------------------
+
+// subgraph returns a new design with the same identity holding copies of the
+// nodes for which keepNode returns true, their ancestors, the relationships for
+// which keepRel returns true and the BELONGS_TO relationships between kept nodes.
+// Endpoints of kept relationships are kept too. The receiver is not modified.
+func (d *Design) subgraph(keepNode func(*Node) bool, keepRel func(Relationship) bool) *Design {
+	byFullId := d.nodesByFullId()
+
+	kept := map[*Node]struct{}{}
+	var keep func(n *Node)
+	keep = func(n *Node) {
+		for cur := n; cur != nil; cur = d.parentOf(cur) {
+			kept[cur] = struct{}{}
+		}
+	}
+	if root, ok := d.nodes[d.ID]; ok {
+		keep(root)
+	}
+	for _, node := range d.nodes {
+		if keepNode(node) {
+			keep(node)
+		}
+	}
+
+	var rels []Relationship
+	for _, rel := range d.relationships {
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd || rel.Type == RelBelongsTo || !keepRel(rel) {
+			continue
+		}
+		keep(start)
+		keep(end)
+		rels = append(rels, rel)
+	}
+
+	sub := d.emptyCopy()
+	for node := range kept {
+		copied := *node
+		copied.design = sub
+		sub.nodes[copied.ID] = &copied
+	}
+	for _, rel := range d.relationships {
+		if rel.Type != RelBelongsTo {
+			continue
+		}
+		start, end := byFullId[rel.StartID], byFullId[rel.EndID]
+		_, okStart := kept[start]
+		_, okEnd := kept[end]
+		if okStart && okEnd {
+			sub.relationships = append(sub.relationships, rel)
+		}
+	}
+	sub.relationships = append(sub.relationships, rels...)
+	return sub
+}
+
+// emptyCopy returns a design with the identity and settings of d but no nodes
+// or relationships.
+func (d *Design) emptyCopy() *Design {
+	return &Design{
+		ID:                  d.ID,
+		Name:                d.Name,
+		Description:         d.Description,
+		Version:             d.Version,
+		nodes:               map[string]*Node{},
+		defaultDescription:  d.defaultDescription,
+		duplicatePolicy:     d.duplicatePolicy,
+		log:                 d.log,
+		labelPolicy:         d.labelPolicy,
+		customKinds:         d.customKinds,
+		impliedUseDisabled:  d.impliedUseDisabled,
+		inScope:             d.inScope,
+		dynamicViews:        d.dynamicViews,
+		customViews:         d.customViews,
+		strictReferences:    d.strictReferences,
+		describe:            d.describe,
+		requireDescriptions: d.requireDescriptions,
+		tagDefaults:         d.tagDefaults,
+		meta:                maps.Clone(d.meta),
+		references:          slices.Clone(d.references),
+	}
+}
+
+// FilterByTechnology returns the subgraph of the elements and relationships
+// whose technology contains tech, ignoring case: the matching elements, the
+// matching relationships and the relationships between matching elements,
+// together with their endpoints and ancestors so the hierarchy still renders.
+// Combined with an exporter such as ToMermaid, this produces protocol-specific
+// views such as "everything using gRPC". The receiver is not modified.
+func (d *Design) FilterByTechnology(tech string) *Design {
+	matches := func(technology string) bool {
+		return technology != "" && strings.Contains(strings.ToLower(technology), strings.ToLower(tech))
+	}
+	byFullId := d.nodesByFullId()
+	nodeMatches := func(id string) bool {
+		node, ok := byFullId[id]
+		return ok && matches(node.Technology)
+	}
+	return d.subgraph(
+		func(n *Node) bool { return matches(n.Technology) },
+		func(rel Relationship) bool {
+			return matches(rel.Technology) || (nodeMatches(rel.StartID) && nodeMatches(rel.EndID))
+		},
+	)
+}
+
+// depth returns the C4 level of n: 1 for persons and systems, 2 for containers,
+// 3 for components and 4 for code elements. Other nodes sit one level below
+// their parent.
+func (d *Design) depth(n *Node) int {
+	switch n.NodeType {
+	case NodeTypeDesign:
+		return 0
+	case NodeTypePerson, NodeTypePersonGroup, NodeTypeSystem:
+		return 1
+	case NodeTypeContainer:
+		return 2
+	case NodeTypeComponent:
+		return 3
+	case NodeTypeCode:
+		return 4
+	}
+	if parent := d.parentOf(n); parent != nil {
+		return d.depth(parent) + 1
+	}
+	return 1
+}
+
+// AtLevel returns the design pruned below the C4 level of the given node type,
+// e.g. NodeTypeSystem for a System Context view with only persons and systems.
+// Relationships of pruned elements are rolled up to their closest surviving
+// ancestors; those ending up within a single element are dropped and the
+// resulting duplicates merged. The receiver is not modified.
+func (d *Design) AtLevel(level NodeType) *Design {
+	maxDepth := d.depth(&Node{NodeType: level})
+	byFullId := d.nodesByFullId()
+
+	// surviving returns n or its closest ancestor at or above the level
+	surviving := func(n *Node) *Node {
+		for cur := n; cur != nil; cur = d.parentOf(cur) {
+			if d.depth(cur) <= maxDepth {
+				return cur
+			}
+		}
+		return nil
+	}
+
+	pruned := d.emptyCopy()
+	for _, node := range d.nodes {
+		if d.depth(node) <= maxDepth {
+			copied := *node
+			copied.design = pruned
+			pruned.nodes[copied.ID] = &copied
+		}
+	}
+
+	seen := map[string]struct{}{}
+	for _, rel := range d.relationships {
+		start, okStart := byFullId[rel.StartID]
+		end, okEnd := byFullId[rel.EndID]
+		if !okStart || !okEnd {
+			continue
+		}
+		from, to := surviving(start), surviving(end)
+		if from == nil || to == nil || from == to {
+			continue
+		}
+		if rel.Type == RelBelongsTo && (from != start || to != end) {
+			continue
+		}
+		rolled := rel
+		if from != start || to != end {
+			source := rel.Ref()
+			if rel.DerivedFrom != nil {
+				source = *rel.DerivedFrom
+			}
+			rolled.StartID, rolled.EndID, rolled.DerivedFrom = from.FullId(), to.FullId(), &source
+		}
+		if _, ok := seen[rolled.Key()]; ok {
+			continue
+		}
+		seen[rolled.Key()] = struct{}{}
+		pruned.relationships = append(pruned.relationships, rolled)
+	}
+	return pruned
+}
+
+// clone returns a deep copy of the design: nodes, relationships and settings.
+// Parents of the copied nodes point to the copies.
+func (d *Design) clone() *Design {
+	c := d.emptyCopy()
+	c.customKinds = maps.Clone(d.customKinds)
+	c.tagDefaults = maps.Clone(d.tagDefaults)
+	c.errs = slices.Clone(d.errs)
+	c.dynamicViews = make([]*DynamicView, 0, len(d.dynamicViews))
+	for _, view := range d.dynamicViews {
+		copied := *view
+		copied.steps = slices.Clone(view.steps)
+		c.dynamicViews = append(c.dynamicViews, &copied)
+	}
+	c.customViews = make([]*CustomView, 0, len(d.customViews))
+	for _, view := range d.customViews {
+		copied := *view
+		copied.include = slices.Clone(view.include)
+		c.customViews = append(c.customViews, &copied)
+	}
+
+	copies := make(map[*Node]*Node, len(d.nodes))
+	for id, node := range d.nodes {
+		copied := *node
+		copied.design = c
+		copied.Tags = slices.Clone(node.Tags)
+		copied.Labels = slices.Clone(node.Labels)
+		copies[node] = &copied
+		c.nodes[id] = &copied
+	}
+	for node, copied := range copies {
+		if parent := d.parentOf(node); parent != nil {
+			copied.ParentNode = copies[parent]
+		}
+	}
+	c.relationships = make([]Relationship, 0, len(d.relationships))
+	for _, rel := range d.relationships {
+		rel.Tags = slices.Clone(rel.Tags)
+		rel.Properties = maps.Clone(rel.Properties)
+		c.relationships = append(c.relationships, rel)
+	}
+	return c
+}
+
+// removeNode deletes n, its descendants and every relationship touching them.
+func (d *Design) removeNode(n *Node) {
+	removed := map[string]struct{}{}
+	for _, node := range d.nodes {
+		if slices.Contains(d.ancestorsOrSelf(node), n) {
+			removed[node.FullId()] = struct{}{}
+		}
+	}
+	removed[n.FullId()] = struct{}{}
+	for id, node := range d.nodes {
+		if _, ok := removed[node.FullId()]; ok {
+			delete(d.nodes, id)
+		}
+	}
+	d.relationships = slices.DeleteFunc(d.relationships, func(rel Relationship) bool {
+		_, start := removed[rel.StartID]
+		_, end := removed[rel.EndID]
+		return start || end
+	})
+	d.hierarchy = nil
+	d.relationshipIndex = nil
+}
+
+// removeRelationship deletes the relationships with the given key.
+func (d *Design) removeRelationship(key string) {
+	d.relationships = slices.DeleteFunc(d.relationships, func(rel Relationship) bool {
+		return rel.Key() == key
+	})
+	d.hierarchy = nil
+	d.relationshipIndex = nil
+}
@@ -0,0 +1,189 @@
+package neoarch
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// -----------------------------------------------------------------------------
+// Pulling manual edits back from Neo4j
+// -----------------------------------------------------------------------------
+
+// PullMode tells PullChangesFromNeo4j what to do with the changes it finds.
+type PullMode int
+
+const (
+	PullReview PullMode = iota // Only report the changes
+	PullApply                  // Apply the changes without conflict to the design
+)
+
+// RemoteChange is a property edited in the database since the design was saved.
+type RemoteChange struct {
+	NodeID string // FullId of the node, or of the start of the relationship
+	// Relationship is the key of the relationship (see Relationship.Key) for a
+	// change of one of its extra properties, "" for a node property.
+	Relationship string
+	// Property is "description", "tags" or "technology" for any node,
+	// "meta_<key>" for the metadata of the design (see Design.SetMeta) and the
+	// key of an extra property for a relationship (see Relationship.Properties).
+	Property string
+	Local    any // Value in the design, nil when unset
+	Remote   any // Value in the database, nil when unset
+	// Conflict is set when the design changed the property too, or when the
+	// database has no saved value to tell which side changed. Conflicts are
+	// never applied.
+	Conflict bool
+	Applied  bool
+}
+
+// PullChangesFromNeo4j detects the properties edited directly in the database
+// (e.g. with Bloom) since the last SaveToNeo4j, so the next save doesn't
+// overwrite them: the descriptions, tags and technologies of the nodes, the
+// metadata of the design and the extra properties of the relationships. Each
+// saved node and relationship keeps the values it was saved with: a property is
+// a remote change when the database value differs from them, and a conflict when
+// the design value does too. Properties only changed in the design are not
+// reported. With PullApply, changes without conflict are copied into the
+// design. Node changes come first, sorted by node FullId, then relationship
+// changes, by relationship. The other relationship fields, such as their
+// technology, are not compared.
+func (d *Design) PullChangesFromNeo4j(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig, mode PullMode) ([]RemoteChange, error) {
+	records, edges, err := queryDesignGraph(ctx, driver, sessConfig, d.ID, d.Version)
+	if err != nil {
+		return nil, err
+	}
+	saved := make(map[string]NodeRecord, len(records))
+	for _, record := range records {
+		saved[record.ID] = record
+	}
+
+	var changes []RemoteChange
+	report := func(change RemoteChange, base any, hasBase bool, equal func(a, b any) bool, apply func()) {
+		if equal(change.Remote, change.Local) || hasBase && equal(change.Remote, base) {
+			return
+		}
+		change.Conflict = !hasBase || !equal(change.Local, base)
+		if mode == PullApply && !change.Conflict {
+			apply()
+			change.Applied = true
+		}
+		changes = append(changes, change)
+	}
+
+	for _, node := range d.sortedNodes() {
+		record, ok := saved[node.FullId()]
+		if !ok {
+			continue
+		}
+		props := record.Properties
+
+		remoteDesc, _ := props["description"].(string)
+		baseDesc, hasBase := props["savedDescription"].(string)
+		report(RemoteChange{NodeID: node.FullId(), Property: "description", Local: node.Description, Remote: remoteDesc},
+			baseDesc, hasBase, sameValue, func() { node.Description = remoteDesc })
+
+		remoteTags := stringList(props["tags"])
+		_, hasBase = props["savedTags"]
+		report(RemoteChange{NodeID: node.FullId(), Property: "tags", Local: slices.Clone(node.Tags), Remote: remoteTags},
+			stringList(props["savedTags"]), hasBase, sameTags, func() { node.Tags = remoteTags })
+
+		remoteTechnology, _ := props["technology"].(string)
+		baseTechnology, hasBase := props["savedTechnology"].(string)
+		report(RemoteChange{NodeID: node.FullId(), Property: "technology", Local: node.Technology, Remote: remoteTechnology},
+			baseTechnology, hasBase, sameValue, func() { node.Technology = remoteTechnology })
+
+		if node.NodeType == NodeTypeDesign && node.ID == d.ID {
+			remote := metaFromProperties(props)
+			base, hasBase := parseSaved[string](props["savedMeta"])
+			for _, key := range unionKeys(d.meta, remote, base) {
+				report(RemoteChange{NodeID: node.FullId(), Property: metaPrefix + key, Local: entry(d.meta, key), Remote: entry(remote, key)},
+					entry(base, key), hasBase, sameValue, func() { d.SetMeta(key, remote[key]) })
+			}
+		}
+	}
+
+	remoteEdges := make(map[string]EdgeRecord, len(edges))
+	for _, edge := range edges {
+		description, _ := edge.Properties["description"].(string)
+		remoteEdges[Relationship{StartID: edge.StartID, EndID: edge.EndID, Type: RelationshipType(edge.Type), Description: description}.Key()] = edge
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, sorted := range sortedRelationships(d.relationships) {
+		key := sorted.Key()
+		edge, ok := remoteEdges[key]
+		if !ok {
+			continue
+		}
+		delete(remoteEdges, key) // duplicates share the stored relationship
+		rel := &d.relationships[d.relIndex().byKey[key]]
+		local, remote := relationshipPropertiesFrom(rel.Properties), relationshipPropertiesFrom(edge.Properties)
+		base, hasBase := parseSaved[any](edge.Properties["savedProperties"])
+		for _, prop := range unionKeys(local, remote, base) {
+			report(RemoteChange{NodeID: rel.StartID, Relationship: key, Property: prop, Local: entry(local, prop), Remote: entry(remote, prop)},
+				entry(base, prop), hasBase, sameJSON, func() {
+					if value, ok := remote[prop]; ok {
+						d.setPropertyLocked(rel, prop, value)
+					} else {
+						delete(rel.Properties, prop)
+					}
+				})
+		}
+	}
+	return changes, nil
+}
+
+// savedJSON encodes the values a map-valued property was saved with, such as
+// the metadata of the design, since Neo4j can't store maps.
+func savedJSON[V any](m map[string]V) string {
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// parseSaved decodes a map saved by savedJSON, reporting whether there was one.
+func parseSaved[V any](saved any) (map[string]V, bool) {
+	s, ok := saved.(string)
+	if !ok {
+		return nil, false
+	}
+	var m map[string]V
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// unionKeys returns the keys of the maps, sorted.
+func unionKeys[V any](ms ...map[string]V) []string {
+	keys := map[string]struct{}{}
+	for _, m := range ms {
+		for key := range m {
+			keys[key] = struct{}{}
+		}
+	}
+	return slices.Sorted(maps.Keys(keys))
+}
+
+// entry returns the value of key in m, or nil when it is not set.
+func entry[V any](m map[string]V, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return nil
+}
+
+func sameValue(a, b any) bool { return a == b }
+
+func sameTags(a, b any) bool { return slices.Equal(a.([]string), b.([]string)) }
+
+// sameJSON compares property values by their JSON encoding, since the driver
+// returns int64 and []any for the int and []string saved by the design.
+func sameJSON(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
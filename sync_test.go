@@ -0,0 +1,131 @@
+package neoarch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// graphRecord returns a row of the design graph query: a saved node, and one of
+// its relationships with the id of its end, if any.
+func graphRecord(labels []string, props map[string]any, rel *neo4j.Relationship, endID string) *neo4j.Record {
+	record := &neo4j.Record{Keys: []string{"n", "r", "endID"}, Values: []any{neo4j.Node{Labels: labels, Props: props}, nil, nil}}
+	if rel != nil {
+		record.Values[1], record.Values[2] = *rel, endID
+	}
+	return record
+}
+
+func TestPullChangesFromNeo4j(t *testing.T) {
+	newDesign := func() *Design {
+		d := newShopDesign()
+		d.SetMeta("team", "core")
+		d.AddRelationshipWithProps(d.lookupNode("Shop.Shop.Web"), d.lookupNode("Shop.Shop.API"), RelUses, "Calls", map[string]any{"sla_ms": 200, "auth": "jwt"})
+		// Changed in the design since it was saved
+		d.lookupNode("Shop.Shop.DB").Technology = "Spanner"
+		d.lookupNode("Payments").Description = "Takes card payments"
+		return d
+	}
+	records := []*neo4j.Record{
+		// Edited in the database: the description of Payments, only changed
+		// in the design, is not
+		graphRecord([]string{"Container"}, map[string]any{
+			"id": "Shop.Shop.API", "description": "Order API", "savedDescription": "Backend",
+			"tags": []any{}, "savedTags": []any{}, "technology": "Go", "savedTechnology": "Go",
+		}, nil, ""),
+		graphRecord([]string{"Container"}, map[string]any{
+			"id": "Shop.Shop.DB", "description": "Orders", "savedDescription": "Orders",
+			"tags": []any{}, "savedTags": []any{}, "technology": "MySQL", "savedTechnology": "Postgres",
+		}, nil, ""),
+		graphRecord([]string{"Container"}, map[string]any{
+			"id": "Shop.Shop.Web", "description": "Storefront", "savedDescription": "Storefront",
+			"tags": []any{"frontend"}, "technology": "React", "savedTechnology": "React",
+		}, &neo4j.Relationship{Type: "USES", Props: map[string]any{
+			"description": "Calls", "sla_ms": int64(150), "savedProperties": `{"auth":"jwt","sla_ms":200}`,
+		}}, "Shop.Shop.API"),
+		graphRecord([]string{"Design"}, map[string]any{
+			"id": "design_Shop", "description": "Online shop", "savedDescription": "Online shop",
+			"tags": []any{}, "savedTags": []any{}, "savedTechnology": "",
+			"meta_team": "platform", "meta_owner": "alice", "savedMeta": `{"team":"core"}`,
+		}, nil, ""),
+		graphRecord([]string{"System"}, map[string]any{
+			"id": "Payments", "description": "Takes payments", "savedDescription": "Takes payments",
+			"tags": []any{}, "savedTags": []any{}, "technology": "", "savedTechnology": "",
+		}, nil, ""),
+	}
+	driver := &stubDriver{records: func(string) []*neo4j.Record { return records }}
+	want := []RemoteChange{
+		{NodeID: "Shop.Shop.API", Property: "description", Local: "Backend", Remote: "Order API"},
+		// Changed on both sides
+		{NodeID: "Shop.Shop.DB", Property: "technology", Local: "Spanner", Remote: "MySQL", Conflict: true},
+		// Saved before the tags were kept: no base to tell which side changed
+		{NodeID: "Shop.Shop.Web", Property: "tags", Local: []string(nil), Remote: []string{"frontend"}, Conflict: true},
+		{NodeID: "design_Shop", Property: "meta_owner", Local: nil, Remote: "alice"},
+		{NodeID: "design_Shop", Property: "meta_team", Local: "core", Remote: "platform"},
+		// Removed from the database, and changed there
+		{NodeID: "Shop.Shop.Web", Relationship: "Shop.Shop.Web|Shop.Shop.API|USES|Calls", Property: "auth", Local: "jwt", Remote: nil},
+		{NodeID: "Shop.Shop.Web", Relationship: "Shop.Shop.Web|Shop.Shop.API|USES|Calls", Property: "sla_ms", Local: 200, Remote: int64(150)},
+	}
+	check := func(t *testing.T, got []RemoteChange, applied bool) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("got %d changes, want %d: %+v", len(got), len(want), got)
+		}
+		for i, change := range got {
+			w := want[i]
+			w.Applied = applied && !w.Conflict
+			if change.NodeID != w.NodeID || change.Relationship != w.Relationship || change.Property != w.Property ||
+				!sameJSON(change.Local, w.Local) || !sameJSON(change.Remote, w.Remote) || change.Conflict != w.Conflict || change.Applied != w.Applied {
+				t.Errorf("change %d = %+v, want %+v", i, change, w)
+			}
+		}
+	}
+	calls := func(d *Design) Relationship {
+		for _, rel := range d.relationships {
+			if rel.StartID == "Shop.Shop.Web" && rel.Description == "Calls" {
+				return rel
+			}
+		}
+		t.Fatal("the relationship is missing")
+		return Relationship{}
+	}
+
+	t.Run("review", func(t *testing.T) {
+		d := newDesign()
+		before := d.Fingerprint()
+		got, err := d.PullChangesFromNeo4j(context.Background(), driver, neo4j.SessionConfig{}, PullReview)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, got, false)
+		if d.Fingerprint() != before {
+			t.Error("reviewing the changes modified the design")
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		d := newDesign()
+		got, err := d.PullChangesFromNeo4j(context.Background(), driver, neo4j.SessionConfig{}, PullApply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, got, true)
+		if desc := d.lookupNode("Shop.Shop.API").Description; desc != "Order API" {
+			t.Errorf("API description = %q, want the database one", desc)
+		}
+		// Conflicts are left alone
+		if tech := d.lookupNode("Shop.Shop.DB").Technology; tech != "Spanner" {
+			t.Errorf("DB technology = %q, want the design one", tech)
+		}
+		if tags := d.lookupNode("Shop.Shop.Web").Tags; len(tags) != 0 {
+			t.Errorf("Web tags = %v, want none", tags)
+		}
+		if d.Meta("team") != "platform" || d.Meta("owner") != "alice" {
+			t.Errorf("metadata = %v, want the database one", d.meta)
+		}
+		if props := calls(d).Properties; props["sla_ms"] != int64(150) || props["auth"] != nil {
+			t.Errorf("relationship properties = %v, want sla_ms 150 only", props)
+		}
+	})
+}
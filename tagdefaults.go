@@ -0,0 +1,88 @@
+package neoarch
+
+import (
+	"fmt"
+	"slices"
+)
+
+// -----------------------------------------------------------------------------
+// Tag defaults
+// -----------------------------------------------------------------------------
+
+// TagDefaults are the attributes implied by a tag, registered with
+// Design.TagDefaults.
+type TagDefaults struct {
+	Technology string       // Used by exporters for nodes without a technology
+	Style      ElementStyle // Applied by the exporters to nodes without an explicit style
+}
+
+// TagDefaults registers the defaults implied by tag, e.g. "postgres" for the
+// PostgreSQL technology and a cylinder, so tagged nodes don't repeat them.
+// Registering a tag again replaces its defaults.
+//
+// The defaults don't change the nodes: exporters read them through
+// DesignView.Technology and DesignView.Style. When several tags of a node
+// imply different defaults, its first tag wins in every exporter and Validate
+// warns about the conflict.
+func (d *Design) TagDefaults(tag string, defaults TagDefaults) *Design {
+	if d.tagDefaults == nil {
+		d.tagDefaults = map[string]TagDefaults{}
+	}
+	d.tagDefaults[tag] = defaults
+	return d
+}
+
+// technologyOf returns the technology of n, or the one implied by its first
+// tag with a default technology.
+func (d *Design) technologyOf(n *Node) string {
+	if n.Technology != "" {
+		return n.Technology
+	}
+	for _, tag := range n.Tags {
+		if defaults, ok := d.tagDefaults[tag]; ok && defaults.Technology != "" {
+			return defaults.Technology
+		}
+	}
+	return ""
+}
+
+// Technology returns the technology exporters should show for n: its own, or
+// the one implied by its tags (see Design.TagDefaults).
+func (v *DesignView) Technology(n *Node) string {
+	return v.design.technologyOf(n)
+}
+
+// validateTagDefaults warns about nodes whose tags imply different
+// technologies or styles.
+func (d *Design) validateTagDefaults() []ValidationIssue {
+	if len(d.tagDefaults) == 0 {
+		return nil
+	}
+	var issues []ValidationIssue
+	for _, node := range d.sortedNodes() {
+		var technologies []string
+		var styles []ElementStyle
+		var tags []string
+		for _, tag := range node.Tags {
+			defaults, ok := d.tagDefaults[tag]
+			if !ok {
+				continue
+			}
+			tags = append(tags, tag)
+			if defaults.Technology != "" && !slices.Contains(technologies, defaults.Technology) {
+				technologies = append(technologies, defaults.Technology)
+			}
+			if !defaults.Style.IsZero() && !slices.Contains(styles, defaults.Style) {
+				styles = append(styles, defaults.Style)
+			}
+		}
+		if (len(technologies) > 1 && node.Technology == "") || (len(styles) > 1 && node.Appearance.IsZero()) {
+			issues = append(issues, ValidationIssue{
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("node %s has tags %q with conflicting defaults; the first one applies", node.FullId(), tags),
+				NodeIDs:  []string{node.FullId()},
+			})
+		}
+	}
+	return issues
+}
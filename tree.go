@@ -0,0 +1,92 @@
+package neoarch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Terminal tree
+// -----------------------------------------------------------------------------
+
+// TreeOptions controls TreeString.
+type TreeOptions struct {
+	// Unicode draws the tree with box-drawing characters instead of ASCII.
+	Unicode bool
+	// ShowUses lists the outgoing USES relationships of every node below it, as
+	// "-> target (description)".
+	ShowUses bool
+}
+
+// TreeString renders the BELONGS_TO hierarchy of the design as an indented
+// tree, for terminals and test failure output. Each line shows the node type,
+// its name, its tags in brackets and "(external)" for external nodes.
+// Top-level nodes are sorted by FullId and children are in the order they were
+// added, so the output is deterministic.
+func (d *Design) TreeString(opts TreeOptions) string {
+	branch, last, pipe, arrow := "+-- ", "`-- ", "|   ", "-> "
+	if opts.Unicode {
+		branch, last, pipe, arrow = "├── ", "└── ", "│   ", "→ "
+	}
+
+	h := d.hierarchyIndex()
+	uses := map[string][]Relationship{}
+	if opts.ShowUses {
+		for _, rel := range sortedRelationships(d.relationships) {
+			if usesLike(rel) {
+				uses[rel.StartID] = append(uses[rel.StartID], rel)
+			}
+		}
+	}
+
+	b := strings.Builder{}
+	var write func(n *Node, prefix string, isLast bool)
+	write = func(n *Node, prefix string, isLast bool) {
+		connector, childPrefix := branch, prefix+pipe
+		if isLast {
+			connector, childPrefix = last, prefix+"    "
+		}
+		fmt.Fprintf(&b, "%s%s%s\n", prefix, connector, treeLabel(n))
+
+		children := h.children[n.FullId()]
+		// Relationships hang below the node, before its children
+		relPrefix := childPrefix + pipe
+		if len(children) == 0 {
+			relPrefix = childPrefix + "    "
+		}
+		for _, rel := range uses[n.FullId()] {
+			target := rel.EndID
+			if end, ok := h.byFullId[rel.EndID]; ok {
+				target = end.FullName()
+			}
+			fmt.Fprintf(&b, "%s%s%s (%s)\n", relPrefix, arrow, target, rel.Description)
+		}
+		for i, child := range children {
+			write(child, childPrefix, i == len(children)-1)
+		}
+	}
+
+	fmt.Fprintf(&b, "[%s] %s\n", NodeTypeDesign, d.Name)
+	var top []*Node
+	for _, node := range d.sortedNodes() {
+		if node.NodeType != NodeTypeDesign && h.parents[node.FullId()] == "" {
+			top = append(top, node)
+		}
+	}
+	for i, node := range top {
+		write(node, "", i == len(top)-1)
+	}
+	return b.String()
+}
+
+// treeLabel returns the line of n in TreeString.
+func treeLabel(n *Node) string {
+	label := fmt.Sprintf("[%s] %s", n.NodeType, n.Name)
+	if len(n.Tags) > 0 {
+		label += " [" + strings.Join(n.Tags, ", ") + "]"
+	}
+	if n.IsExternal {
+		label += " (external)"
+	}
+	return label
+}
@@ -0,0 +1,281 @@
+package neoarch
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Validation
+// -----------------------------------------------------------------------------
+
+// Severity tells how serious a validation issue is.
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// ValidationIssue describes a single problem found by Validate.
+type ValidationIssue struct {
+	Code     string // Kind of problem, e.g. "containment"
+	Severity Severity
+	Message  string
+	NodeIDs  []string // FullIds of the nodes involved
+	Location string   // file:line of the call that declared the first node involved, when known
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+}
+
+// Validate checks the design for modeling problems and returns every issue found.
+// Export options can be passed to also check the problems specific to rendering
+// with them. It does not modify the design.
+func (d *Design) Validate(opts ...ExportOption) []ValidationIssue {
+	view := newViewOptions(opts)
+
+	var issues []ValidationIssue
+	for _, err := range d.errs {
+		issue := ValidationIssue{Code: "build", Severity: SeverityError, Message: err.Error()}
+		if be := (*BuildError)(nil); errors.As(err, &be) {
+			issue.Location = be.Location
+		}
+		issues = append(issues, issue)
+	}
+	issues = append(issues, withCode("design-root", d.validateDesignRoot())...)
+	for _, ref := range d.unresolvedReferences() {
+		issues = append(issues, ValidationIssue{
+			Code:     "unresolved-reference",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("unresolved node reference %q from %s", ref.ID, ref.CallSite),
+			NodeIDs:  []string{ref.ID},
+			Location: ref.CallSite,
+		})
+	}
+	issues = append(issues, withCode("containment", d.validateContainment())...)
+	issues = append(issues, withCode("relationship-description", d.validateRelationshipDescriptions())...)
+	issues = append(issues, withCode("external-description", d.validateExternalDescriptions())...)
+	issues = append(issues, withCode("tag-defaults", d.validateTagDefaults())...)
+	issues = append(issues, withCode("person-name", d.validatePersonNames())...)
+	if view.CollapsePersonGroups {
+		issues = append(issues, withCode("person-group", d.validatePersonGroupMembership())...)
+	}
+
+	byFullId := d.nodesByFullId()
+	for i, issue := range issues {
+		if issue.Location == "" && len(issue.NodeIDs) > 0 {
+			if node, ok := byFullId[issue.NodeIDs[0]]; ok {
+				issues[i].Location = node.Location()
+			}
+		}
+	}
+	return issues
+}
+
+// withCode sets the code of the issues found by one check.
+func withCode(code string, issues []ValidationIssue) []ValidationIssue {
+	for i := range issues {
+		issues[i].Code = code
+	}
+	return issues
+}
+
+// validateDesignRoot checks that the design has its own Design node and no
+// other, e.g. one added by NodeReference or left over from merging designs.
+// Exporters always use the node whose ID is the design ID.
+func (d *Design) validateDesignRoot() []ValidationIssue {
+	var issues []ValidationIssue
+	if _, ok := d.nodes[d.ID]; !ok {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s: %s", ErrDesignNodeNotFound, d.ID),
+		})
+	}
+	for _, node := range d.sortedNodes() {
+		if node.NodeType == NodeTypeDesign && node.ID != d.ID {
+			issues = append(issues, ValidationIssue{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("node %s is a second Design node; the design root is %s", node.FullId(), d.ID),
+				NodeIDs:  []string{node.FullId()},
+			})
+		}
+	}
+	return issues
+}
+
+// validateContainment checks that BELONGS_TO edges form a tree: nested nodes
+// have exactly one parent, top-level nodes at most one, and there are no cycles.
+func (d *Design) validateContainment() []ValidationIssue {
+	byFullId := d.nodesByFullId()
+
+	parents := map[*Node][]string{}
+	dependsOn := map[*Node]map[*Node]struct{}{}
+	for _, rel := range d.relationships {
+		if rel.Type != RelBelongsTo {
+			continue
+		}
+		child, ok := byFullId[rel.StartID]
+		if !ok {
+			continue
+		}
+		parents[child] = append(parents[child], rel.EndID)
+		if parent, ok := byFullId[rel.EndID]; ok {
+			if dependsOn[child] == nil {
+				dependsOn[child] = map[*Node]struct{}{}
+			}
+			dependsOn[child][parent] = struct{}{}
+		}
+	}
+
+	nodes := make([]*Node, 0, len(d.nodes))
+	all := make(map[*Node]int, len(d.nodes))
+	for _, node := range d.nodes {
+		nodes = append(nodes, node)
+		all[node] = 0
+	}
+	sortNodes(nodes)
+
+	var issues []ValidationIssue
+	for _, node := range nodes {
+		switch {
+		case len(parents[node]) > 1:
+			issues = append(issues, ValidationIssue{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("node %s belongs to several parents: %s", node.FullId(), strings.Join(parents[node], ", ")),
+				NodeIDs:  append([]string{node.FullId()}, parents[node]...),
+			})
+		case len(parents[node]) == 0 && node.ParentNode != nil:
+			issues = append(issues, ValidationIssue{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("node %s is nested under %s but has no BELONGS_TO relationship", node.FullId(), node.ParentNode.FullId()),
+				NodeIDs:  []string{node.FullId()},
+			})
+		}
+		if _, ok := dependsOn[node][node]; ok {
+			issues = append(issues, ValidationIssue{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("node %s belongs to itself", node.FullId()),
+				NodeIDs:  []string{node.FullId()},
+			})
+		}
+	}
+
+	if cycle := cycleMembers(all, dependsOn); len(cycle) > 0 {
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("BELONGS_TO relationships form a cycle between: %s", strings.Join(cycle, ", ")),
+			NodeIDs:  cycle,
+		})
+	}
+	return issues
+}
+
+// validateRelationshipDescriptions warns about relationships without a description,
+// which render as blank edge labels.
+func (d *Design) validateRelationshipDescriptions() []ValidationIssue {
+	var issues []ValidationIssue
+	for _, rel := range d.relationships {
+		if rel.Description != "" {
+			continue
+		}
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%s relationship %s -> %s has an empty description", rel.Type, rel.StartID, rel.EndID),
+			NodeIDs:  []string{rel.StartID, rel.EndID},
+			Location: rel.Location(),
+		})
+	}
+	return issues
+}
+
+// validateExternalDescriptions warns about external nodes without a description:
+// they stand for third parties, which readers of the design know least about.
+// It is the Validate counterpart of LintExternalDescription.
+func (d *Design) validateExternalDescriptions() []ValidationIssue {
+	var issues []ValidationIssue
+	for _, node := range d.sortedNodes() {
+		if LintExternalDescription.Node(d, node) {
+			continue
+		}
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("external %s %s has an empty description; describe what the third party provides so reviewers have context",
+				strings.ToLower(string(node.NodeType)), node.FullId()),
+			NodeIDs: []string{node.FullId()},
+		})
+	}
+	return issues
+}
+
+// validatePersonGroupMembership flags persons that are members of more than one
+// PersonGroup, since collapsing groups can only re-point them to one of them.
+func (d *Design) validatePersonGroupMembership() []ValidationIssue {
+	groups := map[string][]string{}
+	var persons []string
+	for _, rel := range d.relationships {
+		if rel.Type != RelMemberOf {
+			continue
+		}
+		if _, ok := groups[rel.StartID]; !ok {
+			persons = append(persons, rel.StartID)
+		}
+		groups[rel.StartID] = append(groups[rel.StartID], rel.EndID)
+	}
+
+	var issues []ValidationIssue
+	for _, person := range persons {
+		if len(groups[person]) < 2 {
+			continue
+		}
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("person %s is a member of several groups (%s); it is collapsed into the first one", person, strings.Join(groups[person], ", ")),
+			NodeIDs:  append([]string{person}, groups[person]...),
+		})
+	}
+	return issues
+}
+
+// personNameClashes returns the persons among nodes sharing their name with
+// another, by name, in the order of nodes. Only persons scoped to different
+// systems (see System.Person) or to a system and the design root can share a
+// name.
+func personNameClashes(nodes []*Node) map[string][]*Node {
+	byName := map[string][]*Node{}
+	for _, node := range nodes {
+		if node.NodeType == NodeTypePerson {
+			byName[node.Name] = append(byName[node.Name], node)
+		}
+	}
+	for name, persons := range byName {
+		if len(persons) < 2 {
+			delete(byName, name)
+		}
+	}
+	return byName
+}
+
+// validatePersonNames checks that persons have distinct names, which
+// Structurizr requires: the exporter qualifies the names of the scoped ones
+// with their system.
+func (d *Design) validatePersonNames() []ValidationIssue {
+	clashes := personNameClashes(d.sortedNodes())
+	var issues []ValidationIssue
+	for _, name := range slices.Sorted(maps.Keys(clashes)) {
+		var ids []string
+		for _, person := range clashes[name] {
+			ids = append(ids, person.FullId())
+		}
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("persons %s share the name %q", strings.Join(ids, ", "), name),
+			NodeIDs:  ids,
+		})
+	}
+	return issues
+}
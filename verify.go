@@ -0,0 +1,126 @@
+package neoarch
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// -----------------------------------------------------------------------------
+// Verification of a saved design
+// -----------------------------------------------------------------------------
+
+// PropertyMismatch is a node property whose saved value differs from the model.
+type PropertyMismatch struct {
+	NodeID   string // FullId of the node
+	Property string
+	Want     any
+	Got      any
+}
+
+func (m PropertyMismatch) String() string {
+	return fmt.Sprintf("%s: %s is %v, want %v", m.NodeID, m.Property, m.Got, m.Want)
+}
+
+// VerificationReport lists the differences between a design and what is saved
+// in Neo4j. Extra data in the database is not reported.
+type VerificationReport struct {
+	MissingNodes         []string // FullIds of the nodes not found
+	MissingRelationships []Relationship
+	Mismatches           []PropertyMismatch
+}
+
+// Clean reports whether the database matches the design.
+func (r *VerificationReport) Clean() bool {
+	return len(r.MissingNodes) == 0 && len(r.MissingRelationships) == 0 && len(r.Mismatches) == 0
+}
+
+func (r *VerificationReport) String() string {
+	b := strings.Builder{}
+	for _, id := range r.MissingNodes {
+		fmt.Fprintf(&b, "missing node %s\n", id)
+	}
+	for _, rel := range r.MissingRelationships {
+		fmt.Fprintf(&b, "missing relationship %s -[%s]-> %s (%q)\n", rel.StartID, rel.Type, rel.EndID, rel.Description)
+	}
+	for _, m := range r.Mismatches {
+		fmt.Fprintf(&b, "%s\n", m)
+	}
+	return b.String()
+}
+
+// VerificationError is returned by SaveToNeo4j with WithVerify when the saved
+// design doesn't match the model.
+type VerificationError struct {
+	Report *VerificationReport
+}
+
+func (e *VerificationError) Error() string {
+	r := e.Report
+	return fmt.Sprintf("design not saved as modeled: %d missing nodes, %d missing relationships, %d property mismatches",
+		len(r.MissingNodes), len(r.MissingRelationships), len(r.Mismatches))
+}
+
+// VerifyInNeo4j re-reads the design from the database and compares it with the
+// in-memory model: every node must exist with the same name, description and
+// tags, and every relationship must exist between the saved nodes with the same
+// type and description. It only reads from the database.
+func (d *Design) VerifyInNeo4j(ctx context.Context, driver neo4j.DriverWithContext, sessConfig neo4j.SessionConfig) (*VerificationReport, error) {
+	records, edges, err := queryDesignGraph(ctx, driver, sessConfig, d.ID, d.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := make(map[string]NodeRecord, len(records))
+	for _, record := range records {
+		saved[record.ID] = record
+	}
+	savedRels := make(map[string]struct{}, len(edges))
+	for _, edge := range edges {
+		desc, _ := edge.Properties["description"].(string)
+		savedRels[Relationship{StartID: edge.StartID, EndID: edge.EndID, Type: RelationshipType(edge.Type), Description: desc}.Key()] = struct{}{}
+	}
+
+	report := &VerificationReport{}
+	for _, node := range d.sortedNodes() {
+		record, ok := saved[node.FullId()]
+		if !ok {
+			report.MissingNodes = append(report.MissingNodes, node.FullId())
+			continue
+		}
+		mismatch := func(property string, want, got any) {
+			report.Mismatches = append(report.Mismatches, PropertyMismatch{NodeID: node.FullId(), Property: property, Want: want, Got: got})
+		}
+		if name, _ := record.Properties["name"].(string); name != node.Name {
+			mismatch("name", node.Name, record.Properties["name"])
+		}
+		if desc, _ := record.Properties["description"].(string); desc != node.Description {
+			mismatch("description", node.Description, record.Properties["description"])
+		}
+		if tags := stringList(record.Properties["tags"]); !slices.Equal(tags, node.Tags) && len(tags)+len(node.Tags) > 0 {
+			mismatch("tags", node.Tags, tags)
+		}
+	}
+
+	for _, rel := range d.relationships {
+		if _, ok := savedRels[rel.Key()]; !ok {
+			report.MissingRelationships = append(report.MissingRelationships, rel)
+		}
+	}
+	return report, nil
+}
+
+// stringList converts a list property read from Neo4j to a []string.
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package neoarch
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// -----------------------------------------------------------------------------
+// Versioned designs
+// -----------------------------------------------------------------------------
+
+// LoadFromNeo4j rebuilds the unversioned design saved with the given id. See
+// LoadFromNeo4jVersion.
+func LoadFromNeo4j(ctx context.Context, driver neo4j.DriverWithContext, designID string) (*Design, error) {
+	return LoadFromNeo4jVersion(ctx, driver, designID, "")
+}
+
+// LoadFromNeo4jVersion rebuilds a snapshot of a design saved by SaveToNeo4j
+// with Design.Version set to version. Saving a design under several versions
+// keeps one subgraph per version, told apart by the version property of their
+// nodes, so snapshots can be loaded side by side and compared, e.g. with Diff.
+// Like DeleteFromNeo4j, it reads the "neo4j" database.
+//
+// The design gets back what the database holds: nodes with their properties
+// and parents, the design metadata (see SetMeta), and explicit relationships
+// with their attributes. Scenarios, implied relationships, styles, custom
+// kinds and design settings are not saved, and so not restored. It fails with
+// ErrDesignNodeNotFound when nothing was saved under that id and version, and
+// with a *SchemaVersionError when the design was saved with a newer schema
+// (see SchemaVersion).
+func LoadFromNeo4jVersion(ctx context.Context, driver neo4j.DriverWithContext, designID, version string) (*Design, error) {
+	records, edges, err := queryDesignGraph(ctx, driver, neo4j.SessionConfig{DatabaseName: "neo4j"}, designID, version)
+	if err != nil {
+		return nil, err
+	}
+	return designFromRecords(designID, version, records, edges)
+}
+
+// designFromRecords rebuilds a design from the records of queryDesignGraph.
+func designFromRecords(designID, version string, records []NodeRecord, edges []EdgeRecord) (*Design, error) {
+	text := func(props map[string]any, key string) string {
+		s, _ := props[key].(string)
+		return s
+	}
+
+	d := &Design{ID: designID, Version: version, nodes: map[string]*Node{}}
+	byFullId := map[string]*Node{}
+	var rootProps map[string]any
+	for _, record := range records {
+		props := record.Properties
+		if record.ID == designID {
+			rootProps = props
+		}
+		// Scenario nodes and the nodes scenarios add are not part of the design
+		if slices.Contains(record.Labels, "Scenario") || props["scenario"] != nil {
+			continue
+		}
+		nodeType := NodeType(text(props, "nodeType"))
+		node := &Node{
+			ID:          record.ID,
+			Name:        text(props, "name"),
+			Description: text(props, "description"),
+			NodeType:    nodeType,
+			Technology:  text(props, "technology"),
+			Snippet:     CodeSnippet{Language: text(props, "codeLanguage"), Code: text(props, "codeSnippet")},
+			Layout: LayoutHint{
+				Rank:              LayoutRank(text(props, "layoutRank")),
+				Group:             text(props, "layoutGroup"),
+				PreferredPosition: text(props, "layoutPosition"),
+			},
+			design: d,
+		}
+		node.IsExternal, _ = props["external"].(bool)
+		node.Deprecated, _ = props["deprecated"].(bool)
+		node.DeprecationReason = text(props, "deprecationReason")
+		if tags := stringList(props["tags"]); len(tags) > 0 {
+			node.Tags = tags
+		}
+		for _, label := range record.Labels {
+			if label != string(nodeType) {
+				node.Labels = append(node.Labels, label)
+			}
+		}
+		byFullId[record.ID] = node
+	}
+
+	root, ok := byFullId[designID]
+	if !ok || root.NodeType != NodeTypeDesign {
+		if version != "" {
+			return nil, fmt.Errorf("%w: %s version %s", ErrDesignNodeNotFound, designID, version)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrDesignNodeNotFound, designID)
+	}
+	if err := checkSchemaVersion(designID, rootProps); err != nil {
+		return nil, err
+	}
+	d.Name, d.Description = root.Name, root.Description
+	d.meta = metaFromProperties(rootProps)
+
+	// A node belongs to the parent whose FullId prefixes its own; other
+	// BELONGS_TO relationships are kept as plain relationships.
+	for _, edge := range edges {
+		child, okChild := byFullId[edge.StartID]
+		parent, okParent := byFullId[edge.EndID]
+		if !okChild || !okParent || RelationshipType(edge.Type) != RelBelongsTo || child.ParentNode != nil {
+			continue
+		}
+		if strings.HasPrefix(edge.StartID, edge.EndID+".") {
+			child.ParentNode = parent
+			child.ID = strings.TrimPrefix(edge.StartID, edge.EndID+".")
+		}
+	}
+	for _, node := range byFullId {
+		d.nodes[node.ID] = node
+	}
+
+	for _, edge := range edges {
+		_, okStart := byFullId[edge.StartID]
+		_, okEnd := byFullId[edge.EndID]
+		props := edge.Properties
+		if !okStart || !okEnd || RelationshipType(edge.Type) == RelImpliedUse || props["scenario"] != nil {
+			continue
+		}
+		rel := Relationship{
+			StartID:          edge.StartID,
+			EndID:            edge.EndID,
+			Type:             RelationshipType(edge.Type),
+			Description:      text(props, "description"),
+			Technology:       text(props, "technology"),
+			InteractionStyle: InteractionStyle(text(props, "interactionStyle")),
+		}
+		if tags := stringList(props["tags"]); len(tags) > 0 {
+			rel.Tags = tags
+		}
+		if weight, ok := props["weight"].(int64); ok {
+			rel.Weight = int(weight)
+		}
+		rel.Optional, _ = props["optional"].(bool)
+		rel.Properties = relationshipPropertiesFrom(props)
+		d.relationships = append(d.relationships, rel)
+	}
+	d.hierarchy = nil
+	d.relationshipIndex = nil
+	return d, nil
+}
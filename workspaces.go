@@ -0,0 +1,70 @@
+package neoarch
+
+import (
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Workspace composition
+// -----------------------------------------------------------------------------
+
+// ToStructurizrDSLExtending is like ToStructurizrDSL but renders a workspace
+// extending the one at parentWorkspacePath, for designs kept in several
+// repositories: each repository renders the elements, relationships and views
+// of its own design, on top of the shared workspace. The landscape view is
+// left to the parent workspace, which Structurizr would reject as a duplicate.
+// Check IdentifierCollisions first, since identifiers of both workspaces
+// share one namespace.
+func (d *Design) ToStructurizrDSLExtending(parentWorkspacePath string, opts ...ExportOption) string {
+	return d.ToStructurizrDSL(append(opts, ExtendWorkspace(parentWorkspacePath))...)
+}
+
+// ComposeWorkspaces renders a root Structurizr workspace whose model includes
+// the DSL files at paths with !include, and that shows them all in a landscape
+// view. Structurizr inlines included files in the model block, so they must
+// hold model fragments: elements and relationships, not whole workspaces.
+func ComposeWorkspaces(paths []string) string {
+	b := strings.Builder{}
+	w := newDSLWriter(&b)
+	w.open("workspace")
+	w.line("!identifiers hierarchical")
+	w.line("")
+	w.open("model")
+	for _, path := range paths {
+		w.line("!include %s", path)
+	}
+	w.close()
+	w.line("")
+	w.open("views")
+	w.open(`systemLandscape "landscape"`)
+	w.line("include *")
+	w.line("autolayout lr")
+	w.close()
+	w.close()
+	w.close()
+	w.flush()
+	return b.String()
+}
+
+// IdentifierCollisions returns the workspace-level Structurizr identifiers of
+// the design, those of its persons and software systems, that start with one of
+// the reserved prefixes, sorted. Designs rendered into one workspace, e.g. with
+// ToStructurizrDSLExtending, can each be given a prefix to keep their
+// identifiers apart; a design using another design's prefix collides with it.
+func (d *Design) IdentifierCollisions(reservedPrefixes []string) []string {
+	var collisions []string
+	for _, node := range d.sortedNodes() {
+		switch node.NodeType {
+		case NodeTypePerson, NodeTypePersonGroup, NodeTypeSystem:
+		default:
+			continue
+		}
+		id := sanitizeIdentifier(node.ID)
+		if slices.ContainsFunc(reservedPrefixes, func(prefix string) bool { return prefix != "" && strings.HasPrefix(id, prefix) }) {
+			collisions = append(collisions, id)
+		}
+	}
+	slices.Sort(collisions)
+	return slices.Compact(collisions)
+}
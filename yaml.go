@@ -0,0 +1,508 @@
+package neoarch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// -----------------------------------------------------------------------------
+// YAML model files
+// -----------------------------------------------------------------------------
+
+// YAMLModel is the schema of the YAML model files read by ParseYAML. It mirrors
+// the DSL: systems hold subsystems and containers, containers hold nested
+// containers and components. Relationships reference elements by their path of
+// names separated by "/", e.g. "UserSystem/User gRPC Service/Handler"; persons
+// are referenced by name.
+//
+//	name: Twitter Clone
+//	description: Social + User Systems
+//	meta:
+//	  team: platform
+//	persons:
+//	  - name: User
+//	    description: Any internet user
+//	    external: true
+//	systems:
+//	  - name: UserSystem
+//	    description: Handles user management
+//	    containers:
+//	      - name: User gRPC Service
+//	        technology: Go/gRPC
+//	        tags: [grpc]
+//	        components:
+//	          - name: Handler
+//	relationships:
+//	  - from: User
+//	    to: UserSystem/User gRPC Service
+//	    description: Signs up
+//	    technology: HTTPS   # optional
+//	    type: USES          # optional: USES (default), INTERACTS_WITH or a custom type
+//	    properties:         # optional, see Design.AddRelationshipWithProps
+//	      sla_ms: 200
+//
+// Every element also takes deprecated and deprecationReason (see Node.Deprecate).
+type YAMLModel struct {
+	Name          string             `yaml:"name"`
+	Description   string             `yaml:"description,omitempty"`
+	Version       string             `yaml:"version,omitempty"` // See Design.Version
+	Meta          map[string]string  `yaml:"meta,omitempty"`    // See Design.SetMeta
+	External      bool               `yaml:"external,omitempty"`
+	Tags          []string           `yaml:"tags,omitempty"`
+	Persons       []YAMLPerson       `yaml:"persons,omitempty"`
+	Systems       []YAMLSystem       `yaml:"systems,omitempty"`
+	Relationships []YAMLRelationship `yaml:"relationships,omitempty"`
+}
+
+// YAMLPerson is a person of a YAMLModel.
+type YAMLPerson struct {
+	Name              string   `yaml:"name"`
+	Description       string   `yaml:"description,omitempty"`
+	External          bool     `yaml:"external,omitempty"`
+	Tags              []string `yaml:"tags,omitempty"`
+	Deprecated        bool     `yaml:"deprecated,omitempty"`
+	DeprecationReason string   `yaml:"deprecationReason,omitempty"`
+}
+
+// YAMLSystem is a system, or a subsystem, of a YAMLModel.
+type YAMLSystem struct {
+	ID                string          `yaml:"id,omitempty"` // Only for top-level systems, defaults to the name
+	Name              string          `yaml:"name"`
+	Description       string          `yaml:"description,omitempty"`
+	External          bool            `yaml:"external,omitempty"`
+	Tags              []string        `yaml:"tags,omitempty"`
+	Deprecated        bool            `yaml:"deprecated,omitempty"`
+	DeprecationReason string          `yaml:"deprecationReason,omitempty"`
+	Systems           []YAMLSystem    `yaml:"systems,omitempty"`
+	Containers        []YAMLContainer `yaml:"containers,omitempty"`
+}
+
+// YAMLContainer is a container, or a nested container, of a YAMLModel.
+type YAMLContainer struct {
+	Name              string          `yaml:"name"`
+	Description       string          `yaml:"description,omitempty"`
+	Technology        string          `yaml:"technology,omitempty"`
+	External          bool            `yaml:"external,omitempty"`
+	Tags              []string        `yaml:"tags,omitempty"`
+	Deprecated        bool            `yaml:"deprecated,omitempty"`
+	DeprecationReason string          `yaml:"deprecationReason,omitempty"`
+	Containers        []YAMLContainer `yaml:"containers,omitempty"`
+	Components        []YAMLComponent `yaml:"components,omitempty"`
+}
+
+// YAMLComponent is a component of a YAMLModel.
+type YAMLComponent struct {
+	ID                string   `yaml:"id,omitempty"` // Defaults to the name
+	Name              string   `yaml:"name"`
+	Description       string   `yaml:"description,omitempty"`
+	Technology        string   `yaml:"technology,omitempty"`
+	External          bool     `yaml:"external,omitempty"`
+	Tags              []string `yaml:"tags,omitempty"`
+	Deprecated        bool     `yaml:"deprecated,omitempty"`
+	DeprecationReason string   `yaml:"deprecationReason,omitempty"`
+	Language          string   `yaml:"language,omitempty"` // Language of the snippet
+	Snippet           string   `yaml:"snippet,omitempty"`  // See Component.Snippet
+}
+
+// YAMLRelationship is a relationship of a YAMLModel.
+type YAMLRelationship struct {
+	From        string         `yaml:"from"`
+	To          string         `yaml:"to"`
+	Description string         `yaml:"description,omitempty"`
+	Type        string         `yaml:"type,omitempty"`
+	Technology  string         `yaml:"technology,omitempty"`
+	Tags        []string       `yaml:"tags,omitempty"`
+	Interaction string         `yaml:"interaction,omitempty"` // Synchronous or Asynchronous
+	Weight      int            `yaml:"weight,omitempty"`
+	Optional    bool           `yaml:"optional,omitempty"`
+	Properties  map[string]any `yaml:"properties,omitempty"`
+}
+
+// ParseYAML reads a model file following the YAMLModel schema and compiles it
+// into a Design. Unknown keys are errors rather than being ignored.
+func ParseYAML(r io.Reader) (*Design, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var model YAMLModel
+	if err := decoder.Decode(&model); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("yaml: empty model")
+		}
+		return nil, err
+	}
+	return model.Design()
+}
+
+// Design compiles the model into a Design.
+func (m YAMLModel) Design() (*Design, error) {
+	if m.Name == "" {
+		return nil, errors.New("yaml: the model has no name")
+	}
+	d := NewDesign(m.Name, m.Description)
+	d.Version = m.Version
+	for _, key := range slices.Sorted(maps.Keys(m.Meta)) {
+		d.SetMeta(key, m.Meta[key])
+	}
+	for _, tag := range m.Tags {
+		d.Tag(tag)
+	}
+	if m.External {
+		d.External()
+	}
+
+	refs := map[string]INode{}
+	register := func(path string, n INode) error {
+		if _, ok := refs[path]; ok {
+			return fmt.Errorf("yaml: %q is declared twice", path)
+		}
+		refs[path] = n
+		return nil
+	}
+
+	for _, p := range m.Persons {
+		person := d.Person(p.Name, p.Description)
+		applyYAMLNode(person.Node, p.External, p.Tags, "", p.Deprecated, p.DeprecationReason)
+		if err := register(p.Name, person); err != nil {
+			return nil, err
+		}
+	}
+
+	var addContainer func(path string, c *Container, yc YAMLContainer) error
+	addContainer = func(path string, c *Container, yc YAMLContainer) error {
+		applyYAMLNode(c.Node, yc.External, yc.Tags, yc.Technology, yc.Deprecated, yc.DeprecationReason)
+		if err := register(path, c); err != nil {
+			return err
+		}
+		for _, nested := range yc.Containers {
+			if err := addContainer(path+"/"+nested.Name, c.Container(nested.Name, nested.Description), nested); err != nil {
+				return err
+			}
+		}
+		for _, yc := range yc.Components {
+			id := yc.ID
+			if id == "" {
+				id = yc.Name
+			}
+			component := c.ComponentWithId(id, yc.Name, yc.Description)
+			applyYAMLNode(component.Node, yc.External, yc.Tags, yc.Technology, yc.Deprecated, yc.DeprecationReason)
+			component.Node.Snippet = CodeSnippet{Language: yc.Language, Code: yc.Snippet}
+			if err := register(path+"/"+yc.Name, component); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var addSystem func(path string, s *System, ys YAMLSystem) error
+	addSystem = func(path string, s *System, ys YAMLSystem) error {
+		applyYAMLNode(s.Node, ys.External, ys.Tags, "", ys.Deprecated, ys.DeprecationReason)
+		if err := register(path, s); err != nil {
+			return err
+		}
+		for _, sub := range ys.Systems {
+			if err := addSystem(path+"/"+sub.Name, s.System(sub.Name, sub.Description), sub); err != nil {
+				return err
+			}
+		}
+		for _, yc := range ys.Containers {
+			if err := addContainer(path+"/"+yc.Name, s.Container(yc.Name, yc.Description), yc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, ys := range m.Systems {
+		id := ys.ID
+		if id == "" {
+			id = ys.Name
+		}
+		if err := addSystem(ys.Name, d.SystemWithId(id, ys.Name, ys.Description), ys); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, yr := range m.Relationships {
+		from, ok := refs[yr.From]
+		if !ok {
+			return nil, fmt.Errorf("yaml: relationships[%d]: unknown element %q", i, yr.From)
+		}
+		to, ok := refs[yr.To]
+		if !ok {
+			return nil, fmt.Errorf("yaml: relationships[%d]: unknown element %q", i, yr.To)
+		}
+		relType := RelUses
+		if yr.Type != "" {
+			relType = SanitizeRelationshipType(yr.Type)
+			if relType == "" || relType == RelBelongsTo || relType == RelMemberOf || relType == RelImpliedUse {
+				return nil, fmt.Errorf("yaml: relationships[%d]: invalid type %q", i, yr.Type)
+			}
+		}
+		interaction := InteractionStyle(yr.Interaction)
+		if interaction != "" && interaction != InteractionSynchronous && interaction != InteractionAsynchronous {
+			return nil, fmt.Errorf("yaml: relationships[%d]: invalid interaction %q", i, yr.Interaction)
+		}
+		rel := d.recordRelationship(Relationship{
+			StartID:          from.FullId(),
+			EndID:            to.FullId(),
+			Type:             relType,
+			Description:      yr.Description,
+			Technology:       yr.Technology,
+			Tags:             yr.Tags,
+			InteractionStyle: interaction,
+			Weight:           yr.Weight,
+			Optional:         yr.Optional,
+		})
+		for _, key := range slices.Sorted(maps.Keys(yr.Properties)) {
+			d.setRelationshipProperty(rel, key, yr.Properties[key])
+		}
+	}
+
+	if errs := d.errs; len(errs) > 0 {
+		return nil, fmt.Errorf("yaml: %w", errors.Join(errs...))
+	}
+	return d, nil
+}
+
+// applyYAMLNode copies the attributes shared by all YAML elements onto n.
+func applyYAMLNode(n *Node, external bool, tags []string, technology string, deprecated bool, deprecationReason string) {
+	n.IsExternal = external
+	n.Deprecated, n.DeprecationReason = deprecated, deprecationReason
+	for _, tag := range tags {
+		n.Tag(tag)
+	}
+	n.Technology = strings.TrimSpace(technology)
+}
+
+// ToYAML dumps the design following the YAMLModel schema, so that ParseYAML
+// rebuilds an Equal design. Elements are sorted by name and relationships by
+// endpoints, type and description, keeping diffs stable in version control.
+//
+// Only what the schema can express is supported: person groups, code elements,
+// custom nodes, labels, layout hints, explicit styles, technologies on persons
+// or systems, NoImplied or derived relationships, relationship properties
+// other than strings, booleans, ints and floats, and extra BELONGS_TO edges
+// make ToYAML fail, as do elements whose path would be ambiguous.
+func (d *Design) ToYAML() ([]byte, error) {
+	m := YAMLModel{Name: d.Name, Description: d.Description, Version: d.Version, Meta: maps.Clone(d.meta)}
+	if root, ok := d.nodes[d.ID]; ok {
+		m.External = root.IsExternal
+		m.Tags = slices.Clone(root.Tags)
+		if len(m.Tags) > 0 && m.Tags[0] == "design" {
+			m.Tags = m.Tags[1:]
+		}
+	}
+
+	children := map[*Node][]*Node{}
+	var persons, systems []*Node
+	for _, node := range d.sortedNodes() {
+		if node.NodeType == NodeTypeDesign {
+			continue
+		}
+		if err := checkYAMLNode(node); err != nil {
+			return nil, err
+		}
+		parent := d.parentOf(node)
+		switch {
+		case parent != nil:
+			children[parent] = append(children[parent], node)
+		case node.NodeType == NodeTypePerson:
+			persons = append(persons, node)
+		case node.NodeType == NodeTypeSystem:
+			systems = append(systems, node)
+		default:
+			return nil, fmt.Errorf("yaml: %s %q must belong to a system", node.NodeType, node.FullId())
+		}
+	}
+	byName := func(nodes []*Node) []*Node {
+		sort.Slice(nodes, func(i, j int) bool {
+			if nodes[i].Name != nodes[j].Name {
+				return nodes[i].Name < nodes[j].Name
+			}
+			return nodes[i].FullId() < nodes[j].FullId()
+		})
+		return nodes
+	}
+
+	paths := map[string]string{} // FullId -> path
+	used := map[string]string{}  // path -> FullId
+	setPath := func(n *Node, path string) error {
+		if strings.Contains(n.Name, "/") {
+			return fmt.Errorf("yaml: the name of %q contains a \"/\"", n.FullId())
+		}
+		if other, ok := used[path]; ok {
+			return fmt.Errorf("yaml: %q and %q share the path %q", other, n.FullId(), path)
+		}
+		used[path] = n.FullId()
+		paths[n.FullId()] = path
+		return nil
+	}
+
+	for _, p := range byName(persons) {
+		if err := setPath(p, p.Name); err != nil {
+			return nil, err
+		}
+		m.Persons = append(m.Persons, YAMLPerson{Name: p.Name, Description: p.Description, External: p.IsExternal, Tags: p.Tags,
+			Deprecated: p.Deprecated, DeprecationReason: p.DeprecationReason})
+	}
+
+	var toContainer func(path string, c *Node) (YAMLContainer, error)
+	toContainer = func(path string, c *Node) (YAMLContainer, error) {
+		yc := YAMLContainer{Name: c.Name, Description: c.Description, Technology: c.Technology, External: c.IsExternal, Tags: c.Tags,
+			Deprecated: c.Deprecated, DeprecationReason: c.DeprecationReason}
+		if err := setPath(c, path); err != nil {
+			return yc, err
+		}
+		for _, child := range byName(children[c]) {
+			switch child.NodeType {
+			case NodeTypeContainer:
+				nested, err := toContainer(path+"/"+child.Name, child)
+				if err != nil {
+					return yc, err
+				}
+				yc.Containers = append(yc.Containers, nested)
+			case NodeTypeComponent:
+				if err := setPath(child, path+"/"+child.Name); err != nil {
+					return yc, err
+				}
+				component := YAMLComponent{Name: child.Name, Description: child.Description, Technology: child.Technology, External: child.IsExternal, Tags: child.Tags,
+					Deprecated: child.Deprecated, DeprecationReason: child.DeprecationReason, Language: child.Snippet.Language, Snippet: child.Snippet.Code}
+				if id := strings.TrimPrefix(child.ID, c.ID+"."); id != child.Name {
+					component.ID = id
+				}
+				yc.Components = append(yc.Components, component)
+			default:
+				return yc, fmt.Errorf("yaml: %s %q cannot belong to a container", child.NodeType, child.FullId())
+			}
+		}
+		return yc, nil
+	}
+
+	var toSystem func(path string, s *Node) (YAMLSystem, error)
+	toSystem = func(path string, s *Node) (YAMLSystem, error) {
+		ys := YAMLSystem{Name: s.Name, Description: s.Description, External: s.IsExternal, Tags: s.Tags,
+			Deprecated: s.Deprecated, DeprecationReason: s.DeprecationReason}
+		if err := setPath(s, path); err != nil {
+			return ys, err
+		}
+		for _, child := range byName(children[s]) {
+			switch child.NodeType {
+			case NodeTypeSystem:
+				sub, err := toSystem(path+"/"+child.Name, child)
+				if err != nil {
+					return ys, err
+				}
+				ys.Systems = append(ys.Systems, sub)
+			case NodeTypeContainer:
+				yc, err := toContainer(path+"/"+child.Name, child)
+				if err != nil {
+					return ys, err
+				}
+				ys.Containers = append(ys.Containers, yc)
+			default:
+				return ys, fmt.Errorf("yaml: %s %q cannot belong to a system", child.NodeType, child.FullId())
+			}
+		}
+		return ys, nil
+	}
+
+	for _, s := range byName(systems) {
+		ys, err := toSystem(s.Name, s)
+		if err != nil {
+			return nil, err
+		}
+		if s.ID != s.Name {
+			ys.ID = s.ID
+		}
+		m.Systems = append(m.Systems, ys)
+	}
+
+	byFullId := d.nodesByFullId()
+	for _, rel := range d.relationships {
+		if rel.Type == RelBelongsTo {
+			start, ok := byFullId[rel.StartID]
+			if ok && d.parentOf(start) != nil && d.parentOf(start).FullId() == rel.EndID {
+				continue // Recreated with the element
+			}
+			return nil, fmt.Errorf("yaml: BELONGS_TO %s -> %s is not part of the hierarchy", rel.StartID, rel.EndID)
+		}
+		if rel.NoImplied {
+			return nil, fmt.Errorf("yaml: relationship %s -> %s: NoImplied is not supported", rel.StartID, rel.EndID)
+		}
+		if rel.DerivedFrom != nil {
+			return nil, fmt.Errorf("yaml: relationship %s -> %s: derived relationships are not supported", rel.StartID, rel.EndID)
+		}
+		from, okFrom := paths[rel.StartID]
+		to, okTo := paths[rel.EndID]
+		if !okFrom || !okTo {
+			return nil, fmt.Errorf("yaml: relationship %s -> %s references an unknown element", rel.StartID, rel.EndID)
+		}
+		for key, value := range rel.Properties {
+			switch value.(type) {
+			case string, bool, int, float64:
+			default:
+				// Would not read back as the same type
+				return nil, fmt.Errorf("yaml: relationship %s -> %s: property %q has an unsupported %T value", rel.StartID, rel.EndID, key, value)
+			}
+		}
+		yr := YAMLRelationship{From: from, To: to, Description: rel.Description, Technology: rel.Technology, Tags: rel.Tags, Interaction: string(rel.InteractionStyle), Weight: rel.Weight, Optional: rel.Optional,
+			Properties: rel.Properties}
+		if rel.Type != RelUses {
+			yr.Type = string(rel.Type)
+		}
+		m.Relationships = append(m.Relationships, yr)
+	}
+	sort.SliceStable(m.Relationships, func(i, j int) bool {
+		a, b := m.Relationships[i], m.Relationships[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Description < b.Description
+	})
+
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkYAMLNode returns an error when n has attributes the YAML schema can't express.
+func checkYAMLNode(n *Node) error {
+	switch {
+	case n.NodeType != NodeTypePerson && n.NodeType != NodeTypeSystem &&
+		n.NodeType != NodeTypeContainer && n.NodeType != NodeTypeComponent:
+		return fmt.Errorf("yaml: %s nodes are not supported (%q)", n.NodeType, n.FullId())
+	case len(n.Labels) > 0:
+		return fmt.Errorf("yaml: labels are not supported (%q)", n.FullId())
+	case !n.Layout.IsZero():
+		return fmt.Errorf("yaml: layout hints are not supported (%q)", n.FullId())
+	case !n.Appearance.IsZero():
+		return fmt.Errorf("yaml: styles are not supported (%q)", n.FullId())
+	case !n.Snippet.IsZero() && n.NodeType != NodeTypeComponent:
+		return fmt.Errorf("yaml: snippets are only supported on components (%q)", n.FullId())
+	case n.Technology != "" && (n.NodeType == NodeTypePerson || n.NodeType == NodeTypeSystem):
+		return fmt.Errorf("yaml: technology on a %s is not supported (%q)", n.NodeType, n.FullId())
+	}
+	return nil
+}
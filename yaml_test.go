@@ -0,0 +1,94 @@
+package neoarch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// newYAMLDesign returns the shop fixture with everything the YAML schema
+// expresses beyond names and descriptions.
+func newYAMLDesign() *Design {
+	d := newShopDesign()
+	d.Version = "v2"
+	d.SetMeta("team", "platform")
+	d.SetMeta("repo", "github.com/acme/shop")
+	d.lookupNode("Shop.Shop.DB").Deprecate("Moved to Spanner")
+	d.lookupNode("person_Customer").Deprecated = true
+	orders := &Component{Node: d.lookupNode("Shop.Shop.API.Shop.API.Orders")}
+	orders.Snippet("go", "type Orders struct{}")
+	orders.Tag("core")
+	cache := (&System{Node: d.lookupNode("Shop"), design: d}).Container("Cache", "Hot orders").WithTechnology("Redis")
+	orders.UsesRel(cache, "Caches").Technology("RESP").Tag("hot").Async().Optional().Weight(3).
+		Property("sla_ms", 20).Property("ratio", 0.5).Property("owner", "orders").Property("critical", true)
+	return d
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	d := newYAMLDesign()
+	out, err := d.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"version: v2\n", "meta:\n  repo: github.com/acme/shop\n  team: platform\n", "deprecationReason: Moved to Spanner\n", "sla_ms: 20\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("the YAML lacks %q:\n%s", want, out)
+		}
+	}
+
+	parsed, err := ParseYAML(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if !parsed.Equal(d) {
+		t.Errorf("the parsed design differs: %+v\n%s", Diff(d, parsed), out)
+	}
+	again, err := parsed.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, out) {
+		t.Errorf("dumping the parsed design gave\n%s\nwant\n%s", again, out)
+	}
+}
+
+func TestToYAMLRejectsWhatItCannotExpress(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(d *Design)
+		want string
+	}{
+		{"layout", func(d *Design) { d.lookupNode("Shop").LayoutHint(LayoutHint{Group: "core"}) }, "layout hints are not supported"},
+		{"style", func(d *Design) { d.lookupNode("Shop").Style("", "#ff0000", "") }, "styles are not supported"},
+		{"label", func(d *Design) { d.lookupNode("Shop").AddLabel("Legacy") }, "labels are not supported"},
+		{"property", func(d *Design) {
+			d.relationships[len(d.relationships)-1].Properties = map[string]any{"since": int64(2020)}
+		}, `property "since" has an unsupported int64 value`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newShopDesign()
+			tt.edit(d)
+			if _, err := d.ToYAML(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ToYAML returned %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct{ name, model, want string }{
+		{"empty", "", "yaml: empty model"},
+		{"unknown key", "name: Shop\ncolour: red\n", "field colour not found"},
+		{"unknown element", "name: Shop\nrelationships:\n  - from: A\n    to: B\n", `relationships[0]: unknown element "A"`},
+		{"meta key", "name: Shop\nmeta:\n  bad key: x\n", `invalid metadata key "bad key"`},
+		{"property key", "name: Shop\npersons:\n  - name: A\n  - name: B\nrelationships:\n  - from: A\n    to: B\n    properties:\n      description: x\n", `invalid property "description"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseYAML(strings.NewReader(tt.model)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAML returned %v, want %s", err, tt.want)
+			}
+		})
+	}
+}